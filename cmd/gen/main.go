@@ -83,6 +83,16 @@ func main() {
 			//	return tag
 			//}),
 			tagWithNS),
+		g.GenerateModel("crawl_marks", tagWithNS),
+		g.GenerateModel("notices", tagWithNS),
+		g.GenerateModel("keyword_groups", gen.FieldType("user_id", "int64"),
+			gen.FieldType("target_chat_id", "int64"), tagWithNS),
+		g.GenerateModel("keyword_hits", gen.FieldType("user_id", "int64"), tagWithNS),
+		g.GenerateModel("conversations", gen.FieldType("chat_id", "int64"),
+			gen.FieldType("user_id", "int64"), tagWithNS),
+		g.GenerateModel("chat_settings", gen.FieldType("chat_id", "int64"), tagWithNS),
+		g.GenerateModel("digest_items", gen.FieldType("chat_id", "int64"), tagWithNS),
+		g.GenerateModel("outbox", gen.FieldType("chat_id", "int64"), tagWithNS),
 		//companyGenerator,
 		//g.GenerateModelAs("people", "Person",
 		//	gen.FieldIgnore("deleted_at"),
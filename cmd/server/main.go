@@ -5,6 +5,8 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	// Chats set their timezone by name, the image may not ship zoneinfo.
+	_ "time/tzdata"
 
 	"github.com/rs/zerolog/log"
 
@@ -3,7 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/rs/zerolog"
 
@@ -36,20 +38,106 @@ func (c *PDFServiceConfig) WebhookURL() string {
 	return fmt.Sprintf("%s:%d", c.WebhookServer, c.WebhookServerPort)
 }
 
+// SMTPConfig is the mail server the email notifications are sent with.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	// From is the sender address, Username unless set.
+	From string
+	// Domains are the domains mails may be sent to, the domain of From
+	// unless set, so a chat can not have the server mail anyone.
+	Domains []string
+}
+
+func (c *SMTPConfig) Init() *SMTPConfig {
+	c.Host = os.Getenv(constant.SMTPHost)
+	c.Port = defaultSMTPPort
+	if v := os.Getenv(constant.SMTPPort); v != "" {
+		if i, err := strconv.Atoi(v); err == nil && i > 0 {
+			c.Port = i
+		}
+	}
+	c.Username = os.Getenv(constant.SMTPUsername)
+	c.Password = os.Getenv(constant.SMTPPassword)
+	c.From = os.Getenv(constant.SMTPFrom)
+	if c.From == "" {
+		c.From = c.Username
+	}
+	c.Domains = nil
+	for _, d := range strings.Split(os.Getenv(constant.SMTPDomains), ",") {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			c.Domains = append(c.Domains, d)
+		}
+	}
+	if _, domain, ok := strings.Cut(c.From, "@"); ok && len(c.Domains) == 0 {
+		c.Domains = []string{strings.ToLower(domain)}
+	}
+	return c
+}
+
+// Allows reports whether mails may be sent to address.
+func (c *SMTPConfig) Allows(address string) bool {
+	i := strings.LastIndex(address, "@")
+	return i >= 0 && slices.Contains(c.Domains, strings.ToLower(address[i+1:]))
+}
+
+// Addr is the host:port of the mail server.
+func (c *SMTPConfig) Addr() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+// SiteConfig is one freecms site/channel pair to crawl notices from.
+type SiteConfig struct {
+	// Name is shown as the origin of the notices, defaults to Host.
+	Name      string
+	Host      string
+	SiteId    string
+	ChannelId string
+}
+
 type ServiceConfig struct {
 	PDF              *PDFServiceConfig
+	SMTP             *SMTPConfig
 	ManagerId        int64
 	MessageServerUrl string
+	Sites            []SiteConfig
+	CrawlRetries     int
+	CrawlRate        float64
+	NoticeDays       int
+	KeywordHitDays   int
 	BaseDir          string
 	LogLevel         zerolog.Level
 }
 
+// IsKnownHost reports whether host is the alarm server or one of the
+// crawled sites.
+func (c *ServiceConfig) IsKnownHost(host string) bool {
+	if host == c.MessageServerUrl {
+		return true
+	}
+	for _, site := range c.Sites {
+		if site.Host == host {
+			return true
+		}
+	}
+	return false
+}
+
 func NewServiceConfig() *ServiceConfig {
 	pdf := &PDFServiceConfig{}
+	smtp := &SMTPConfig{}
 	return &ServiceConfig{
 		PDF:              pdf.Init(),
+		SMTP:             smtp.Init(),
 		ManagerId:        ManagerId(),
 		MessageServerUrl: MessageServerUrl(),
+		Sites:            Sites(),
+		CrawlRetries:     CrawlRetries(),
+		CrawlRate:        CrawlRate(),
+		NoticeDays:       NoticeDays(),
+		KeywordHitDays:   KeywordHitDays(),
 		BaseDir:          BaseDir(),
 		LogLevel:         LogLevel(),
 	}
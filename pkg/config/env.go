@@ -4,6 +4,7 @@ import (
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/gythialy/magnet/pkg/constant"
 	"github.com/rs/zerolog"
@@ -11,6 +12,17 @@ import (
 
 const (
 	defaultScheduleInterval = 1
+	defaultCrawlRetries     = 3
+	defaultCrawlRate        = 5
+	defaultNoticeDays       = 30
+	defaultKeywordHitDays   = 180
+	defaultSMTPPort         = 587
+	// minKeywordHitDays is the longest window of /keyword_stats.
+	minKeywordHitDays = 30
+	// DefaultSiteId and DefaultChannelId identify the notice channel crawled
+	// on SERVER_URL when FREECMS_SITES is not set.
+	DefaultSiteId    = "404bb030-5be9-4070-85bd-c94b1473e8de"
+	DefaultChannelId = "c5bff13f-21ca-4dac-b158-cb40accd3035"
 )
 
 func ManagerId() int64 {
@@ -62,6 +74,49 @@ func TelegramName() string {
 	return os.Getenv(constant.TelegramBotName)
 }
 
+// CrawlRetries is how many times a failed crawler request (network error,
+// 429 or 5xx) is retried with exponential backoff.
+func CrawlRetries() int {
+	if v := os.Getenv(constant.CrawlRetries); v != "" {
+		if i, err := strconv.Atoi(v); err == nil && i >= 0 {
+			return i
+		}
+	}
+	return defaultCrawlRetries
+}
+
+// CrawlRate is the maximum number of crawler requests per second shared by
+// all sources. Zero or a negative value disables the limit.
+func CrawlRate() float64 {
+	if v := os.Getenv(constant.CrawlRate); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return defaultCrawlRate
+}
+
+// NoticeDays is how many days crawled notices are kept for /test_keyword.
+func NoticeDays() int {
+	if v := os.Getenv(constant.NoticeDays); v != "" {
+		if i, err := strconv.Atoi(v); err == nil && i > 0 {
+			return i
+		}
+	}
+	return defaultNoticeDays
+}
+
+// KeywordHitDays is how many days keyword hits are kept for /keyword_stats,
+// at least the 30 days it reports on.
+func KeywordHitDays() int {
+	if v := os.Getenv(constant.KeywordHitDays); v != "" {
+		if i, err := strconv.Atoi(v); err == nil && i > 0 {
+			return max(i, minKeywordHitDays)
+		}
+	}
+	return defaultKeywordHitDays
+}
+
 func ScheduleInterval() int {
 	result := defaultScheduleInterval
 	interval := os.Getenv(constant.ScheduleInterval)
@@ -73,4 +128,54 @@ func ScheduleInterval() int {
 	return result
 }
 
+// Sites parses FREECMS_SITES, a ';' separated list of
+// "host|siteId|channelId[|name]" entries. Hosts may be given with or without
+// a scheme. When the variable is unset, the single site on SERVER_URL with
+// the default site and channel is returned.
+func Sites() []SiteConfig {
+	value := os.Getenv(constant.FreecmsSites)
+	if strings.TrimSpace(value) == "" {
+		host := MessageServerUrl()
+		if host == "" {
+			return nil
+		}
+		return []SiteConfig{{
+			Name:      host,
+			Host:      host,
+			SiteId:    DefaultSiteId,
+			ChannelId: DefaultChannelId,
+		}}
+	}
+	return parseSites(value)
+}
 
+func parseSites(value string) []SiteConfig {
+	var sites []SiteConfig
+	for _, entry := range strings.Split(value, ";") {
+		parts := strings.Split(entry, "|")
+		if len(parts) < 3 {
+			continue
+		}
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		host := parts[0]
+		if parse, err := url.Parse(host); err == nil && parse.Host != "" {
+			host = parse.Host
+		}
+		if host == "" || parts[1] == "" || parts[2] == "" {
+			continue
+		}
+		name := host
+		if len(parts) > 3 && parts[3] != "" {
+			name = parts[3]
+		}
+		sites = append(sites, SiteConfig{
+			Name:      name,
+			Host:      host,
+			SiteId:    parts[1],
+			ChannelId: parts[2],
+		})
+	}
+	return sites
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSites(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []SiteConfig
+	}{
+		{
+			name:  "Single site without name",
+			value: "a.example.com|site1|channel1",
+			expected: []SiteConfig{
+				{Name: "a.example.com", Host: "a.example.com", SiteId: "site1", ChannelId: "channel1"},
+			},
+		},
+		{
+			name:  "Multiple sites with scheme and name",
+			value: "https://a.example.com|site1|channel1|北京; b.example.com | site2 | channel2 | 河北 ",
+			expected: []SiteConfig{
+				{Name: "北京", Host: "a.example.com", SiteId: "site1", ChannelId: "channel1"},
+				{Name: "河北", Host: "b.example.com", SiteId: "site2", ChannelId: "channel2"},
+			},
+		},
+		{
+			name:  "Invalid entries are skipped",
+			value: "a.example.com|site1;;b.example.com||channel2;c.example.com|site3|channel3",
+			expected: []SiteConfig{
+				{Name: "c.example.com", Host: "c.example.com", SiteId: "site3", ChannelId: "channel3"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSites(tt.value); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseSites(%q) = %v, want %v", tt.value, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSMTPDomains(t *testing.T) {
+	t.Setenv("SMTP_FROM", "Bot@Example.com")
+	t.Setenv("SMTP_ALLOWED_DOMAINS", "")
+	c := (&SMTPConfig{}).Init()
+	if !c.Allows("ops@example.COM") || c.Allows("ops@example.org") || c.Allows("example.com") {
+		t.Errorf("expected only the domain of the sender, got %v", c.Domains)
+	}
+	t.Setenv("SMTP_ALLOWED_DOMAINS", " example.org, Example.net ,")
+	c = (&SMTPConfig{}).Init()
+	if !reflect.DeepEqual(c.Domains, []string{"example.org", "example.net"}) || c.Allows("ops@example.com") {
+		t.Errorf("unexpected domains %v", c.Domains)
+	}
+}
@@ -9,6 +9,7 @@ const (
 	EditKeyword        = "/edit_keywords"
 	DeleteKeyword      = "/delete_keywords"
 	AddAlarmKeyword    = "/add_alarm_keywords"
+	AddFilter          = "/add_filters"
 	SearchAlarmRecords = "/search_alarm_records"
 	SearchHistory      = "/search_history_title"
 	ListToday          = "/list_today"
@@ -21,4 +22,20 @@ const (
 	Statistics         = "/statistics"
 	AlarmCallback      = "alarm:"
 	SearchCallback     = "search:"
+	TestKeyword        = "/test_keyword"
+	TestCallback       = "test:"
+	AddGroup           = "/add_group"
+	Groups             = "/groups"
+	RenameGroup        = "/rename_group"
+	SetGroup           = "/set_group"
+	MoveKeywords       = "/move_keywords"
+	DeleteGroup        = "/delete_group"
+	KeywordStats       = "/keyword_stats"
+	ExportKeywords     = "/export_keywords"
+	ImportKeywords     = "/import_keywords"
+	Keywords           = "/keywords"
+	KeywordsCallback   = "kw:"
+	Cancel             = "/cancel"
+	Delivery           = "/delivery"
+	Settings           = "/settings"
 )
@@ -12,6 +12,17 @@ const (
 	LogLevel         = "LOG_LEVEL"
 	CrawlDays        = "CRAWL_DAYS"
 	RestyTrace       = "RESTY_TRACE"
+	FreecmsSites     = "FREECMS_SITES"
+	CrawlRetries     = "CRAWL_RETRIES"
+	CrawlRate        = "CRAWL_RATE"
+	NoticeDays       = "NOTICE_DAYS"
+	KeywordHitDays   = "KEYWORD_HIT_DAYS"
+	SMTPHost         = "SMTP_HOST"
+	SMTPPort         = "SMTP_PORT"
+	SMTPUsername     = "SMTP_USERNAME"
+	SMTPPassword     = "SMTP_PASSWORD"
+	SMTPFrom         = "SMTP_FROM"
+	SMTPDomains      = "SMTP_ALLOWED_DOMAINS"
 	PDFEndPoint       = "/pdf/"
 	WebhookServerURL  = "WEBHOOK_SERVER_URL"
 	WebhookServerPort = "WEBHOOK_SERVER_PORT"
@@ -0,0 +1,38 @@
+package dal
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+// Get returns the settings of chatId, the defaults when it has none.
+func (c *chatSetting) Get(chatId int64) (*model.ChatSetting, error) {
+	result, err := c.Where(c.ChatID.Eq(chatId)).Limit(1).Find()
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return &model.ChatSetting{ChatID: chatId}, nil
+	}
+	return result[0], nil
+}
+
+// Put stores setting as the settings of its chat, the chat is unique.
+func (c *chatSetting) Put(setting *model.ChatSetting) error {
+	setting.ID = nil
+	return c.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: c.ChatID.ColumnName().String()}},
+		UpdateAll: true,
+	}).Create(setting)
+}
+
+// SetDelivery stores how the matched notices are delivered to chatId.
+func (c *chatSetting) SetDelivery(chatId int64, delivery string) error {
+	return c.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: c.ChatID.ColumnName().String()}},
+		DoUpdates: clause.AssignmentColumns([]string{c.Delivery.ColumnName().String(), c.UpdatedAt.ColumnName().String()}),
+	}).Create(&model.ChatSetting{ChatID: chatId, Delivery: delivery, UpdatedAt: time.Now()})
+}
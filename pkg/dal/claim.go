@@ -1,6 +1,8 @@
 package dal
 
 import (
+	"errors"
+
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -30,3 +32,11 @@ func InsertIfAbsent(db *gorm.DB, value interface{}, conflictColumns ...string) (
 	}
 	return res.RowsAffected == 1, nil
 }
+
+// duplicated reports whether err is the violation of a unique index.
+func duplicated(db *gorm.DB, err error) bool {
+	if t, ok := db.Dialector.(gorm.ErrorTranslator); ok && err != nil {
+		err = t.Translate(err)
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
@@ -0,0 +1,48 @@
+package dal
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+// Active returns the conversation of userId in chatId, nil when there is none
+// or it expired before now. An expired conversation is removed.
+func (c *conversation) Active(chatId, userId int64, now time.Time) (*model.Conversation, error) {
+	result, err := c.Where(c.ChatID.Eq(chatId), c.UserID.Eq(userId)).Limit(1).Find()
+	if err != nil || len(result) == 0 {
+		return nil, err
+	}
+	if !result[0].ExpiresAt.After(now) {
+		_, err = c.Remove(chatId, userId)
+		return nil, err
+	}
+	return result[0], nil
+}
+
+// Replace stores conv as the only conversation of its user in its chat, the
+// chat and user are unique.
+func (c *conversation) Replace(conv *model.Conversation) error {
+	conv.ID = nil
+	conv.UpdatedAt = time.Now()
+	return c.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: c.ChatID.ColumnName().String()}, {Name: c.UserID.ColumnName().String()}},
+		DoUpdates: clause.AssignmentColumns([]string{c.Dialog.ColumnName().String(), c.Step.ColumnName().String(),
+			c.Data.ColumnName().String(), c.ExpiresAt.ColumnName().String(), c.UpdatedAt.ColumnName().String()}),
+	}).Create(conv)
+}
+
+// Remove ends the conversation of userId in chatId, it returns how many were
+// removed.
+func (c *conversation) Remove(chatId, userId int64) (int64, error) {
+	info, err := c.Where(c.ChatID.Eq(chatId), c.UserID.Eq(userId)).Delete()
+	return info.RowsAffected, err
+}
+
+// Purge removes the conversations expired before now.
+func (c *conversation) Purge(now time.Time) (int64, error) {
+	info, err := c.Where(c.ExpiresAt.Lte(now)).Delete()
+	return info.RowsAffected, err
+}
@@ -0,0 +1,48 @@
+package dal
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gythialy/magnet/pkg/model"
+	"gorm.io/gorm"
+)
+
+func TestConversation(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "conversation.db")
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.Conversation{})
+	SetDefault(db)
+
+	chatId, userId, other := int64(-100777), int64(7), int64(8)
+	now := time.Now()
+	for _, conv := range []*model.Conversation{
+		{ChatID: chatId, UserID: userId, Dialog: "first", ExpiresAt: now.Add(time.Minute)},
+		{ChatID: chatId, UserID: userId, Dialog: "second", Step: 1, ExpiresAt: now.Add(time.Minute)},
+		{ChatID: chatId, UserID: other, Dialog: "other", ExpiresAt: now.Add(-time.Minute)},
+	} {
+		if err := Conversation.Replace(conv); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, _ := Conversation.Where(Conversation.ChatID.Eq(chatId), Conversation.UserID.Eq(userId)).Count(); n != 1 {
+		t.Errorf("expected one conversation per user and chat, got %d", n)
+	}
+	if conv, err := Conversation.Active(chatId, userId, now); err != nil || conv == nil || conv.Dialog != "second" {
+		t.Errorf("Active() = %+v, %v", conv, err)
+	}
+	if conv, err := Conversation.Active(chatId, userId, now.Add(time.Hour)); err != nil || conv != nil {
+		t.Errorf("expected the conversation expired, got %+v, %v", conv, err)
+	}
+	if n, _ := Conversation.Remove(chatId, userId); n != 0 {
+		t.Errorf("expected the expired conversation removed by Active, removed %d", n)
+	}
+	if n, err := Conversation.Purge(now); err != nil || n != 1 {
+		t.Errorf("Purge() = %d, %v", n, err)
+	}
+}
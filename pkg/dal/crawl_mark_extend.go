@@ -0,0 +1,50 @@
+package dal
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+// Get returns the high-water mark of a notice source, i.e. the publish time
+// of the newest notice already crawled from it. A source that was never
+// crawled returns the zero time.
+func (c *crawlMark) Get(source string) (time.Time, error) {
+	mark, err := c.Where(c.Source.Eq(source)).First()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return mark.MarkedAt, nil
+}
+
+// Advance moves the high-water mark of a source forward to markedAt. A mark
+// older than the stored one is ignored, so a late or partial run can never
+// move a source backwards. The source is unique, overlapping runs update the
+// same row.
+func (c *crawlMark) Advance(source string, markedAt time.Time) error {
+	return Q.Transaction(func(tx *Query) error {
+		dao := tx.CrawlMark
+		mark, err := dao.Where(dao.Source.Eq(source)).First()
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && !markedAt.After(mark.MarkedAt) {
+			return nil
+		}
+		return dao.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: dao.Source.ColumnName().String()}},
+			DoUpdates: clause.AssignmentColumns([]string{dao.MarkedAt.ColumnName().String(), dao.UpdatedAt.ColumnName().String()}),
+		}).Create(&model.CrawlMark{
+			Source:    source,
+			MarkedAt:  markedAt,
+			UpdatedAt: time.Now(),
+		})
+	})
+}
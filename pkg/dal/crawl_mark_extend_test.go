@@ -0,0 +1,50 @@
+package dal
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gythialy/magnet/pkg/model"
+	"gorm.io/gorm"
+)
+
+func TestCrawlMark_Advance(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "crawl_mark.db")
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.CrawlMark{})
+	SetDefault(db)
+
+	dao := CrawlMark
+	if mark, err := dao.Get("a"); err != nil || !mark.IsZero() {
+		t.Fatalf("expected zero mark, got %s, %v", mark, err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	steps := []struct {
+		markedAt time.Time
+		want     time.Time
+	}{
+		{now, now},
+		{now.Add(-time.Hour), now},
+		{now.Add(time.Hour), now.Add(time.Hour)},
+	}
+	for i, step := range steps {
+		if err := dao.Advance("a", step.markedAt); err != nil {
+			t.Fatal(err)
+		}
+		if mark, _ := dao.Get("a"); !mark.Equal(step.want) {
+			t.Errorf("step %d: expected %s, got %s", i, step.want, mark)
+		}
+	}
+	if mark, _ := dao.Get("b"); !mark.IsZero() {
+		t.Errorf("expected marks to be kept per source, got %s", mark)
+	}
+	if n, _ := dao.Count(); n != 1 {
+		t.Errorf("expected 1 mark, got %d", n)
+	}
+}
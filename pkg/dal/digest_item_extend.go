@@ -0,0 +1,42 @@
+package dal
+
+import (
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+// Enqueue queues item for the digest of its chat and reports whether it was
+// queued, a notice already waiting for the digest is not queued again. The
+// chat and url are unique, so two runs queueing a notice at once queue it
+// once.
+func (d *digestItem) Enqueue(item *model.DigestItem) (bool, error) {
+	return InsertIfAbsent(d.UnderlyingDB(), item, d.ChatID.ColumnName().String(), d.URL.ColumnName().String())
+}
+
+// Pending returns when the notices waiting for the digest of each chat were
+// queued and the groups holding them, keyed by chat id.
+func (d *digestItem) Pending() (map[int64][]*model.DigestItem, error) {
+	result, err := d.Select(d.ChatID, d.QueuedAt, d.GroupID).Order(d.ID).Find()
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[int64][]*model.DigestItem)
+	for _, v := range result {
+		pending[v.ChatID] = append(pending[v.ChatID], v)
+	}
+	return pending, nil
+}
+
+// GetByChatId returns the notices waiting for the digest of chatId in the
+// order they were queued.
+func (d *digestItem) GetByChatId(chatId int64) ([]*model.DigestItem, error) {
+	return d.Where(d.ChatID.Eq(chatId)).Order(d.ID).Find()
+}
+
+// DeleteByIds drops delivered notices from the queue.
+func (d *digestItem) DeleteByIds(ids []int32) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := d.Where(d.ID.In(ids...)).Delete()
+	return err
+}
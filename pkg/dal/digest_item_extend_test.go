@@ -0,0 +1,84 @@
+package dal
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gythialy/magnet/pkg/model"
+	"gorm.io/gorm"
+)
+
+func TestDigestItem(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "digest_item.db")
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.DigestItem{}, &model.ChatSetting{})
+	SetDefault(db)
+
+	chatId, other := int64(-100777), int64(8)
+	now := time.Now()
+	for i, item := range []*model.DigestItem{
+		{ChatID: chatId, URL: "https://example.com/1", QueuedAt: now},
+		{ChatID: chatId, URL: "https://example.com/2", QueuedAt: now.Add(-time.Hour), GroupID: 3},
+		{ChatID: chatId, URL: "https://example.com/1", QueuedAt: now},
+		{ChatID: other, URL: "https://example.com/1", QueuedAt: now},
+	} {
+		queued, err := DigestItem.Enqueue(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if queued != (i != 2) {
+			t.Errorf("item %d: queued = %v", i, queued)
+		}
+	}
+
+	pending, err := DigestItem.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || len(pending[chatId]) != 2 || !pending[chatId][1].QueuedAt.Equal(now.Add(-time.Hour)) ||
+		pending[chatId][1].GroupID != 3 || len(pending[other]) != 1 {
+		t.Errorf("unexpected pending digests %v", pending)
+	}
+
+	items, err := DigestItem.GetByChatId(chatId)
+	if err != nil || len(items) != 2 || items[0].URL != "https://example.com/1" {
+		t.Fatalf("unexpected items %v, %v", items, err)
+	}
+	if err := DigestItem.DeleteByIds([]int32{*items[0].ID, *items[1].ID}); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := DigestItem.GetByChatId(chatId); len(items) != 0 {
+		t.Errorf("expected the digest of %d to be empty, got %d items", chatId, len(items))
+	}
+
+	if setting, err := ChatSetting.Get(chatId); err != nil || setting.Delivery != "" {
+		t.Errorf("expected the default setting, got %+v, %v", setting, err)
+	}
+	if err := ChatSetting.Put(&model.ChatSetting{ChatID: chatId, Timezone: "Europe/Berlin", UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"daily 08:00", "hourly"} {
+		if err := ChatSetting.SetDelivery(chatId, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if setting, _ := ChatSetting.Get(chatId); setting.Delivery != "hourly" || setting.Timezone != "Europe/Berlin" {
+		t.Errorf("expected hourly delivery in the kept timezone, got %+v", setting)
+	}
+	setting, _ := ChatSetting.Get(chatId)
+	setting.Delivery, setting.Timezone = "daily 09:00", ""
+	if err := ChatSetting.Put(setting); err != nil {
+		t.Fatal(err)
+	}
+	if setting, _ := ChatSetting.Get(chatId); setting.Delivery != "daily 09:00" || setting.Timezone != "" {
+		t.Errorf("expected the settings replaced, got %+v", setting)
+	}
+	if count, _ := ChatSetting.Where(ChatSetting.ChatID.Eq(chatId)).Count(); count != 1 {
+		t.Errorf("expected one setting row, got %d", count)
+	}
+}
@@ -16,44 +16,84 @@ import (
 )
 
 var (
-	Q       = new(Query)
-	Alarm   *alarm
-	History *history
-	Keyword *keyword
+	Q            = new(Query)
+	Alarm        *alarm
+	ChatSetting  *chatSetting
+	Conversation *conversation
+	CrawlMark    *crawlMark
+	DigestItem   *digestItem
+	History      *history
+	Keyword      *keyword
+	KeywordGroup *keywordGroup
+	KeywordHit   *keywordHit
+	Notice       *notice
+	Outbox       *outbox
 )
 
 func SetDefault(db *gorm.DB, opts ...gen.DOOption) {
 	*Q = *Use(db, opts...)
 	Alarm = &Q.Alarm
+	ChatSetting = &Q.ChatSetting
+	Conversation = &Q.Conversation
+	CrawlMark = &Q.CrawlMark
+	DigestItem = &Q.DigestItem
 	History = &Q.History
 	Keyword = &Q.Keyword
+	KeywordGroup = &Q.KeywordGroup
+	KeywordHit = &Q.KeywordHit
+	Notice = &Q.Notice
+	Outbox = &Q.Outbox
 }
 
 func Use(db *gorm.DB, opts ...gen.DOOption) *Query {
 	return &Query{
-		db:      db,
-		Alarm:   newAlarm(db, opts...),
-		History: newHistory(db, opts...),
-		Keyword: newKeyword(db, opts...),
+		db:           db,
+		Alarm:        newAlarm(db, opts...),
+		ChatSetting:  newChatSetting(db, opts...),
+		Conversation: newConversation(db, opts...),
+		CrawlMark:    newCrawlMark(db, opts...),
+		DigestItem:   newDigestItem(db, opts...),
+		History:      newHistory(db, opts...),
+		Keyword:      newKeyword(db, opts...),
+		KeywordGroup: newKeywordGroup(db, opts...),
+		KeywordHit:   newKeywordHit(db, opts...),
+		Notice:       newNotice(db, opts...),
+		Outbox:       newOutbox(db, opts...),
 	}
 }
 
 type Query struct {
 	db *gorm.DB
 
-	Alarm   alarm
-	History history
-	Keyword keyword
+	Alarm        alarm
+	ChatSetting  chatSetting
+	Conversation conversation
+	CrawlMark    crawlMark
+	DigestItem   digestItem
+	History      history
+	Keyword      keyword
+	KeywordGroup keywordGroup
+	KeywordHit   keywordHit
+	Notice       notice
+	Outbox       outbox
 }
 
 func (q *Query) Available() bool { return q.db != nil }
 
 func (q *Query) clone(db *gorm.DB) *Query {
 	return &Query{
-		db:      db,
-		Alarm:   q.Alarm.clone(db),
-		History: q.History.clone(db),
-		Keyword: q.Keyword.clone(db),
+		db:           db,
+		Alarm:        q.Alarm.clone(db),
+		ChatSetting:  q.ChatSetting.clone(db),
+		Conversation: q.Conversation.clone(db),
+		CrawlMark:    q.CrawlMark.clone(db),
+		DigestItem:   q.DigestItem.clone(db),
+		History:      q.History.clone(db),
+		Keyword:      q.Keyword.clone(db),
+		KeywordGroup: q.KeywordGroup.clone(db),
+		KeywordHit:   q.KeywordHit.clone(db),
+		Notice:       q.Notice.clone(db),
+		Outbox:       q.Outbox.clone(db),
 	}
 }
 
@@ -67,24 +107,48 @@ func (q *Query) WriteDB() *Query {
 
 func (q *Query) ReplaceDB(db *gorm.DB) *Query {
 	return &Query{
-		db:      db,
-		Alarm:   q.Alarm.replaceDB(db),
-		History: q.History.replaceDB(db),
-		Keyword: q.Keyword.replaceDB(db),
+		db:           db,
+		Alarm:        q.Alarm.replaceDB(db),
+		ChatSetting:  q.ChatSetting.replaceDB(db),
+		Conversation: q.Conversation.replaceDB(db),
+		CrawlMark:    q.CrawlMark.replaceDB(db),
+		DigestItem:   q.DigestItem.replaceDB(db),
+		History:      q.History.replaceDB(db),
+		Keyword:      q.Keyword.replaceDB(db),
+		KeywordGroup: q.KeywordGroup.replaceDB(db),
+		KeywordHit:   q.KeywordHit.replaceDB(db),
+		Notice:       q.Notice.replaceDB(db),
+		Outbox:       q.Outbox.replaceDB(db),
 	}
 }
 
 type queryCtx struct {
-	Alarm   IAlarmDo
-	History IHistoryDo
-	Keyword IKeywordDo
+	Alarm        IAlarmDo
+	ChatSetting  IChatSettingDo
+	Conversation IConversationDo
+	CrawlMark    ICrawlMarkDo
+	DigestItem   IDigestItemDo
+	History      IHistoryDo
+	Keyword      IKeywordDo
+	KeywordGroup IKeywordGroupDo
+	KeywordHit   IKeywordHitDo
+	Notice       INoticeDo
+	Outbox       IOutboxDo
 }
 
 func (q *Query) WithContext(ctx context.Context) *queryCtx {
 	return &queryCtx{
-		Alarm:   q.Alarm.WithContext(ctx),
-		History: q.History.WithContext(ctx),
-		Keyword: q.Keyword.WithContext(ctx),
+		Alarm:        q.Alarm.WithContext(ctx),
+		ChatSetting:  q.ChatSetting.WithContext(ctx),
+		Conversation: q.Conversation.WithContext(ctx),
+		CrawlMark:    q.CrawlMark.WithContext(ctx),
+		DigestItem:   q.DigestItem.WithContext(ctx),
+		History:      q.History.WithContext(ctx),
+		Keyword:      q.Keyword.WithContext(ctx),
+		KeywordGroup: q.KeywordGroup.WithContext(ctx),
+		KeywordHit:   q.KeywordHit.WithContext(ctx),
+		Notice:       q.Notice.WithContext(ctx),
+		Outbox:       q.Outbox.WithContext(ctx),
 	}
 }
 
@@ -81,8 +81,16 @@ func Test_WithContext(t *testing.T) {
 
 	for _, ctx := range []context.Context{
 		qCtx.Alarm.UnderlyingDB().Statement.Context,
+		qCtx.ChatSetting.UnderlyingDB().Statement.Context,
+		qCtx.Conversation.UnderlyingDB().Statement.Context,
+		qCtx.CrawlMark.UnderlyingDB().Statement.Context,
+		qCtx.DigestItem.UnderlyingDB().Statement.Context,
 		qCtx.History.UnderlyingDB().Statement.Context,
 		qCtx.Keyword.UnderlyingDB().Statement.Context,
+		qCtx.KeywordGroup.UnderlyingDB().Statement.Context,
+		qCtx.KeywordHit.UnderlyingDB().Statement.Context,
+		qCtx.Notice.UnderlyingDB().Statement.Context,
+		qCtx.Outbox.UnderlyingDB().Statement.Context,
 	} {
 		if v := ctx.Value(key); v != value {
 			t.Errorf("get value from context fail, expect %q, got %q", value, v)
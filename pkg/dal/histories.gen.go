@@ -33,6 +33,14 @@ func newHistory(db *gorm.DB, opts ...gen.DOOption) history {
 	_history.UpdatedAt = field.NewTime(tableName, "updated_at")
 	_history.Title = field.NewString(tableName, "title")
 	_history.HasTenderCode = field.NewInt32(tableName, "has_tender_code")
+	_history.Source = field.NewString(tableName, "source")
+	_history.RegionName = field.NewString(tableName, "region_name")
+	_history.Budget = field.NewFloat64(tableName, "budget")
+	_history.OpenTenderTime = field.NewTime(tableName, "open_tender_time")
+	_history.ExpireTime = field.NewTime(tableName, "expire_time")
+	_history.AgentName = field.NewString(tableName, "agent_name")
+	_history.AgentLinkMan = field.NewString(tableName, "agent_link_man")
+	_history.AgentLinkPhone = field.NewString(tableName, "agent_link_phone")
 
 	_history.fillFieldMap()
 
@@ -42,12 +50,20 @@ func newHistory(db *gorm.DB, opts ...gen.DOOption) history {
 type history struct {
 	historyDo
 
-	ALL           field.Asterisk
-	UserID        field.Int64
-	URL           field.String
-	UpdatedAt     field.Time
-	Title         field.String
-	HasTenderCode field.Int32
+	ALL            field.Asterisk
+	UserID         field.Int64
+	URL            field.String
+	UpdatedAt      field.Time
+	Title          field.String
+	HasTenderCode  field.Int32
+	Source         field.String
+	RegionName     field.String
+	Budget         field.Float64
+	OpenTenderTime field.Time
+	ExpireTime     field.Time
+	AgentName      field.String
+	AgentLinkMan   field.String
+	AgentLinkPhone field.String
 
 	fieldMap map[string]field.Expr
 }
@@ -69,6 +85,14 @@ func (h *history) updateTableName(table string) *history {
 	h.UpdatedAt = field.NewTime(table, "updated_at")
 	h.Title = field.NewString(table, "title")
 	h.HasTenderCode = field.NewInt32(table, "has_tender_code")
+	h.Source = field.NewString(table, "source")
+	h.RegionName = field.NewString(table, "region_name")
+	h.Budget = field.NewFloat64(table, "budget")
+	h.OpenTenderTime = field.NewTime(table, "open_tender_time")
+	h.ExpireTime = field.NewTime(table, "expire_time")
+	h.AgentName = field.NewString(table, "agent_name")
+	h.AgentLinkMan = field.NewString(table, "agent_link_man")
+	h.AgentLinkPhone = field.NewString(table, "agent_link_phone")
 
 	h.fillFieldMap()
 
@@ -85,12 +109,20 @@ func (h *history) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
 }
 
 func (h *history) fillFieldMap() {
-	h.fieldMap = make(map[string]field.Expr, 5)
+	h.fieldMap = make(map[string]field.Expr, 13)
 	h.fieldMap["user_id"] = h.UserID
 	h.fieldMap["url"] = h.URL
 	h.fieldMap["updated_at"] = h.UpdatedAt
 	h.fieldMap["title"] = h.Title
 	h.fieldMap["has_tender_code"] = h.HasTenderCode
+	h.fieldMap["source"] = h.Source
+	h.fieldMap["region_name"] = h.RegionName
+	h.fieldMap["budget"] = h.Budget
+	h.fieldMap["open_tender_time"] = h.OpenTenderTime
+	h.fieldMap["expire_time"] = h.ExpireTime
+	h.fieldMap["agent_name"] = h.AgentName
+	h.fieldMap["agent_link_man"] = h.AgentLinkMan
+	h.fieldMap["agent_link_phone"] = h.AgentLinkPhone
 }
 
 func (h history) clone(db *gorm.DB) history {
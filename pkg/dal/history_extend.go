@@ -43,8 +43,10 @@ func (h *history) GetByUserId(userId int64) ([]*model.History, error) {
 
 func (h *history) Insert(data []*model.History) error {
 	if err := h.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: h.UserID.ColumnName().String()}, {Name: h.URL.ColumnName().String()}},
-		DoUpdates: clause.AssignmentColumns([]string{h.Title.ColumnName().String(), h.UpdatedAt.ColumnName().String()}),
+		Columns: []clause.Column{{Name: h.UserID.ColumnName().String()}, {Name: h.URL.ColumnName().String()}},
+		DoUpdates: clause.AssignmentColumns([]string{h.Title.ColumnName().String(), h.UpdatedAt.ColumnName().String(), h.Source.ColumnName().String(),
+			h.RegionName.ColumnName().String(), h.Budget.ColumnName().String(), h.OpenTenderTime.ColumnName().String(), h.ExpireTime.ColumnName().String(),
+			h.AgentName.ColumnName().String(), h.AgentLinkMan.ColumnName().String(), h.AgentLinkPhone.ColumnName().String()}),
 	}).CreateInBatches(data, batchSize); err == nil {
 		return nil
 	} else {
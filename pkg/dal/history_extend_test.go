@@ -3,6 +3,7 @@ package dal
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -15,7 +16,7 @@ import (
 )
 
 func setupTestDB(t *testing.T) func() {
-	f := "./history.db"
+	f := filepath.Join(t.TempDir(), "history.db")
 	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
@@ -5,61 +5,113 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"gorm.io/gen/field"
 
 	"github.com/gythialy/magnet/pkg/model"
 )
 
+// Insert adds the keywords userId does not have yet, numbered after the
+// keywords of userId.
 func (k *keyword) Insert(keywords []string, userId int64, t model.KeywordType) string {
-	var data []*model.Keyword
 	var result []string
-	for _, kw := range keywords {
-		kw = strings.TrimSpace(kw)
-		if kw == "" {
-			continue
-		}
-		e := &model.Keyword{
-			Keyword: kw,
-			UserID:  userId,
-			Type:    int32(t),
+	err := k.numbered(func(tx *Query) error {
+		result = nil
+		next, err := tx.Keyword.nextNumber(userId)
+		if err != nil {
+			return err
 		}
-		if count, err := k.Where(field.Attrs(&e)).Count(); err == nil && count == 0 {
-			data = append(data, e)
-			result = append(result, kw)
+		var data []*model.Keyword
+		for _, kw := range keywords {
+			kw = strings.TrimSpace(kw)
+			if kw == "" {
+				continue
+			}
+			e := &model.Keyword{
+				Keyword: kw,
+				UserID:  userId,
+				Type:    int32(t),
+			}
+			if count, err := tx.Keyword.Where(field.Attrs(&e)).Count(); err == nil && count == 0 {
+				e.Number = next
+				next++
+				data = append(data, e)
+				result = append(result, kw)
+			}
 		}
-	}
-	if err := k.CreateInBatches(data, batchSize); err != nil {
+		return tx.Keyword.CreateInBatches(data, batchSize)
+	})
+	if err != nil {
 		return ""
 	}
 	return strings.Join(result, ", ")
 }
 
-func (k *keyword) DeleteByIds(ids string) (string, error) {
-	var dbIds []int32
+// nextNumber returns the number of the next keyword of userId. Numbers are
+// per user and not reused, deleted keywords included.
+func (k *keyword) nextNumber(userId int64) (int32, error) {
+	last, err := k.Unscoped().Where(k.UserID.Eq(userId)).Order(k.Number.Desc()).Limit(1).Find()
+	if err != nil || len(last) == 0 {
+		return 1, err
+	}
+	return last[0].Number + 1, nil
+}
+
+// numberRetries bounds how often keywords are added again when another
+// insert took their numbers meanwhile.
+const numberRetries = 3
+
+// numbered runs fc, which adds keywords numbered by nextNumber, in a
+// transaction, again while the numbers it handed out clash with those of a
+// concurrent insert.
+func (k *keyword) numbered(fc func(tx *Query) error) (err error) {
+	for i := 0; i < numberRetries; i++ {
+		if err = Q.Transaction(fc); !duplicated(k.UnderlyingDB(), err) {
+			return err
+		}
+	}
+	return err
+}
+
+// DeleteByNumbers deletes the keywords of userId by their comma separated
+// numbers.
+func (k *keyword) DeleteByNumbers(userId int64, numbers string) (string, error) {
+	var dbNumbers []int32
 	var result []string
-	tmp := strings.Split(ids, ",")
+	tmp := strings.Split(numbers, ",")
 	for _, kw := range tmp {
 		kw = strings.TrimSpace(kw)
 		if kw == "" {
 			continue
 		}
 		if i, err := strconv.ParseInt(kw, 10, 32); err == nil {
-			dbIds = append(dbIds, int32(i))
+			dbNumbers = append(dbNumbers, int32(i))
 		}
 	}
-	if records, err := k.Where(k.ID.In(dbIds...)).Find(); err == nil {
+	err := Q.Transaction(func(tx *Query) error {
+		records, err := tx.Keyword.Where(tx.Keyword.UserID.Eq(userId), tx.Keyword.Number.In(dbNumbers...)).Find()
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return fmt.Errorf("keywords %s not found", strings.TrimSpace(numbers))
+		}
+		var ids []int32
 		for _, r := range records {
-			result = append(result, fmt.Sprintf("%d:%s", *r.ID, r.Keyword))
+			ids = append(ids, *r.ID)
+			result = append(result, fmt.Sprintf("%d:%s", r.Number, r.Keyword))
 		}
-		if _, err := k.Delete(records...); err == nil {
-			return strings.Join(result, ";"), nil
-		} else {
-			return "", err
+		if _, err := tx.Keyword.Delete(records...); err != nil {
+			return err
 		}
-	} else {
+		_, err = tx.KeywordHit.Where(tx.KeywordHit.KeywordID.In(ids...)).Delete()
+		return err
+	})
+	if err != nil {
 		return "", err
 	}
+	return strings.Join(result, ";"), nil
 }
 
 func (k *keyword) GetByUserIdAndType(userId int64, t model.KeywordType) []*model.Keyword {
@@ -110,15 +162,19 @@ func (k *keyword) CountByUserId(userId int64, t model.KeywordType) int64 {
 	}
 }
 
-func (k *keyword) EditById(content []string) error {
+// EditByNumber replaces keywords of userId, each entry is "number=keyword".
+func (k *keyword) EditByNumber(userId int64, content []string) error {
 	var combinedErr []error
 	for idx, r := range content {
-		split := strings.Split(r, "=")
-		if i, err := strconv.ParseInt(strings.TrimSpace(split[0]), 10, 32); err == nil {
-			data := strings.TrimSpace(split[1])
+		// Only the first "=" separates the number, keywords may contain one.
+		number, data, _ := strings.Cut(r, "=")
+		if i, err := strconv.ParseInt(strings.TrimSpace(number), 10, 32); err == nil {
+			data := strings.TrimSpace(data)
 			if data != "" {
-				if _, err := k.Where(k.ID.Eq(int32(i))).Update(k.Keyword, data); err != nil {
+				if info, err := k.Where(k.UserID.Eq(userId), k.Number.Eq(int32(i))).Update(k.Keyword, data); err != nil {
 					combinedErr = append(combinedErr, fmt.Errorf("invalid id: %d, content: %s, %s", idx, r, err.Error()))
+				} else if info.RowsAffected == 0 {
+					combinedErr = append(combinedErr, fmt.Errorf("keyword %d not found", i))
 				}
 			}
 		} else {
@@ -128,3 +184,102 @@ func (k *keyword) EditById(content []string) error {
 
 	return errors.Join(combinedErr...)
 }
+
+// GetByNumber returns the keyword number of userId.
+func (k *keyword) GetByNumber(userId int64, number int32) (*model.Keyword, error) {
+	return k.Where(k.UserID.Eq(userId), k.Number.Eq(number)).First()
+}
+
+// SetKeyword replaces the keyword number of userId.
+func (k *keyword) SetKeyword(userId int64, number int32, keyword string) error {
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" {
+		return fmt.Errorf("empty keyword")
+	}
+	kw, err := k.GetByNumber(userId, number)
+	if err != nil {
+		return err
+	}
+	if count, err := k.Where(k.UserID.Eq(userId), k.Type.Eq(kw.Type), k.Keyword.Eq(keyword), k.ID.Neq(*kw.ID)).Count(); err != nil {
+		return err
+	} else if count > 0 {
+		return fmt.Errorf("keyword %s already exists", keyword)
+	}
+	_, err = k.Where(k.ID.Eq(*kw.ID)).Update(k.Keyword, keyword)
+	return err
+}
+
+// SetMuted pauses or resumes the keyword number of userId.
+func (k *keyword) SetMuted(userId int64, number int32, muted bool) error {
+	value := int32(0)
+	if muted {
+		value = 1
+	}
+	info, err := k.Where(k.UserID.Eq(userId), k.Number.Eq(number)).Update(k.Muted, value)
+	if err == nil && info.RowsAffected == 0 {
+		err = fmt.Errorf("keyword %d not found", number)
+	}
+	return err
+}
+
+// ImportedKeyword is a keyword to import, Group names its group, empty for
+// none.
+type ImportedKeyword struct {
+	Keyword string
+	Type    model.KeywordType
+	Group   string
+}
+
+// Import adds keywords for userId in one transaction, creating the groups
+// named that do not exist yet. It returns how many groups were created.
+func (k *keyword) Import(userId int64, keywords []ImportedKeyword) (int, error) {
+	created := 0
+	err := k.numbered(func(tx *Query) error {
+		created = 0
+		g := tx.KeywordGroup
+		existing, err := g.Where(g.UserID.Eq(userId)).Find()
+		if err != nil {
+			return err
+		}
+		next, err := tx.Keyword.nextNumber(userId)
+		if err != nil {
+			return err
+		}
+		groups := make(map[string]*int32, len(existing))
+		for _, v := range existing {
+			groups[v.Name] = v.ID
+		}
+		data := make([]*model.Keyword, 0, len(keywords))
+		for _, kw := range keywords {
+			e := &model.Keyword{
+				Keyword: kw.Keyword,
+				UserID:  userId,
+				Type:    int32(kw.Type),
+				Number:  next,
+			}
+			next++
+			if kw.Group != "" {
+				id, ok := groups[kw.Group]
+				if !ok {
+					group := &model.KeywordGroup{UserID: userId, Name: kw.Group, CreatedAt: time.Now()}
+					if err := g.Create(group); err != nil {
+						return err
+					}
+					id = group.ID
+					groups[kw.Group] = id
+					created++
+				}
+				e.GroupID = id
+			}
+			data = append(data, e)
+		}
+		if len(data) == 0 {
+			return nil
+		}
+		return tx.Keyword.CreateInBatches(data, batchSize)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return created, nil
+}
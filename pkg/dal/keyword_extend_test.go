@@ -3,7 +3,7 @@ package dal
 import (
 	"fmt"
 	"math/rand"
-	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
@@ -15,10 +15,7 @@ import (
 )
 
 func TestNewAlarmKeywordDao(t *testing.T) {
-	f := "./keyword.db"
-	defer func() {
-		_ = os.Remove(f)
-	}()
+	f := filepath.Join(t.TempDir(), "keyword.db")
 	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 	})
@@ -26,7 +23,7 @@ func TestNewAlarmKeywordDao(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_ = db.AutoMigrate(&model.Keyword{})
+	_ = db.AutoMigrate(&model.Keyword{}, &model.KeywordHit{})
 	db.Debug()
 	values := []string{"test", "test2", "test3", "test4"}
 	id := int64(1111)
@@ -47,19 +44,19 @@ func TestNewAlarmKeywordDao(t *testing.T) {
 	}
 	var ids []string
 	for _, f := range find {
-		ids = append(ids, strconv.FormatInt(int64(*f.ID), 10))
+		ids = append(ids, strconv.FormatInt(int64(f.Number), 10))
 	}
 
 	var tmp []string
 	for _, id := range ids {
 		tmp = append(tmp, fmt.Sprintf("%s=%s", id, generateRandomString(10)))
 	}
-	if err = Keyword.EditById(tmp); err != nil {
+	if err = Keyword.EditByNumber(id, tmp); err != nil {
 		t.Fatal(err)
 	}
 
 	joinIds := strings.Join(ids, ",")
-	if info, err := Keyword.DeleteByIds(joinIds); err != nil {
+	if info, err := Keyword.DeleteByNumbers(id, joinIds); err != nil {
 		t.Fatal(err)
 	} else {
 		t.Log(info)
@@ -68,6 +65,44 @@ func TestNewAlarmKeywordDao(t *testing.T) {
 	t.Log(Keyword.GetKeywords(id, model.PROJECT))
 }
 
+func TestKeyword_Import(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "keyword_import.db")
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.Keyword{}, &model.KeywordGroup{})
+	SetDefault(db)
+
+	id := int64(1111)
+	gear, _ := KeywordGroup.Add(id, "network gear")
+	created, err := Keyword.Import(id, []ImportedKeyword{
+		{Keyword: "交换机", Type: model.PROJECT, Group: "network gear"},
+		{Keyword: "某公司", Type: model.PROJECT, Group: "competitors"},
+		{Keyword: "路由器", Type: model.PROJECT, Group: "competitors"},
+		{Keyword: "某公司", Type: model.ALARM},
+	})
+	if err != nil || created != 1 {
+		t.Fatalf("expected 1 created group, got %d, %v", created, err)
+	}
+	groups := KeywordGroup.GetByUserId(id)
+	if len(groups) != 2 || groups[1].Name != "competitors" {
+		t.Fatalf("unexpected groups %+v", groups)
+	}
+	for _, kw := range Keyword.GetByUserIdAndType(id, model.PROJECT) {
+		want := *groups[1].ID
+		if kw.Keyword == "交换机" {
+			want = *gear.ID
+		}
+		if kw.GroupID == nil || *kw.GroupID != want {
+			t.Errorf("expected %s in group %d, got %v", kw.Keyword, want, kw.GroupID)
+		}
+	}
+	if alarms := Keyword.GetByUserIdAndType(id, model.ALARM); len(alarms) != 1 || alarms[0].GroupID != nil {
+		t.Errorf("unexpected alarm keywords %+v", alarms)
+	}
+}
+
 func generateRandomString(length int) string {
 	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 	var sb strings.Builder
@@ -76,3 +111,147 @@ func generateRandomString(length int) string {
 	}
 	return sb.String()
 }
+
+func TestKeyword_SetKeyword(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "keyword_set.db")
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.Keyword{})
+	SetDefault(db)
+
+	userId, other := int64(3131), int64(3232)
+	Keyword.Insert([]string{"服务器", "交换机"}, userId, model.PROJECT)
+	Keyword.Insert([]string{"路由器"}, userId, model.ALARM)
+	kws := Keyword.GetByUserIdAndType(userId, model.PROJECT)
+	number := kws[0].Number
+
+	if err := Keyword.SetKeyword(userId, number, "交换机"); err == nil {
+		t.Error("expected renaming to an existing keyword to fail")
+	}
+	if err := Keyword.SetKeyword(userId, number, "路由器"); err != nil {
+		t.Errorf("expected an alarm keyword not to clash, got %v", err)
+	}
+	if err := Keyword.SetKeyword(other, number, "打印机"); err == nil {
+		t.Error("expected the keyword of another user to be left alone")
+	}
+	if err := Keyword.SetMuted(other, number, true); err == nil {
+		t.Error("expected muting the keyword of another user to fail")
+	}
+	if err := Keyword.SetMuted(userId, number, true); err != nil {
+		t.Fatal(err)
+	}
+	kw, err := Keyword.GetByNumber(userId, number)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kw.Keyword != "路由器" || kw.Muted != 1 {
+		t.Errorf("unexpected keyword %+v", kw)
+	}
+	if _, err := Keyword.GetByNumber(other, number); err == nil {
+		t.Error("expected the keyword of another user not to be found")
+	}
+}
+
+func TestKeyword_OtherChat(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "keyword_other.db")
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.Keyword{}, &model.KeywordHit{}, &model.KeywordGroup{})
+	SetDefault(db)
+
+	userId, other := int64(4141), int64(4242)
+	Keyword.Insert([]string{"服务器", "交换机"}, userId, model.PROJECT)
+	Keyword.Insert([]string{"打印机"}, other, model.PROJECT)
+	Keyword.Insert([]string{"某公司"}, userId, model.ALARM)
+	numbers := func(id int64) []int32 {
+		var n []int32
+		for _, t := range []model.KeywordType{model.PROJECT, model.ALARM} {
+			for _, kw := range Keyword.GetByUserIdAndType(id, t) {
+				n = append(n, kw.Number)
+			}
+		}
+		return n
+	}
+	// Numbers count per chat, across keyword types.
+	if got := fmt.Sprint(numbers(userId), numbers(other)); got != "[1 2 3] [1]" {
+		t.Errorf("unexpected numbers %s", got)
+	}
+
+	if _, err := Keyword.DeleteByNumbers(other, "2,3"); err == nil {
+		t.Error("expected deleting keywords another chat does not have to fail")
+	}
+	if err := Keyword.EditByNumber(other, []string{"2=stolen"}); err == nil {
+		t.Error("expected editing a keyword another chat does not have to fail")
+	}
+	if result, err := Keyword.DeleteByNumbers(other, "1"); err != nil || result != "1:打印机" {
+		t.Errorf("DeleteByNumbers() = %q, %v", result, err)
+	}
+	group, _ := KeywordGroup.Add(other, "stolen")
+	if n, _ := Keyword.MoveToGroup(other, group.ID, []int32{1, 2}); n != 0 {
+		t.Errorf("expected no keyword of another chat moved, got %d", n)
+	}
+	if kws := Keyword.GetByUserIdAndType(userId, model.PROJECT); len(kws) != 2 || kws[1].Keyword != "交换机" ||
+		kws[0].GroupID != nil {
+		t.Errorf("expected the keywords untouched, got %+v", kws)
+	}
+
+	// A deleted number is not handed out again.
+	Keyword.Insert([]string{"路由器"}, other, model.PROJECT)
+	if got := numbers(other); len(got) != 1 || got[0] != 2 {
+		t.Errorf("expected number 2, got %v", got)
+	}
+}
+
+// TestKeyword_NumberTaken verifies that keywords are added again when a
+// concurrent insert took their numbers.
+func TestKeyword_NumberTaken(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "keyword_taken.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.Keyword{})
+	SetDefault(db)
+
+	userId := int64(5151)
+	raced := false
+	// The first insert finds the number it was handed taken meanwhile.
+	_ = db.Callback().Create().Before("gorm:create").Register("test:race", func(tx *gorm.DB) {
+		if tx.Statement.Table == model.TableNameKeyword && !raced {
+			raced = true
+			tx.Session(&gorm.Session{NewDB: true}).Exec(
+				"INSERT INTO `keywords` (`created_at`, `keyword`, `user_id`, `type`, `counter`, `number`) VALUES (CURRENT_TIMESTAMP, 'other', ?, 1, 0, 1)",
+				userId)
+		}
+	})
+	if result := Keyword.Insert([]string{"服务器"}, userId, model.PROJECT); result != "服务器" {
+		t.Fatalf("Insert() = %q", result)
+	}
+	if kws := Keyword.GetByUserIdAndType(userId, model.PROJECT); !raced || len(kws) != 1 || kws[0].Number != 1 {
+		t.Errorf("expected the keyword added after the clash, got %+v", kws)
+	}
+}
+
+// TestKeyword_DeleteByNumbersRollsBack verifies that the keywords are kept
+// when their hits cannot be deleted.
+func TestKeyword_DeleteByNumbersRollsBack(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "keyword_delete.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// No keyword_hits table, deleting the hits fails.
+	_ = db.AutoMigrate(&model.Keyword{})
+	SetDefault(db)
+
+	userId := int64(6161)
+	Keyword.Insert([]string{"服务器"}, userId, model.PROJECT)
+	if _, err := Keyword.DeleteByNumbers(userId, "1"); err == nil {
+		t.Fatal("expected deleting the hits to fail")
+	}
+	if kws := Keyword.GetByUserIdAndType(userId, model.PROJECT); len(kws) != 1 {
+		t.Errorf("expected the keyword kept, got %+v", kws)
+	}
+}
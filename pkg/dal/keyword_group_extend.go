@@ -0,0 +1,94 @@
+package dal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+// Add creates the group name for userId, the name must be unique per user.
+func (g *keywordGroup) Add(userId int64, name string) (*model.KeywordGroup, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("empty group name")
+	}
+	if count, err := g.Where(g.UserID.Eq(userId), g.Name.Eq(name)).Count(); err != nil {
+		return nil, err
+	} else if count > 0 {
+		return nil, fmt.Errorf("group %s already exists", name)
+	}
+	group := &model.KeywordGroup{
+		UserID:    userId,
+		Name:      name,
+		CreatedAt: time.Now(),
+	}
+	if err := g.Create(group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// GetByUserId returns the groups of userId in the order they were created.
+func (g *keywordGroup) GetByUserId(userId int64) []*model.KeywordGroup {
+	if result, err := g.Where(g.UserID.Eq(userId)).Order(g.ID).Find(); err == nil {
+		return result
+	}
+	return nil
+}
+
+// GetById returns the group id if it belongs to userId.
+func (g *keywordGroup) GetById(userId int64, id int32) (*model.KeywordGroup, error) {
+	return g.Where(g.ID.Eq(id), g.UserID.Eq(userId)).First()
+}
+
+// Get returns the group id of any user.
+func (g *keywordGroup) Get(id int32) (*model.KeywordGroup, error) {
+	return g.Where(g.ID.Eq(id)).First()
+}
+
+// Rename renames the group id of userId.
+func (g *keywordGroup) Rename(userId int64, id int32, name string) error {
+	group, err := g.GetById(userId, id)
+	if err != nil {
+		return err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("empty group name")
+	}
+	if count, err := g.Where(g.UserID.Eq(userId), g.Name.Eq(name), g.ID.Neq(id)).Count(); err != nil {
+		return err
+	} else if count > 0 {
+		return fmt.Errorf("group %s already exists", name)
+	}
+	_, err = g.Where(g.ID.Eq(*group.ID)).Update(g.Name, name)
+	return err
+}
+
+// Remove deletes the group id of userId, its keywords are kept ungrouped.
+func (g *keywordGroup) Remove(userId int64, id int32) error {
+	group, err := g.GetById(userId, id)
+	if err != nil {
+		return err
+	}
+	return Q.Transaction(func(tx *Query) error {
+		k := tx.Keyword
+		if _, err := k.Where(k.GroupID.Eq(*group.ID)).Update(k.GroupID, nil); err != nil {
+			return err
+		}
+		_, err := tx.KeywordGroup.Where(tx.KeywordGroup.ID.Eq(*group.ID)).Delete()
+		return err
+	})
+}
+
+// MoveToGroup moves the keywords numbers of userId into groupId, nil moves
+// them out of their group. It returns how many keywords were moved.
+func (k *keyword) MoveToGroup(userId int64, groupId *int32, numbers []int32) (int64, error) {
+	if len(numbers) == 0 {
+		return 0, nil
+	}
+	info, err := k.Where(k.UserID.Eq(userId), k.Number.In(numbers...)).Update(k.GroupID, groupId)
+	return info.RowsAffected, err
+}
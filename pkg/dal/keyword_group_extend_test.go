@@ -0,0 +1,64 @@
+package dal
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gythialy/magnet/pkg/model"
+	"gorm.io/gorm"
+)
+
+func TestKeywordGroup(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "keyword_group.db")
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.Keyword{}, &model.KeywordGroup{})
+	SetDefault(db)
+
+	id, other := int64(1111), int64(2222)
+	group, err := KeywordGroup.Add(id, "network gear")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := KeywordGroup.Add(id, "network gear"); err == nil {
+		t.Error("expected a duplicate group name to be rejected")
+	}
+	if _, err := KeywordGroup.Add(other, "network gear"); err != nil {
+		t.Errorf("expected the name to be free for another user, got %v", err)
+	}
+	if err := KeywordGroup.Rename(other, *group.ID, "stolen"); err == nil {
+		t.Error("expected renaming a group of another user to fail")
+	}
+	if err := KeywordGroup.Rename(id, *group.ID, "competitors"); err != nil {
+		t.Fatal(err)
+	}
+
+	Keyword.Insert([]string{"交换机", "路由器"}, id, model.PROJECT)
+	Keyword.Insert([]string{"防火墙"}, other, model.PROJECT)
+	var numbers []int32
+	for _, kw := range append(Keyword.GetByUserIdAndType(id, model.PROJECT), Keyword.GetByUserIdAndType(other, model.PROJECT)...) {
+		numbers = append(numbers, kw.Number)
+	}
+	if n, err := Keyword.MoveToGroup(id, group.ID, numbers); err != nil || n != 2 {
+		t.Errorf("expected the 2 keywords of the owner to be moved, got %d, %v", n, err)
+	}
+	if n, err := Keyword.MoveToGroup(id, nil, numbers[:1]); err != nil || n != 1 {
+		t.Errorf("expected 1 keyword to be ungrouped, got %d, %v", n, err)
+	}
+
+	if err := KeywordGroup.Remove(id, *group.ID); err != nil {
+		t.Fatal(err)
+	}
+	groups := KeywordGroup.GetByUserId(id)
+	if len(groups) != 0 {
+		t.Errorf("expected no group left, got %+v", groups)
+	}
+	for _, kw := range Keyword.GetByUserIdAndType(id, model.PROJECT) {
+		if kw.GroupID != nil {
+			t.Errorf("expected %s to be ungrouped, got group %d", kw.Keyword, *kw.GroupID)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package dal
+
+import (
+	"slices"
+	"time"
+
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+// Record stores the hits of keywords on notices, a notice already counted for
+// a keyword, e.g. on /retry, is not counted again.
+func (h *keywordHit) Record(hits []*model.KeywordHit) error {
+	type hitKey struct {
+		keywordId int32
+		url       string
+	}
+	if len(hits) == 0 {
+		return nil
+	}
+	var ids []int32
+	urls := make([]string, 0, len(hits))
+	for _, v := range hits {
+		if !slices.Contains(ids, v.KeywordID) {
+			ids = append(ids, v.KeywordID)
+		}
+		urls = append(urls, v.URL)
+	}
+	seen := make(map[hitKey]bool)
+	for start := 0; start < len(urls); start += batchSize {
+		end := min(start+batchSize, len(urls))
+		stored, err := h.Select(h.KeywordID, h.URL).Where(h.KeywordID.In(ids...), h.URL.In(urls[start:end]...)).Find()
+		if err != nil {
+			return err
+		}
+		for _, v := range stored {
+			seen[hitKey{v.KeywordID, v.URL}] = true
+		}
+	}
+	var data []*model.KeywordHit
+	for _, v := range hits {
+		if k := (hitKey{v.KeywordID, v.URL}); !seen[k] {
+			seen[k] = true
+			data = append(data, v)
+		}
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	return h.CreateInBatches(data, batchSize)
+}
+
+// CountByKeyword returns the number of hits of every keyword of userId since
+// since, keyed by keyword id. Keywords without hits are left out.
+func (h *keywordHit) CountByKeyword(userId int64, since time.Time) (map[int32]int64, error) {
+	var rows []struct {
+		KeywordID int32
+		Count     int64
+	}
+	if err := h.Select(h.KeywordID, h.ID.Count().As("count")).
+		Where(h.UserID.Eq(userId), h.HitAt.Gte(since)).
+		Group(h.KeywordID).Scan(&rows); err != nil {
+		return nil, err
+	}
+	counts := make(map[int32]int64, len(rows))
+	for _, r := range rows {
+		counts[r.KeywordID] = r.Count
+	}
+	return counts, nil
+}
+
+// Since returns the hits of the keywords of userId since since.
+func (h *keywordHit) Since(userId int64, since time.Time) ([]*model.KeywordHit, error) {
+	return h.Where(h.UserID.Eq(userId), h.HitAt.Gte(since)).Find()
+}
+
+// Prune deletes the hits recorded before before and returns how many were
+// deleted.
+func (h *keywordHit) Prune(before time.Time) (int64, error) {
+	info, err := h.Where(h.HitAt.Lt(before)).Delete()
+	return info.RowsAffected, err
+}
@@ -0,0 +1,69 @@
+package dal
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gythialy/magnet/pkg/model"
+	"gorm.io/gorm"
+)
+
+func TestKeywordHit(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "keyword_hit.db")
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.Keyword{}, &model.KeywordHit{})
+	SetDefault(db)
+
+	id := int64(1111)
+	now := time.Now()
+	hits := []*model.KeywordHit{
+		{KeywordID: 101, UserID: id, URL: "a", HitAt: now.AddDate(0, 0, -10)},
+		{KeywordID: 101, UserID: id, URL: "b", HitAt: now},
+		{KeywordID: 102, UserID: id, URL: "b", HitAt: now},
+		{KeywordID: 103, UserID: 2222, URL: "b", HitAt: now},
+	}
+	if err := KeywordHit.Record(hits); err != nil {
+		t.Fatal(err)
+	}
+	// A notice counted before is not counted again.
+	if err := KeywordHit.Record([]*model.KeywordHit{{KeywordID: 101, UserID: id, URL: "b", HitAt: now}}); err != nil {
+		t.Fatal(err)
+	}
+
+	counts, err := KeywordHit.CountByKeyword(id, now.AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 2 || counts[101] != 1 || counts[102] != 1 {
+		t.Errorf("unexpected counts of the last week %v", counts)
+	}
+	if counts, _ = KeywordHit.CountByKeyword(id, time.Time{}); counts[101] != 2 {
+		t.Errorf("expected 2 hits in total, got %v", counts)
+	}
+	if recent, _ := KeywordHit.Since(id, now.AddDate(0, 0, -1)); len(recent) != 2 {
+		t.Errorf("expected 2 recent hits, got %d", len(recent))
+	}
+
+	if n, err := KeywordHit.Prune(now.AddDate(0, 0, -7)); err != nil || n != 1 {
+		t.Errorf("expected 1 hit pruned, got %d, %v", n, err)
+	}
+	if counts, _ = KeywordHit.CountByKeyword(id, time.Time{}); counts[101] != 1 {
+		t.Errorf("expected 1 hit left, got %v", counts)
+	}
+
+	Keyword.Insert([]string{"test"}, id, model.PROJECT)
+	kw := Keyword.GetByUserIdAndType(id, model.PROJECT)[0]
+	_ = KeywordHit.Record([]*model.KeywordHit{{KeywordID: *kw.ID, UserID: id, URL: "c", HitAt: now}})
+	if _, err := Keyword.DeleteByNumbers(id, fmt.Sprint(kw.Number)); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := KeywordHit.Where(KeywordHit.KeywordID.Eq(*kw.ID)).Count(); n != 0 {
+		t.Errorf("expected the hits of a deleted keyword to be deleted, got %d", n)
+	}
+}
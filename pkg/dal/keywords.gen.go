@@ -36,6 +36,9 @@ func newKeyword(db *gorm.DB, opts ...gen.DOOption) keyword {
 	_keyword.UserID = field.NewInt64(tableName, "user_id")
 	_keyword.Type = field.NewInt32(tableName, "type")
 	_keyword.Counter = field.NewInt32(tableName, "counter")
+	_keyword.GroupID = field.NewInt32(tableName, "group_id")
+	_keyword.Muted = field.NewInt32(tableName, "muted")
+	_keyword.Number = field.NewInt32(tableName, "number")
 
 	_keyword.fillFieldMap()
 
@@ -54,6 +57,9 @@ type keyword struct {
 	UserID    field.Int64
 	Type      field.Int32
 	Counter   field.Int32
+	GroupID   field.Int32
+	Muted     field.Int32
+	Number    field.Int32
 
 	fieldMap map[string]field.Expr
 }
@@ -78,6 +84,9 @@ func (k *keyword) updateTableName(table string) *keyword {
 	k.UserID = field.NewInt64(table, "user_id")
 	k.Type = field.NewInt32(table, "type")
 	k.Counter = field.NewInt32(table, "counter")
+	k.GroupID = field.NewInt32(table, "group_id")
+	k.Muted = field.NewInt32(table, "muted")
+	k.Number = field.NewInt32(table, "number")
 
 	k.fillFieldMap()
 
@@ -94,7 +103,7 @@ func (k *keyword) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
 }
 
 func (k *keyword) fillFieldMap() {
-	k.fieldMap = make(map[string]field.Expr, 8)
+	k.fieldMap = make(map[string]field.Expr, 11)
 	k.fieldMap["id"] = k.ID
 	k.fieldMap["created_at"] = k.CreatedAt
 	k.fieldMap["updated_at"] = k.UpdatedAt
@@ -103,6 +112,9 @@ func (k *keyword) fillFieldMap() {
 	k.fieldMap["user_id"] = k.UserID
 	k.fieldMap["type"] = k.Type
 	k.fieldMap["counter"] = k.Counter
+	k.fieldMap["group_id"] = k.GroupID
+	k.fieldMap["muted"] = k.Muted
+	k.fieldMap["number"] = k.Number
 }
 
 func (k keyword) clone(db *gorm.DB) keyword {
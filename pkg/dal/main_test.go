@@ -0,0 +1,14 @@
+package dal
+
+import (
+	"os"
+	"testing"
+)
+
+// TestMain removes the database the generated tests open in the working
+// directory, the other tests open theirs under t.TempDir().
+func TestMain(m *testing.M) {
+	code := m.Run()
+	_ = os.Remove(_gen_test_db_name)
+	os.Exit(code)
+}
@@ -0,0 +1,103 @@
+package dal
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+// uniqueKey is a natural key that became a unique index after rows were
+// written without one. The rows duplicating it keep the one ordered first
+// by keep.
+type uniqueKey struct {
+	table   string
+	index   string
+	columns string
+	keep    string
+}
+
+var uniqueKeys = []uniqueKey{
+	{model.TableNameCrawlMark, "idx_crawl_marks_source", "source", "marked_at DESC, id DESC"},
+	{model.TableNameNotice, "idx_notices_url", "url", "id DESC"},
+	{model.TableNameChatSetting, "idx_chat_settings_chat_id", "chat_id", "id DESC"},
+	{model.TableNameDigestItem, "idx_digest_items_chat_id_url", "chat_id, url", "id"},
+	{model.TableNameConversation, "idx_conversations_chat_id_user_id", "chat_id, user_id", "id DESC"},
+	// Keywords are renumbered rather than dropped, see renumberKeywords.
+	{model.TableNameKeyword, "idx_keywords_user_id_number", "user_id, number", "id"},
+}
+
+// PrepareUniqueKeys readies a database for the unique indexes AutoMigrate
+// creates: it renumbers the keywords sharing a number, drops the rows
+// duplicating a natural key and the plain index the unique one replaces,
+// AutoMigrate keeps an index of the same name as it is.
+func PrepareUniqueKeys(db *gorm.DB) error {
+	if err := renumberKeywords(db); err != nil {
+		return err
+	}
+	m := db.Migrator()
+	for _, k := range uniqueKeys {
+		if !m.HasTable(k.table) {
+			continue
+		}
+		if err := db.Exec(fmt.Sprintf(
+			"DELETE FROM `%[1]s` WHERE id NOT IN (SELECT id FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY %[2]s ORDER BY %[3]s) AS n FROM `%[1]s`) WHERE n = 1)",
+			k.table, k.columns, k.keep)).Error; err != nil {
+			return err
+		}
+		indexes, err := m.GetIndexes(k.table)
+		if err != nil {
+			return err
+		}
+		for _, idx := range indexes {
+			if unique, _ := idx.Unique(); idx.Name() == k.index && !unique {
+				if err := m.DropIndex(k.table, k.index); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// renumberKeywords numbers the keywords stored before keywords were numbered
+// per user, and those sharing a number with an older keyword of their user,
+// after the keywords of their user in the order they were added.
+func renumberKeywords(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&model.Keyword{}) {
+		return nil
+	}
+	if !m.HasColumn(&model.Keyword{}, "Number") {
+		if err := m.AddColumn(&model.Keyword{}, "Number"); err != nil {
+			return err
+		}
+	}
+	var records []struct {
+		ID     int32
+		UserID int64
+	}
+	if err := db.Raw(fmt.Sprintf(
+		"SELECT id, user_id FROM (SELECT id, user_id, number, ROW_NUMBER() OVER (PARTITION BY user_id, number ORDER BY id) AS n FROM `%s`) WHERE number = 0 OR n > 1 ORDER BY id",
+		model.TableNameKeyword)).Scan(&records).Error; err != nil || len(records) == 0 {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		next := make(map[int64]int32)
+		for _, r := range records {
+			n, ok := next[r.UserID]
+			if !ok {
+				if err := tx.Model(&model.Keyword{}).Unscoped().Where("user_id = ?", r.UserID).
+					Select("COALESCE(MAX(number), 0) + 1").Scan(&n).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Model(&model.Keyword{}).Unscoped().Where("id = ?", r.ID).UpdateColumn("number", n).Error; err != nil {
+				return err
+			}
+			next[r.UserID] = n + 1
+		}
+		return nil
+	})
+}
@@ -0,0 +1,132 @@
+package dal
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gythialy/magnet/pkg/model"
+	"gorm.io/gorm"
+)
+
+// TestPrepareUniqueKeys verifies that a database written before the natural
+// keys were unique gets its duplicates dropped and the unique indexes.
+func TestPrepareUniqueKeys(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "migrate.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Truncate(time.Second)
+	for _, stmt := range []string{
+		"CREATE TABLE `crawl_marks` (`id` integer PRIMARY KEY, `source` text NOT NULL, `marked_at` datetime NOT NULL, `updated_at` datetime NOT NULL)",
+		"CREATE INDEX `idx_crawl_marks_source` ON `crawl_marks`(`source`)",
+		"CREATE TABLE `notices` (`id` integer PRIMARY KEY, `url` text NOT NULL, `title` text NOT NULL, `published_at` datetime NOT NULL, `crawled_at` datetime NOT NULL)",
+		"CREATE INDEX `idx_notices_url` ON `notices`(`url`)",
+		"INSERT INTO `notices` (`url`, `title`, `published_at`, `crawled_at`) VALUES ('a', 'old', 0, 0), ('a', 'new', 0, 0), ('b', 'b', 0, 0)",
+		"CREATE TABLE `conversations` (`id` integer PRIMARY KEY, `chat_id` integer NOT NULL, `user_id` integer NOT NULL, `dialog` text NOT NULL, `step` integer NOT NULL, `expires_at` datetime NOT NULL, `updated_at` datetime NOT NULL)",
+		"CREATE INDEX `idx_conversations_chat_id_user_id` ON `conversations`(`chat_id`, `user_id`)",
+		"CREATE TABLE `chat_settings` (`id` integer PRIMARY KEY, `chat_id` integer NOT NULL, `delivery` text NOT NULL DEFAULT '', `updated_at` datetime NOT NULL)",
+		"CREATE INDEX `idx_chat_settings_chat_id` ON `chat_settings`(`chat_id`)",
+		"INSERT INTO `chat_settings` (`chat_id`, `delivery`, `updated_at`) VALUES (1, 'daily 08:00', 0), (1, 'hourly', 0)",
+		"CREATE TABLE `digest_items` (`id` integer PRIMARY KEY, `chat_id` integer NOT NULL, `url` text NOT NULL, `history` text NOT NULL, `queued_at` datetime NOT NULL)",
+		"CREATE INDEX `idx_digest_items_chat_id_url` ON `digest_items`(`chat_id`, `url`)",
+		"INSERT INTO `digest_items` (`chat_id`, `url`, `history`, `queued_at`) VALUES (1, 'a', 'first', 0), (1, 'a', 'second', 0)",
+		"INSERT INTO `conversations` (`chat_id`, `user_id`, `dialog`, `step`, `expires_at`, `updated_at`) VALUES (1, 2, 'old', 0, 0, 0), (1, 2, 'new', 1, 0, 0), (1, 3, 'other', 0, 0, 0)",
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, mark := range []time.Time{now, now.Add(time.Hour), now.Add(-time.Hour)} {
+		if err := db.Exec("INSERT INTO `crawl_marks` (`source`, `marked_at`, `updated_at`) VALUES ('a', ?, ?)", mark, now).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := PrepareUniqueKeys(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.CrawlMark{}, &model.Notice{}, &model.Conversation{}, &model.ChatSetting{},
+		&model.DigestItem{}); err != nil {
+		t.Fatal(err)
+	}
+	SetDefault(db)
+	if mark, err := CrawlMark.Get("a"); err != nil || !mark.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected the newest mark kept, got %s, %v", mark, err)
+	}
+	if err := db.Create(&model.CrawlMark{Source: "a", MarkedAt: now, UpdatedAt: now}).Error; err == nil {
+		t.Error("expected the source to be unique")
+	}
+	if notices, _ := Notice.Order(Notice.URL).Find(); len(notices) != 2 || notices[0].Title != "new" {
+		t.Errorf("expected the newest copy of a notice kept, got %+v", notices)
+	}
+	if conv, _ := Conversation.Where(Conversation.ChatID.Eq(1), Conversation.UserID.Eq(2)).Find(); len(conv) != 1 ||
+		conv[0].Dialog != "new" {
+		t.Errorf("expected the newest conversation kept, got %+v", conv)
+	}
+	if s, _ := ChatSetting.Find(); len(s) != 1 || s[0].Delivery != "hourly" {
+		t.Errorf("expected the newest settings kept, got %+v", s)
+	}
+	// The first copy of a queued notice was queued first.
+	if items, _ := DigestItem.Find(); len(items) != 1 || items[0].History != "first" {
+		t.Errorf("expected the first queued notice kept, got %+v", items)
+	}
+	// Preparing a migrated database changes nothing.
+	if err := PrepareUniqueKeys(db); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := CrawlMark.Count(); n != 1 {
+		t.Errorf("expected 1 mark, got %d", n)
+	}
+}
+
+// TestPrepareUniqueKeys_Keywords verifies that keywords stored before they
+// were numbered, or sharing a number, are numbered after the keywords of
+// their user instead of dropped.
+func TestPrepareUniqueKeys_Keywords(t *testing.T) {
+	for name, stmts := range map[string][]string{
+		"unnumbered": {
+			"CREATE TABLE `keywords` (`id` integer PRIMARY KEY, `created_at` datetime NOT NULL, `deleted_at` datetime, `keyword` text NOT NULL, `user_id` integer NOT NULL, `type` integer NOT NULL, `counter` integer NOT NULL)",
+			"INSERT INTO `keywords` (`created_at`, `keyword`, `user_id`, `type`, `counter`) VALUES (CURRENT_TIMESTAMP, 'a', 1, 1, 0), (CURRENT_TIMESTAMP, 'b', 1, 1, 0), (CURRENT_TIMESTAMP, 'c', 1, 2, 0), (CURRENT_TIMESTAMP, 'd', 2, 1, 0)",
+		},
+		"shared": {
+			"CREATE TABLE `keywords` (`id` integer PRIMARY KEY, `created_at` datetime NOT NULL, `deleted_at` datetime, `keyword` text NOT NULL, `user_id` integer NOT NULL, `type` integer NOT NULL, `counter` integer NOT NULL, `number` integer NOT NULL DEFAULT 0)",
+			"CREATE INDEX `idx_keywords_user_id_number` ON `keywords`(`user_id`, `number`)",
+			"INSERT INTO `keywords` (`created_at`, `keyword`, `user_id`, `type`, `counter`, `number`) VALUES (CURRENT_TIMESTAMP, 'a', 1, 1, 0, 1), (CURRENT_TIMESTAMP, 'b', 1, 1, 0, 1), (CURRENT_TIMESTAMP, 'c', 1, 2, 0, 0), (CURRENT_TIMESTAMP, 'd', 2, 1, 0, 0)",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "keywords.db")), &gorm.Config{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, stmt := range stmts {
+				if err := db.Exec(stmt).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := PrepareUniqueKeys(db); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.AutoMigrate(&model.Keyword{}); err != nil {
+				t.Fatal(err)
+			}
+			SetDefault(db)
+			var got []string
+			if kws, err := Keyword.Order(Keyword.ID).Find(); err == nil {
+				for _, kw := range kws {
+					got = append(got, fmt.Sprintf("%d:%s:%d", kw.UserID, kw.Keyword, kw.Number))
+				}
+			}
+			if s := strings.Join(got, " "); s != "1:a:1 1:b:2 1:c:3 2:d:1" {
+				t.Errorf("unexpected numbers %s", s)
+			}
+			if err := db.Create(&model.Keyword{Keyword: "e", UserID: 1, Number: 1}).Error; err == nil {
+				t.Error("expected the number to be unique per user")
+			}
+		})
+	}
+}
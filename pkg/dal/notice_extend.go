@@ -0,0 +1,34 @@
+package dal
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+// Save stores crawled notices, replacing the stored copy of a notice that
+// was crawled before. The url is unique, a notice saved by two runs at once
+// is stored once.
+func (n *notice) Save(notices []*model.Notice) error {
+	if len(notices) == 0 {
+		return nil
+	}
+	return n.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: n.URL.ColumnName().String()}},
+		UpdateAll: true,
+	}).CreateInBatches(notices, batchSize)
+}
+
+// Since returns the notices published at or after since, newest first.
+func (n *notice) Since(since time.Time) ([]*model.Notice, error) {
+	return n.Where(n.PublishedAt.Gte(since)).Order(n.PublishedAt.Desc()).Find()
+}
+
+// Prune deletes the notices published before before and returns how many
+// were deleted.
+func (n *notice) Prune(before time.Time) (int64, error) {
+	info, err := n.Where(n.PublishedAt.Lt(before)).Delete()
+	return info.RowsAffected, err
+}
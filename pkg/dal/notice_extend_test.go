@@ -0,0 +1,52 @@
+package dal
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gythialy/magnet/pkg/model"
+	"gorm.io/gorm"
+)
+
+func TestNotice_Save(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "notice.db")
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.Notice{})
+	SetDefault(db)
+
+	dao := Notice
+	now := time.Now().Truncate(time.Second)
+	if err := dao.Save([]*model.Notice{
+		{URL: "a", Title: "old a", PublishedAt: now.Add(-48 * time.Hour), CrawledAt: now},
+		{URL: "b", Title: "b", PublishedAt: now.Add(-time.Hour), CrawledAt: now},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if err := dao.Save([]*model.Notice{
+			{URL: "a", Title: "new a", PublishedAt: now, CrawledAt: now},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notices, err := dao.Since(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notices) != 2 || notices[0].Title != "new a" || notices[1].URL != "b" {
+		t.Errorf("expected the replaced notice first, got %+v", notices)
+	}
+
+	if n, err := dao.Prune(now.Add(-30 * time.Minute)); err != nil || n != 1 {
+		t.Errorf("expected 1 pruned notice, got %d, %v", n, err)
+	}
+	if n, _ := dao.Count(); n != 1 {
+		t.Errorf("expected 1 notice left, got %d", n)
+	}
+}
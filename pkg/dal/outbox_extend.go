@@ -0,0 +1,31 @@
+package dal
+
+import (
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+// Chats returns the chats with messages waiting in the outbox.
+func (o *outbox) Chats() ([]int64, error) {
+	var chats []int64
+	err := o.Distinct(o.ChatID).Pluck(o.ChatID, &chats)
+	return chats, err
+}
+
+// GetByChatId returns the messages waiting for chatId in the order they
+// were queued.
+func (o *outbox) GetByChatId(chatId int64) ([]*model.Outbox, error) {
+	return o.Where(o.ChatID.Eq(chatId)).Order(o.ID).Find()
+}
+
+// Remove drops a delivered or abandoned message.
+func (o *outbox) Remove(id int32) error {
+	_, err := o.Where(o.ID.Eq(id)).Delete()
+	return err
+}
+
+// Waiting reports whether messages to chatId over notifier wait in the
+// outbox.
+func (o *outbox) Waiting(chatId int64, notifier string) (bool, error) {
+	count, err := o.Where(o.ChatID.Eq(chatId), o.Notifier.Eq(notifier)).Count()
+	return count > 0, err
+}
@@ -0,0 +1,91 @@
+package dal
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gythialy/magnet/pkg/model"
+	"gorm.io/gorm"
+)
+
+func TestOutbox(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "outbox.db")
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.Outbox{})
+	SetDefault(db)
+
+	chatId, other := int64(-100777), int64(8)
+	for _, msg := range []*model.Outbox{
+		{ChatID: chatId, Text: "first", CreatedAt: time.Now()},
+		{ChatID: other, Text: "other", CreatedAt: time.Now()},
+		{ChatID: chatId, Text: "second", CreatedAt: time.Now()},
+		{ChatID: other, Text: "mail", CreatedAt: time.Now(), Notifier: "email:ops@example.com"},
+	} {
+		if err := Outbox.Create(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	chats, err := Outbox.Chats()
+	slices.Sort(chats)
+	if err != nil || !slices.Equal(chats, []int64{chatId, other}) {
+		t.Errorf("unexpected chats %v, %v", chats, err)
+	}
+	held, err := Outbox.GetByChatId(chatId)
+	if err != nil || len(held) != 2 || held[0].Text != "first" || held[1].Text != "second" {
+		t.Fatalf("unexpected messages %v, %v", held, err)
+	}
+	if err := Outbox.Remove(*held[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if held, _ := Outbox.GetByChatId(chatId); len(held) != 1 || held[0].Text != "second" {
+		t.Errorf("expected the second message to be left, got %v", held)
+	}
+	if waiting, err := Outbox.Waiting(chatId, ""); err != nil || !waiting {
+		t.Errorf("expected messages to wait for %d, %v", chatId, err)
+	}
+	if waiting, _ := Outbox.Waiting(12345, ""); waiting {
+		t.Error("expected no messages to wait for 12345")
+	}
+	if waiting, _ := Outbox.Waiting(chatId, "email:ops@example.com"); waiting {
+		t.Errorf("expected no mails to wait for %d", chatId)
+	}
+	if waiting, _ := Outbox.Waiting(other, "email:ops@example.com"); !waiting {
+		t.Errorf("expected a mail to wait for %d", other)
+	}
+}
+
+// TestNotifierMigration verifies that the notifier columns are added to the
+// tables of a database created before them.
+func TestNotifierMigration(t *testing.T) {
+	f := filepath.Join(t.TempDir(), "notifier_migration.db")
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tables := []interface{}{&model.Outbox{}, &model.ChatSetting{}, &model.KeywordGroup{}}
+	for _, table := range tables {
+		if err := db.Migrator().CreateTable(table); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Migrator().DropColumn(table, "notifier"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Exec("INSERT INTO `outbox` (`chat_id`, `text`, `created_at`) VALUES (1, 'queued', ?)", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatal(err)
+	}
+	SetDefault(db)
+	if waiting, err := Outbox.Waiting(1, ""); err != nil || !waiting {
+		t.Errorf("expected the queued message on telegram, got %v, %v", waiting, err)
+	}
+}
@@ -66,6 +66,9 @@ type BotContext struct {
 	processor       *InfoProcessor
 	cmdHandler      *CommandsHandler
 	shutdownWebhook func()
+	// botName is the username of the bot, commands in groups may be
+	// addressed to it as "/command@name".
+	botName string
 }
 
 func NewBotContext() (*BotContext, error) {
@@ -93,7 +96,11 @@ func NewBotContext() (*BotContext, error) {
 		return nil, err
 	}
 
-	err = db.AutoMigrate(&model.Keyword{}, &model.History{}, &model.Alarm{})
+	if err = dal.PrepareUniqueKeys(db); err != nil {
+		return nil, err
+	}
+	err = db.AutoMigrate(&model.Keyword{}, &model.History{}, &model.Alarm{}, &model.CrawlMark{}, &model.Notice{}, &model.KeywordGroup{}, &model.KeywordHit{}, &model.Conversation{},
+		&model.ChatSetting{}, &model.DigestItem{}, &model.Outbox{})
 	if err != nil {
 		return nil, err
 	}
@@ -108,7 +115,7 @@ func NewBotContext() (*BotContext, error) {
 	botContext := &BotContext{
 		ctx:       ctx,
 		cancel:    cancel,
-		scheduler: gocron.NewScheduler(time.FixedZone("CST", 8*60*60)),
+		scheduler: gocron.NewScheduler(cst),
 		Logger:    ctxLogger,
 		Config:    cfg,
 		Store:     NewStore(),
@@ -124,9 +131,13 @@ func NewBotContext() (*BotContext, error) {
 
 	telegramBot, err := bot.New(config.TelegramToken(), []bot.Option{
 		bot.WithDefaultHandler(defaultHandlerInstance.Handler),
-		bot.WithHTTPClient(time.Minute, &http.Client{
+		bot.WithMiddlewares(botContext.chatMiddleware),
+		// The getMe below checks the token and names the bot, bot.New
+		// would only call it to check the token.
+		bot.WithSkipGetMe(),
+		bot.WithHTTPClient(time.Minute, NewSender(&http.Client{
 			Timeout: 2 * time.Minute,
-		}),
+		})),
 		// bot.WithDebug(),
 	}...)
 	if err != nil {
@@ -134,6 +145,11 @@ func NewBotContext() (*BotContext, error) {
 	}
 
 	botContext.Bot = telegramBot
+	me, err := telegramBot.GetMe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	botContext.botName = me.Username
 
 	if err = botContext.initBot(); err != nil {
 		return nil, err
@@ -151,10 +167,16 @@ func (ctx *BotContext) initBot() error {
 	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.Debug, bot.MatchTypePrefix, DebugHandler)
 	sHandler := &startHandler{cmdHandler: cmdHandler}
 	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.Start, bot.MatchTypePrefix, sHandler.Handler)
+	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.Cancel, bot.MatchTypePrefix, cmdHandler.CancelHandler)
+	ctx.Bot.RegisterHandlerMatchFunc(cmdHandler.inDialog, cmdHandler.DialogHandler)
+	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.Keywords, bot.MatchTypePrefix, cmdHandler.KeywordsHandler)
+	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.Delivery, bot.MatchTypePrefix, cmdHandler.DeliveryHandler)
+	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.Settings, bot.MatchTypePrefix, cmdHandler.SettingsHandler)
 	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.AddKeyword, bot.MatchTypePrefix, cmdHandler.AddKeywordHandler)
 	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.DeleteKeyword, bot.MatchTypePrefix, cmdHandler.DeleteKeywordHandler)
 	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.EditKeyword, bot.MatchTypePrefix, cmdHandler.EditKeywordHandler)
 	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.AddAlarmKeyword, bot.MatchTypePrefix, cmdHandler.AddAlarmKeywordHandler)
+	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.AddFilter, bot.MatchTypePrefix, cmdHandler.AddFilterHandler)
 	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.SearchAlarmRecords, bot.MatchTypePrefix, cmdHandler.SearchAlarmRecordHandler)
 	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.Alarm, bot.MatchTypePrefix, cmdHandler.AlarmRecordHandler)
 	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.SearchHistory, bot.MatchTypePrefix, cmdHandler.SearchHistoryHandler)
@@ -162,9 +184,22 @@ func (ctx *BotContext) initBot() error {
 	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.ConvertPDF, bot.MatchTypePrefix, cmdHandler.ConvertURLToPDFHandler)
 	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.ConvertIMG, bot.MatchTypePrefix, cmdHandler.ConvertURLToIMGHandler)
 	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.Statistics, bot.MatchTypePrefix, cmdHandler.StaticHandler)
+	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.TestKeyword, bot.MatchTypePrefix, cmdHandler.TestKeywordHandler)
+	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.ExportKeywords, bot.MatchTypePrefix, cmdHandler.ExportKeywordsHandler)
+	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.ImportKeywords, bot.MatchTypePrefix, cmdHandler.ImportKeywordsHandler)
+	ctx.Bot.RegisterHandlerMatchFunc(isImportDocument, cmdHandler.ImportKeywordsHandler)
+	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.KeywordStats, bot.MatchTypePrefix, cmdHandler.KeywordStatsHandler)
+	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.AddGroup, bot.MatchTypePrefix, cmdHandler.AddGroupHandler)
+	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.Groups, bot.MatchTypePrefix, cmdHandler.GroupsHandler)
+	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.RenameGroup, bot.MatchTypePrefix, cmdHandler.RenameGroupHandler)
+	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.SetGroup, bot.MatchTypePrefix, cmdHandler.SetGroupHandler)
+	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.MoveKeywords, bot.MatchTypePrefix, cmdHandler.MoveKeywordsHandler)
+	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.DeleteGroup, bot.MatchTypePrefix, cmdHandler.DeleteGroupHandler)
 	ctx.Bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, constant.SearchCallback, bot.MatchTypePrefix, cmdHandler.HandleCallbackQuery)
 	ctx.Bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, constant.AlarmCallback, bot.MatchTypePrefix, cmdHandler.HandleCallbackQuery)
 	ctx.Bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, constant.TodayCallback, bot.MatchTypePrefix, cmdHandler.HandleCallbackQuery)
+	ctx.Bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, constant.TestCallback, bot.MatchTypePrefix, cmdHandler.HandleCallbackQuery)
+	ctx.Bot.RegisterHandler(bot.HandlerTypeCallbackQueryData, constant.KeywordsCallback, bot.MatchTypePrefix, cmdHandler.HandleCallbackQuery)
 
 	managerHandler := NewManagerHandler(ctx)
 	ctx.Bot.RegisterHandler(bot.HandlerTypeMessageText, constant.Retry, bot.MatchTypePrefix, managerHandler.Retry)
@@ -179,6 +214,12 @@ func (ctx *BotContext) initBot() error {
 	}
 }
 
+// RegisterNoticeSource adds a notice source whose results are merged into
+// every scheduled run. It must be called before Start.
+func (ctx *BotContext) RegisterNoticeSource(source NoticeSource) {
+	ctx.processor.crawler.RegisterSource(source)
+}
+
 func (ctx *BotContext) Start() {
 	scheduleInterval := config.ScheduleInterval()
 
@@ -202,6 +243,21 @@ func (ctx *BotContext) Start() {
 		}),
 	)
 
+	// Digests are due on the minute, retries of the outbox on the second;
+	// these jobs send what became due since their last run.
+	if _, err := ctx.scheduler.Every(1).Minutes().Name("send_digest").SingletonMode().Do(func() error {
+		ctx.processor.SendDigests(time.Now())
+		return nil
+	}); err != nil {
+		ctx.Logger.Error().Stack().Err(err).Msg("")
+	}
+	if _, err := ctx.scheduler.Every(10).Seconds().Name("send_outbox").SingletonMode().Do(func() error {
+		ctx.processor.SendOutbox(time.Now())
+		return nil
+	}); err != nil {
+		ctx.Logger.Error().Stack().Err(err).Msg("")
+	}
+
 	ctx.scheduler.StartAsync()
 	ctx.startWebhookServer()
 	go ctx.Bot.Start(ctx.ctx)
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gythialy/magnet/pkg/constant"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// adminCacheDuration is how long an admin check of a group member is reused.
+const adminCacheDuration = time.Minute
+
+var (
+	// adminCommands change the subscriptions of a chat, in groups only its
+	// admins may run them.
+	adminCommands = []string{
+		constant.AddKeyword, constant.EditKeyword, constant.DeleteKeyword, constant.AddAlarmKeyword,
+		constant.AddFilter, constant.ImportKeywords, constant.AddGroup, constant.RenameGroup,
+		constant.SetGroup, constant.MoveKeywords, constant.DeleteGroup, constant.Delivery,
+		constant.Settings,
+	}
+	// privateCommands show details of the chat or the bot and only run in
+	// private chats.
+	privateCommands = []string{constant.Me, constant.Debug}
+	// adminCallbacks are the /keywords buttons changing a keyword.
+	adminCallbacks = []string{kwDelete, kwConfirmDelete, kwEdit, kwMute, kwGroups, kwMove}
+)
+
+// splitCommand splits "/cmd@bot args", up to the first white space, into the command and the bot it is
+// addressed to, empty when the command is not addressed.
+func splitCommand(text string) (string, string) {
+	cmd := text
+	if i := strings.IndexFunc(text, unicode.IsSpace); i >= 0 {
+		cmd = text[:i]
+	}
+	cmd, name, _ := strings.Cut(cmd, "@")
+	return cmd, name
+}
+
+// stripBotName drops the "@bot" suffix of the command in text, handlers match
+// and trim the bare command.
+func stripBotName(text, name string) string {
+	cmd, _ := splitCommand(text)
+	return cmd + text[len(cmd)+1+len(name):]
+}
+
+// hasCommand reports whether text runs one of commands. Handlers are
+// registered with bot.MatchTypePrefix, so this matches the same way and
+// "/add_keywords\tx" or "/delete_keywords1" count as the command too.
+func hasCommand(commands []string, text string) bool {
+	return slices.ContainsFunc(commands, func(cmd string) bool {
+		return strings.HasPrefix(text, cmd)
+	})
+}
+
+// messageText is the text of msg, the caption of a document.
+func messageText(msg *models.Message) *string {
+	if msg.Text == "" && msg.Caption != "" {
+		return &msg.Caption
+	}
+	return &msg.Text
+}
+
+// chatMiddleware makes commands work in groups: commands addressed to another
+// bot are dropped, the "@bot" suffix of our own is removed, and commands
+// changing the subscriptions of a group are left to its admins.
+func (ctx *BotContext) chatMiddleware(next bot.HandlerFunc) bot.HandlerFunc {
+	return func(c context.Context, b *bot.Bot, update *models.Update) {
+		if msg := update.Message; msg != nil {
+			text := messageText(msg)
+			if strings.HasPrefix(*text, "/") {
+				_, name := splitCommand(*text)
+				if name != "" {
+					if !strings.EqualFold(name, ctx.botName) {
+						return
+					}
+					*text = stripBotName(*text, name)
+				}
+				if msg.Chat.Type != models.ChatTypePrivate {
+					if hasCommand(privateCommands, *text) {
+						return
+					}
+					if hasCommand(adminCommands, *text) && !ctx.isChatAdmin(c, b, msg.Chat.ID, msg.From, msg.SenderChat) {
+						ctx.reply(c, b, msg, "Only the admins of this chat can change its keywords.")
+						return
+					}
+				}
+			}
+		}
+		if query := update.CallbackQuery; query != nil && query.Message.Message != nil {
+			msg := query.Message.Message
+			if msg.Chat.Type != models.ChatTypePrivate && keywordCallbackChanges(query.Data) &&
+				!ctx.isChatAdmin(c, b, msg.Chat.ID, &query.From, nil) {
+				if _, err := b.AnswerCallbackQuery(c, &bot.AnswerCallbackQueryParams{
+					CallbackQueryID: query.ID,
+					Text:            "Only the admins of this chat can change its keywords.",
+					ShowAlert:       true,
+				}); err != nil {
+					ctx.Logger.Error().Stack().Err(err).Msg("")
+				}
+				return
+			}
+		}
+		next(c, b, update)
+	}
+}
+
+// keywordCallbackChanges reports whether the callback data of a /keywords
+// button changes a keyword.
+func keywordCallbackChanges(data string) bool {
+	rest, ok := strings.CutPrefix(data, constant.KeywordsCallback)
+	if !ok {
+		return false
+	}
+	parts := strings.Split(rest, ":")
+	return len(parts) > 1 && slices.Contains(adminCallbacks, parts[1])
+}
+
+// isChatAdmin reports whether the sender of a message in chatId is an admin
+// of it. An anonymous admin sends as the chat itself.
+func (ctx *BotContext) isChatAdmin(c context.Context, b *bot.Bot, chatId int64, from *models.User,
+	senderChat *models.Chat,
+) bool {
+	if senderChat != nil {
+		return senderChat.ID == chatId
+	}
+	if from == nil {
+		return false
+	}
+	key := fmt.Sprintf("admin:%d:%d", chatId, from.ID)
+	if v, ok := ctx.Store.Get(key); ok {
+		return v.(bool)
+	}
+	admin, err := isAdmin(c, b, chatId, from.ID)
+	if err != nil {
+		ctx.Logger.Error().Stack().Err(err).Msgf("get member %d of %d failed", from.ID, chatId)
+		return false
+	}
+	ctx.Store.Set(key, admin, adminCacheDuration)
+	return admin
+}
+
+func isAdmin(c context.Context, b *bot.Bot, chatId, userId int64) (bool, error) {
+	member, err := b.GetChatMember(c, &bot.GetChatMemberParams{ChatID: chatId, UserID: userId})
+	if err != nil {
+		return false, err
+	}
+	return member.Type == models.ChatMemberTypeOwner || member.Type == models.ChatMemberTypeAdministrator, nil
+}
+
+// checkPushTarget checks that userId may send the pushes of a keyword group
+// to chatId: userId must be an admin of it and the bot must be able to post
+// there, as an admin allowed to post in a channel.
+func checkPushTarget(c context.Context, b *bot.Bot, chatId, userId int64) error {
+	if chatId == userId {
+		return nil
+	}
+	chat, err := b.GetChat(c, &bot.GetChatParams{ChatID: chatId})
+	if err != nil {
+		return fmt.Errorf("chat %d not found, add the bot to it first", chatId)
+	}
+	if admin, err := isAdmin(c, b, chatId, userId); err != nil || !admin {
+		return fmt.Errorf("you are not an admin of chat %d", chatId)
+	}
+	member, err := b.GetChatMember(c, &bot.GetChatMemberParams{ChatID: chatId, UserID: b.ID()})
+	if err != nil {
+		return fmt.Errorf("the bot is not in chat %d, add it first", chatId)
+	}
+	if chat.Type == models.ChatTypeChannel {
+		if member.Type == models.ChatMemberTypeAdministrator && member.Administrator.CanPostMessages {
+			return nil
+		}
+		return fmt.Errorf("the bot can not post to channel %d, make it an admin allowed to post", chatId)
+	}
+	switch member.Type {
+	case models.ChatMemberTypeOwner, models.ChatMemberTypeAdministrator, models.ChatMemberTypeMember:
+		return nil
+	}
+	return fmt.Errorf("the bot is not in chat %d, add it first", chatId)
+}
+
+// resolveChat turns the "@name" of a public chat into its id.
+func resolveChat(c context.Context, b *bot.Bot, value string) (string, error) {
+	if !strings.HasPrefix(value, "@") {
+		return value, nil
+	}
+	chat, err := b.GetChat(c, &bot.GetChatParams{ChatID: value})
+	if err != nil {
+		return "", fmt.Errorf("chat %s not found", value)
+	}
+	return fmt.Sprint(chat.ID), nil
+}
+
+func (ctx *BotContext) reply(c context.Context, b *bot.Bot, msg *models.Message, text string) {
+	if _, err := b.SendMessage(c, &bot.SendMessageParams{
+		ChatID:          msg.Chat.ID,
+		Text:            text,
+		ReplyParameters: &models.ReplyParameters{MessageID: msg.ID},
+	}); err != nil {
+		ctx.Logger.Error().Stack().Err(err).Msg("")
+	}
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+func TestSplitCommand(t *testing.T) {
+	for text, want := range map[string][2]string{
+		"/add_keywords@magnet_bot 服务器": {"/add_keywords", "magnet_bot"},
+		"/keywords":                    {"/keywords", ""},
+		"/set_group 1 label=a@b":       {"/set_group", ""},
+		"/import_keywords@bot\nx":      {"/import_keywords", "bot"},
+		"/settings@bot\tnotify=x":      {"/settings", "bot"},
+	} {
+		if cmd, name := splitCommand(text); cmd != want[0] || name != want[1] {
+			t.Errorf("splitCommand(%q) = %q, %q, want %q", text, cmd, name, want)
+		}
+	}
+	if got := stripBotName("/add_keywords@magnet_bot 服务器", "magnet_bot"); got != "/add_keywords 服务器" {
+		t.Errorf("stripBotName() = %q", got)
+	}
+}
+
+func TestChatMiddleware(t *testing.T) {
+	const group, admin, member = int64(-1001), int64(100), int64(200)
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := path.Base(r.URL.Path)
+		mu.Lock()
+		calls[method]++
+		mu.Unlock()
+		switch method {
+		case "getChatMember":
+			status := "member"
+			if r.FormValue("user_id") == "100" {
+				status = "administrator"
+			}
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"status":"` + status + `","user":{"id":1}}}`))
+		case "answerCallbackQuery":
+			_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+		default:
+			_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1,"chat":{"id":1}}}`))
+		}
+	}))
+	defer ts.Close()
+	b, err := bot.New("1:token", bot.WithSkipGetMe(), bot.WithServerURL(ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := testBotContext("")
+	ctx.Store, ctx.botName = NewStore(), "Magnet_Bot"
+
+	var got *models.Update
+	handler := ctx.chatMiddleware(func(_ context.Context, _ *bot.Bot, update *models.Update) { got = update })
+	message := func(chatType models.ChatType, from int64, text string) *models.Update {
+		chatId := group
+		if chatType == models.ChatTypePrivate {
+			chatId = from
+		}
+		return &models.Update{Message: &models.Message{
+			Chat: models.Chat{ID: chatId, Type: chatType},
+			From: &models.User{ID: from},
+			Text: text,
+		}}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		update *models.Update
+		text   string // the text passed on, empty when dropped
+	}{
+		{"other bot", message(models.ChatTypeSupergroup, admin, "/add_keywords@other_bot 服务器"), ""},
+		{"addressed", message(models.ChatTypeSupergroup, admin, "/add_keywords@magnet_bot 服务器"), "/add_keywords 服务器"},
+		{"not an admin", message(models.ChatTypeSupergroup, member, "/add_keywords 服务器"), ""},
+		{"suffixed", message(models.ChatTypeSupergroup, member, "/delete_keywords1"), ""},
+		{"glued", message(models.ChatTypeSupergroup, member, "/add_keywordsfoo"), ""},
+		{"tab", message(models.ChatTypeSupergroup, member, "/add_keywords\tfoo"), ""},
+		{"addressed suffix", message(models.ChatTypeSupergroup, member, "/settings@magnet_bot\tnotify=x"), ""},
+		{"read only", message(models.ChatTypeSupergroup, member, "/keywords"), "/keywords"},
+		{"private only", message(models.ChatTypeGroup, admin, "/me"), ""},
+		{"private suffixed", message(models.ChatTypeGroup, admin, "/mefoo"), ""},
+		{"private", message(models.ChatTypePrivate, member, "/me"), "/me"},
+		{"private change", message(models.ChatTypePrivate, member, "/delete_keywords 1"), "/delete_keywords 1"},
+	} {
+		got = nil
+		handler(context.Background(), b, tc.update)
+		switch {
+		case tc.text == "" && got != nil:
+			t.Errorf("%s: expected %q dropped", tc.name, tc.update.Message.Text)
+		case tc.text != "" && (got == nil || got.Message.Text != tc.text):
+			t.Errorf("%s: expected %q passed on, got %+v", tc.name, tc.text, got)
+		}
+	}
+	if calls["sendMessage"] != 5 {
+		t.Errorf("expected the member told 5 times, got %d messages", calls["sendMessage"])
+	}
+
+	// An anonymous admin sends as the group, the caption of a document is
+	// a command too.
+	anonymous := message(models.ChatTypeSupergroup, 1087968824, "")
+	anonymous.Message.SenderChat = &models.Chat{ID: group}
+	anonymous.Message.Caption = "/import_keywords@magnet_bot"
+	got = nil
+	handler(context.Background(), b, anonymous)
+	if got == nil || got.Message.Caption != "/import_keywords" {
+		t.Errorf("expected the anonymous admin passed on, got %+v", got)
+	}
+
+	callback := func(from int64, data string) *models.Update {
+		return &models.Update{CallbackQuery: &models.CallbackQuery{
+			ID:      "q",
+			From:    models.User{ID: from},
+			Message: models.MaybeInaccessibleMessage{Message: &models.Message{Chat: models.Chat{ID: group, Type: models.ChatTypeSupergroup}}},
+			Data:    data,
+		}}
+	}
+	for data, passed := range map[string]bool{"kw:1:s:3": true, "kw:1:d:3": false, "kw:2": true, "test:2": true} {
+		got = nil
+		handler(context.Background(), b, callback(member, data))
+		if (got != nil) != passed {
+			t.Errorf("%s: expected passed on %v", data, passed)
+		}
+	}
+	got = nil
+	if handler(context.Background(), b, callback(admin, "kw:1:dy:3")); got == nil {
+		t.Error("expected the admin to delete")
+	}
+	if calls["answerCallbackQuery"] != 1 {
+		t.Errorf("expected the member alerted once, got %d", calls["answerCallbackQuery"])
+	}
+	// Admin checks are cached per chat and user.
+	if calls["getChatMember"] != 2 {
+		t.Errorf("expected 2 member lookups, got %d", calls["getChatMember"])
+	}
+}
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gythialy/magnet/pkg/constant"
+	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// parseQuietHours parses the quiet hours of a chat, schedules as for keyword
+// groups separated by "|", e.g. "22:00-08:00|sat,sun".
+func parseQuietHours(s string) ([]schedule, error) {
+	var quiet []schedule
+	for _, w := range strings.Split(s, "|") {
+		sc, err := parseSchedule(w)
+		if err != nil {
+			return nil, err
+		}
+		quiet = append(quiet, sc)
+	}
+	return quiet, nil
+}
+
+// chatLocation is the timezone of the chat of s, China Standard Time unless
+// it set another one.
+func chatLocation(s *model.ChatSetting) *time.Location {
+	if s.Timezone != "" {
+		if loc, err := time.LoadLocation(s.Timezone); err == nil {
+			return loc
+		}
+	}
+	return cst
+}
+
+// quietAt reports whether now is within the quiet hours of the chat of s.
+func quietAt(s *model.ChatSetting, now time.Time) bool {
+	if s.QuietHours == "" {
+		return false
+	}
+	// Quiet hours are validated when set, broken ones do not hold pushes.
+	quiet, err := parseQuietHours(s.QuietHours)
+	if err != nil {
+		return false
+	}
+	loc := chatLocation(s)
+	for _, sc := range quiet {
+		if sc.active(now, loc) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyChatSetting sets one "key=value" setting of /settings on s.
+func applyChatSetting(s *model.ChatSetting, setting string) error {
+	key, value, ok := strings.Cut(setting, "=")
+	key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+	if !ok {
+		return fmt.Errorf("invalid setting %q, use key=value", setting)
+	}
+	switch key {
+	case "tz":
+		if value == "" {
+			s.Timezone = ""
+			break
+		}
+		loc, err := time.LoadLocation(value)
+		if err != nil || strings.EqualFold(value, "local") {
+			return fmt.Errorf("invalid timezone %q, e.g. Asia/Shanghai or Europe/Berlin", value)
+		}
+		s.Timezone = loc.String()
+	case "quiet":
+		value = strings.ToLower(value)
+		if value != "" {
+			windows := strings.Split(value, "|")
+			for i, w := range windows {
+				windows[i] = strings.Join(strings.Fields(w), " ")
+			}
+			value = strings.Join(windows, "|")
+			if _, err := parseQuietHours(value); err != nil {
+				return err
+			}
+		}
+		s.QuietHours = value
+	case "alarms":
+		switch strings.ToLower(value) {
+		case "exempt":
+			s.AlarmExempt = 1
+		case "hold":
+			s.AlarmExempt = 0
+		default:
+			return fmt.Errorf("invalid alarms %q, use exempt or hold", value)
+		}
+	case "notify":
+		spec, err := parseNotifier(value)
+		if err != nil {
+			return err
+		}
+		s.Notifier = spec.String()
+	default:
+		return fmt.Errorf("unknown setting %q, use tz, quiet, alarms or notify", key)
+	}
+	return nil
+}
+
+// formatChatSettings lists the settings of s as /settings takes them, the
+// notifier without its secrets.
+func formatChatSettings(s *model.ChatSetting) string {
+	tz := s.Timezone
+	if tz == "" {
+		tz = "Asia/Shanghai"
+	}
+	alarms := "hold"
+	if s.AlarmExempt != 0 {
+		alarms = "exempt"
+	}
+	d, _ := parseDelivery(s.Delivery)
+	return fmt.Sprintf("tz=%s;quiet=%s;alarms=%s\nnotify: %s\ndelivery: %s", tz, s.QuietHours, alarms,
+		describeNotifier(s.Notifier), d)
+}
+
+// SettingsHandler shows or changes the settings of the chat, e.g.
+// "/settings tz=Europe/Berlin;quiet=22:00-08:00|sat,sun;alarms=exempt" or
+// "/settings notify=wecom:https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=...".
+func (c *CommandsHandler) SettingsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	chatId := update.Message.Chat.ID
+	rest := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, constant.Settings))
+	setting, err := dal.ChatSetting.Get(chatId)
+	if err == nil && rest != "" {
+		notifier := setting.Notifier
+		for _, v := range strings.Split(rest, ";") {
+			if strings.TrimSpace(v) == "" {
+				continue
+			}
+			if err = applyChatSetting(setting, v); err != nil {
+				break
+			}
+		}
+		if err == nil && setting.Notifier != notifier {
+			err = checkNotifier(c.ctx.Config.SMTP, setting.Notifier)
+		}
+		if err == nil {
+			setting.UpdatedAt = time.Now()
+			err = dal.ChatSetting.Put(setting)
+		}
+	}
+	if err != nil {
+		c.sendErrorMessage(ctx, b, update, fmt.Sprintf("%s failed, %s", constant.Settings, err.Error()))
+		return
+	}
+	c.sendText(ctx, b, update, fmt.Sprintf("%s: %s", constant.Settings, formatChatSettings(setting)))
+}
+
+// settings returns the settings of chatId, the defaults when they can not be
+// read.
+func (r *InfoProcessor) settings(chatId int64) *model.ChatSetting {
+	setting, err := dal.ChatSetting.Get(chatId)
+	if err != nil {
+		r.ctx.Logger.Error().Stack().Err(err).Msgf("get settings of %d failed", chatId)
+		return &model.ChatSetting{ChatID: chatId}
+	}
+	return setting
+}
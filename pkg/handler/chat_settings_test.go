@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+func TestApplyChatSetting(t *testing.T) {
+	s := &model.ChatSetting{}
+	for _, v := range []string{"tz=Europe/Berlin", "quiet=22:00-08:00 | Sat,Sun", "alarms=exempt"} {
+		if err := applyChatSetting(s, v); err != nil {
+			t.Fatalf("applyChatSetting(%q): %v", v, err)
+		}
+	}
+	if s.Timezone != "Europe/Berlin" || s.QuietHours != "22:00-08:00|sat,sun" || s.AlarmExempt != 1 {
+		t.Errorf("unexpected settings %+v", s)
+	}
+	if got := formatChatSettings(s); !strings.HasPrefix(got, "tz=Europe/Berlin;quiet=22:00-08:00|sat,sun;alarms=exempt\n") {
+		t.Errorf("formatChatSettings() = %q", got)
+	}
+	if err := applyChatSetting(s, "notify=dingtalk:https://oapi.dingtalk.com/robot/send?access_token=abc SECdef"); err != nil ||
+		s.Notifier != "dingtalk:https://oapi.dingtalk.com/robot/send?access_token=abc SECdef" {
+		t.Errorf("unexpected notifier %q, %v", s.Notifier, err)
+	}
+	if got := formatChatSettings(s); !strings.Contains(got, "\nnotify: dingtalk:oapi.dingtalk.com\n") {
+		t.Errorf("expected the notifier without its secrets, got %q", got)
+	}
+	for _, v := range []string{"tz=Mars/Olympus", "tz=Local", "quiet=soon", "quiet=22:00-08:00|", "alarms=maybe", "colour=red", "tz",
+		"notify=slack:hooks.slack.com"} {
+		if err := applyChatSetting(s, v); err == nil {
+			t.Errorf("applyChatSetting(%q) expected an error", v)
+		}
+	}
+	if err := applyChatSetting(s, "tz="); err != nil || chatLocation(s) != cst {
+		t.Errorf("expected tz= to reset the timezone, got %q, %v", s.Timezone, err)
+	}
+}
+
+func TestQuietAt(t *testing.T) {
+	s := &model.ChatSetting{Timezone: "Europe/Berlin", QuietHours: "22:00-08:00|sat,sun"}
+	berlin := chatLocation(s)
+	for _, tc := range []struct {
+		at   time.Time
+		want bool
+	}{
+		{time.Date(2024, 6, 5, 23, 0, 0, 0, berlin), true},
+		{time.Date(2024, 6, 5, 7, 59, 0, 0, berlin), true},
+		{time.Date(2024, 6, 5, 8, 0, 0, 0, berlin), false},
+		// 14:00 in China is 08:00 in Berlin.
+		{time.Date(2024, 6, 5, 13, 59, 0, 0, cst), true},
+		{time.Date(2024, 6, 5, 14, 0, 0, 0, cst), false},
+		{time.Date(2024, 6, 8, 12, 0, 0, 0, berlin), true},
+	} {
+		if got := quietAt(s, tc.at); got != tc.want {
+			t.Errorf("quietAt(%s) = %v, want %v", tc.at, got, tc.want)
+		}
+	}
+	if quietAt(&model.ChatSetting{}, time.Now()) {
+		t.Error("expected a chat without quiet hours to be never quiet")
+	}
+}
@@ -22,10 +22,25 @@ type CommandSpec struct {
 
 var commandSpecs = []CommandSpec{
 	{Command: constant.Me, Description: "Show user information"},
+	{Command: constant.Cancel, Description: "Stop the command waiting for your reply"},
+	{Command: constant.Keywords, Description: "List keywords with buttons to edit, mute, move or delete them"},
 	{Command: constant.AddKeyword, Description: "Add project monitoring keywords", Usage: "<k1,k2>"},
 	{Command: constant.DeleteKeyword, Description: "Delete keywords by IDs, separated by commas", Usage: "<id1,id2>"},
 	{Command: constant.EditKeyword, Description: "Edit keywords, eg: 1=keyword1; 2=keyword2", Usage: "id1=kw1;id2=kw2"},
+	{Command: constant.TestKeyword, Description: "Show which recently crawled notices a keyword would match", Usage: "<keyword>"},
+	{Command: constant.ExportKeywords, Description: "Export keywords, alarm keywords and filters as a file", Usage: "[json|yaml|csv]"},
+	{Command: constant.ImportKeywords, Description: "Import a keyword file, send it with this command as its caption"},
+	{Command: constant.Delivery, Description: "Push notices instantly or in an hourly, daily or weekly digest", Usage: "instant|hourly|daily 08:00|weekly mon 08:00"},
+	{Command: constant.Settings, Description: "Show or change the chat timezone, quiet hours, whether alarms skip them and the notification channel", Usage: "tz=Asia/Shanghai;quiet=22:00-08:00|sat,sun;alarms=exempt"},
+	{Command: constant.KeywordStats, Description: "Show hits per keyword by day, week and month, dormant and overlapping keywords"},
+	{Command: constant.AddGroup, Description: "Create a keyword group", Usage: "<name>"},
+	{Command: constant.Groups, Description: "List keyword groups and their keywords"},
+	{Command: constant.RenameGroup, Description: "Rename a keyword group", Usage: "<id> <name>"},
+	{Command: constant.SetGroup, Description: "Change group settings: mute, schedule, chat, label, notify", Usage: "<id> mute=on;schedule=mon-fri 08:00-18:00"},
+	{Command: constant.MoveKeywords, Description: "Move keywords into a group, 0 moves them out", Usage: "<group id> <id1,id2>"},
+	{Command: constant.DeleteGroup, Description: "Delete a keyword group, its keywords are kept", Usage: "<id>"},
 	{Command: constant.AddAlarmKeyword, Description: "Add alarm monitoring keywords", Usage: "<k1,k2>"},
+	{Command: constant.AddFilter, Description: "Only receive notices matching the filters, delete them like keywords", Usage: "<regionCode=110000,noticeType=...>"},
 	{Command: constant.SearchAlarmRecords, Description: "Search alarm records by keyword", Usage: "<term>"},
 	{Command: constant.SearchHistory, Description: "Search history records by title", Usage: "<term>"},
 	{Command: constant.ListToday, Description: "List today's records"},
@@ -2,7 +2,9 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 	"net/url"
@@ -10,6 +12,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gythialy/magnet/pkg/config"
 
@@ -32,6 +35,10 @@ const (
 	defaultMessageId = 0
 	alarmTemplate    = "%s%d:%s"
 	historyTemplate  = alarmTemplate
+	// testHeader starts the /test_keyword reply, followed by the canonical
+	// keyword. The paging buttons read the keyword back from it, it does not
+	// fit the 64 bytes of callback data.
+	testHeader = "🔍 "
 )
 
 var (
@@ -41,29 +48,77 @@ var (
 )
 
 type CommandsHandler struct {
-	ctx *BotContext
+	ctx     *BotContext
+	dialogs map[string]*dialog
 }
 
 func NewCommandsHandler(ctx *BotContext) *CommandsHandler {
-	return &CommandsHandler{
-		ctx,
+	c := &CommandsHandler{
+		ctx:     ctx,
+		dialogs: make(map[string]*dialog),
 	}
+	c.registerDialog(c.editKeywordDialog())
+	return c
 }
 
 func (c *CommandsHandler) addKeywordHandler(ctx context.Context, b *bot.Bot, update *models.Update, prefix string, t model.KeywordType) {
 	text := update.Message.Text
 	tmp := strings.TrimSpace(strings.TrimPrefix(text, prefix))
 	keywords := strings.Split(tmp, ",")
+	var invalid []string
+	if t == model.PROJECT {
+		keywords, invalid = validateRules(keywords)
+	}
 	id := update.Message.Chat.ID
 	result := dal.Keyword.Insert(keywords, id, t)
+	text = fmt.Sprintf("%s: %s", prefix, result)
+	if len(invalid) > 0 {
+		text += "\n\nRejected:\n" + strings.Join(invalid, "\n")
+	}
 	if _, err := b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID: update.Message.Chat.ID,
-		Text:   fmt.Sprintf("%s: %s", prefix, result),
+		Text:   text,
 	}); err != nil {
 		c.ctx.Logger.Error().Stack().Err(err).Msg("")
 	}
 }
 
+// validateRules splits keywords into the ones that parse as rules and a
+// description of every malformed one.
+func validateRules(keywords []string) (valid, invalid []string) {
+	for _, kw := range keywords {
+		kw = strings.TrimSpace(kw)
+		if kw == "" {
+			continue
+		}
+		if _, err := rule.ParseComplexRule(&model.Keyword{Keyword: kw}); err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s: %s", kw, err.Error()))
+			continue
+		}
+		valid = append(valid, kw)
+	}
+	return valid, invalid
+}
+
+// validateKeyword checks keyword the way keywords of type t are added and
+// returns it as stored, filters as canonical "key=value" terms.
+func validateKeyword(t model.KeywordType, keyword string) (string, error) {
+	keyword = strings.TrimSpace(keyword)
+	switch t {
+	case model.PROJECT:
+		if _, invalid := validateRules([]string{keyword}); len(invalid) > 0 {
+			return "", errors.New(invalid[0])
+		}
+	case model.FILTER:
+		key, value, err := ParseFilterTerm(keyword)
+		if err != nil {
+			return "", err
+		}
+		return key + "=" + value, nil
+	}
+	return keyword, nil
+}
+
 func (c *CommandsHandler) AddKeywordHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	c.addKeywordHandler(ctx, b, update, constant.AddKeyword, model.PROJECT)
 }
@@ -71,7 +126,7 @@ func (c *CommandsHandler) AddKeywordHandler(ctx context.Context, b *bot.Bot, upd
 func (c *CommandsHandler) DeleteKeywordHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	text := update.Message.Text
 	tmp := strings.TrimSpace(strings.TrimPrefix(text, constant.DeleteKeyword))
-	if result, err := dal.Keyword.DeleteByIds(tmp); err == nil {
+	if result, err := dal.Keyword.DeleteByNumbers(update.Message.Chat.ID, tmp); err == nil {
 		if _, msgErr := b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: update.Message.Chat.ID,
 			Text:   fmt.Sprintf("%s: %s", constant.DeleteKeyword, result),
@@ -99,7 +154,23 @@ func (c *CommandsHandler) EditKeywordHandler(ctx context.Context, b *bot.Bot, up
 		)
 		return
 	}
-	if err := dal.Keyword.EditById(split); err == nil {
+	for idx, r := range split {
+		id, kw, _ := strings.Cut(r, "=")
+		i, err := strconv.ParseInt(strings.TrimSpace(id), 10, 32)
+		if err != nil || strings.TrimSpace(kw) == "" {
+			continue // reported or skipped by EditByNumber
+		}
+		existing, err := dal.Keyword.GetByNumber(update.Message.Chat.ID, int32(i))
+		if err != nil {
+			continue
+		}
+		if kw, err = validateKeyword(model.KeywordType(existing.Type), kw); err != nil {
+			c.sendErrorMessage(ctx, b, update, fmt.Sprintf("%s failed, %s", constant.EditKeyword, err.Error()))
+			return
+		}
+		split[idx] = fmt.Sprintf("%d=%s", i, kw)
+	}
+	if err := dal.Keyword.EditByNumber(update.Message.Chat.ID, split); err == nil {
 		if _, msgErr := b.SendMessage(ctx, &bot.SendMessageParams{
 			ChatID: update.Message.Chat.ID,
 			Text:   fmt.Sprintf("%s: successful.", constant.EditKeyword),
@@ -120,6 +191,36 @@ func (c *CommandsHandler) AddAlarmKeywordHandler(ctx context.Context, b *bot.Bot
 	c.addKeywordHandler(ctx, b, update, constant.AddAlarmKeyword, model.ALARM)
 }
 
+// AddFilterHandler stores notice filters given as "key=value" terms. Values
+// of the same key are alternatives, different keys must all match.
+func (c *CommandsHandler) AddFilterHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	tmp := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, constant.AddFilter))
+	var terms []string
+	for _, term := range strings.Split(tmp, ",") {
+		if strings.TrimSpace(term) == "" {
+			continue
+		}
+		key, value, err := ParseFilterTerm(term)
+		if err != nil {
+			c.sendErrorMessage(ctx, b, update, fmt.Sprintf("%s failed, %s", constant.AddFilter, err.Error()))
+			return
+		}
+		terms = append(terms, key+"="+value)
+	}
+	if len(terms) == 0 {
+		c.sendErrorMessage(ctx, b, update, fmt.Sprintf("Invalid format. Please use: %s key=value,key=value; keys: %s",
+			constant.AddFilter, strings.Join(FilterKeys(), ", ")))
+		return
+	}
+	result := dal.Keyword.Insert(terms, update.Message.Chat.ID, model.FILTER)
+	if _, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   fmt.Sprintf("%s: %s", constant.AddFilter, result),
+	}); err != nil {
+		c.ctx.Logger.Error().Stack().Err(err).Msg("")
+	}
+}
+
 func (c *CommandsHandler) AlarmRecordHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
 	id := update.Message.Chat.ID
 	businessId := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, constant.Alarm))
@@ -220,10 +321,15 @@ func (c *CommandsHandler) paginatedSearchResult(ctx context.Context, b *bot.Bot,
 
 	var response strings.Builder
 	for i, history := range results {
-		fmt.Fprintf(&response, "%d. <a href=\"%s\">%s</a> @ %s\n",
+		source := ""
+		if history.Source != "" {
+			source = fmt.Sprintf(" [%s]", history.Source)
+		}
+		fmt.Fprintf(&response, "%d. <a href=\"%s\">%s</a>%s @ %s\n",
 			(page-1)*historyPageSize+i+1,
 			history.URL,
 			history.Title,
+			source,
 			history.UpdatedAt.Format("2006-01-02 15:04:05"))
 	}
 
@@ -257,6 +363,115 @@ func (c *CommandsHandler) paginatedSearchResult(ctx context.Context, b *bot.Bot,
 	c.sendOrEditMessage(ctx, b, id, messageId, response.String(), replyMarkup)
 }
 
+// TestKeywordHandler evaluates a keyword against the notices crawled in the
+// last days without saving it.
+func (c *CommandsHandler) TestKeywordHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	tmp := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, constant.TestKeyword))
+	if tmp == "" {
+		c.sendErrorMessage(ctx, b, update, fmt.Sprintf("Invalid format. Please use: %s <keyword>", constant.TestKeyword))
+		return
+	}
+	cr, err := rule.ParseComplexRule(&model.Keyword{Keyword: tmp})
+	if err != nil {
+		c.sendErrorMessage(ctx, b, update, fmt.Sprintf("%s failed, %s", constant.TestKeyword, err.Error()))
+		return
+	}
+	c.paginatedTestResult(ctx, b, update.Message.Chat.ID, cr, 1, defaultMessageId)
+}
+
+// testKeyword returns the stored notices of the last days matched by cr,
+// newest first. Unlike Projects.Filter it records no hits and counts no
+// matches. The matches are kept for the pages of the reply, fresh loads the
+// notices again.
+func (c *CommandsHandler) testKeyword(chatId int64, cr *rule.ComplexRule, fresh bool) ([]*Project, error) {
+	key := fmt.Sprintf("%s%d:%s", constant.TestCallback, chatId, cr.ToString())
+	if v, ok := c.ctx.Store.Get(key); ok && !fresh {
+		return v.([]*Project), nil
+	}
+	notices, err := dal.Notice.Since(time.Now().AddDate(0, 0, -c.ctx.Config.NoticeDays))
+	if err != nil {
+		return nil, err
+	}
+	matcher := rule.NewMatcher([]*rule.ComplexRule{cr})
+	needsContent := matcher.NeedsContent()
+	now := time.Now()
+	matched := make([]*Project, 0)
+	for _, n := range notices {
+		p := projectFromNotice(n)
+		content := ""
+		if needsContent {
+			content = cleanContent(p.Content)
+		}
+		target := rule.Target{Budget: p.Budget, Deadline: p.Deadline(), Now: now}
+		for _, m := range matcher.Match(rule.NewDocument(p.ShortTitle, p.OpenTenderCode, content)) {
+			if m.Rule.MatchTarget(target) {
+				matched = append(matched, p)
+			}
+		}
+	}
+	c.ctx.Store.Set(key, matched, DefaultCacheDuration)
+	return matched, nil
+}
+
+func (c *CommandsHandler) paginatedTestResult(ctx context.Context, b *bot.Bot, chatId int64,
+	cr *rule.ComplexRule, page, messageId int,
+) {
+	// A new reply tests the notices crawled since, its pages reuse them.
+	matched, err := c.testKeyword(chatId, cr, messageId == defaultMessageId)
+	if err != nil {
+		c.ctx.Logger.Error().Stack().Err(err).Msg("load notices failed")
+		c.sendOrEditMessage(ctx, b, chatId, messageId, "Failed to load the crawled notices.", nil)
+		return
+	}
+
+	var response strings.Builder
+	fmt.Fprintf(&response, "%s%s\n%d notices matched in the last %d days\n\n",
+		testHeader, html.EscapeString(cr.ToString()), len(matched), c.ctx.Config.NoticeDays)
+	totalPages := (len(matched) + historyPageSize - 1) / historyPageSize
+	start := (page - 1) * historyPageSize
+	for i, p := range matched[min(start, len(matched)):min(start+historyPageSize, len(matched))] {
+		source := ""
+		if p.Source != "" {
+			source = fmt.Sprintf(" [%s]", html.EscapeString(p.Source))
+		}
+		fmt.Fprintf(&response, "%d. <a href=\"%s\">%s</a>%s @ %s\n",
+			start+i+1, p.Pageurl, html.EscapeString(p.ShortTitle), source, p.NoticeTime)
+	}
+
+	var row []models.InlineKeyboardButton
+	if page > 1 {
+		row = append(row, models.InlineKeyboardButton{
+			Text:         fmt.Sprintf("« Previous (%d)", page-1),
+			CallbackData: fmt.Sprintf("%s%d", constant.TestCallback, page-1),
+		})
+	}
+	if page < totalPages {
+		row = append(row, models.InlineKeyboardButton{
+			Text:         fmt.Sprintf("Next (%d) »", page+1),
+			CallbackData: fmt.Sprintf("%s%d", constant.TestCallback, page+1),
+		})
+	}
+	var replyMarkup *models.InlineKeyboardMarkup
+	if len(row) > 0 {
+		replyMarkup = &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{row},
+		}
+	}
+
+	c.sendOrEditMessage(ctx, b, chatId, messageId, response.String(), replyMarkup)
+}
+
+// testedKeyword reads the keyword back from the header of a /test_keyword
+// reply.
+func testedKeyword(text string) (*rule.ComplexRule, error) {
+	header, _, _ := strings.Cut(text, "\n")
+	keyword, ok := strings.CutPrefix(header, testHeader)
+	if !ok {
+		return nil, fmt.Errorf("not a %s reply", constant.TestKeyword)
+	}
+	return rule.ParseComplexRule(&model.Keyword{Keyword: keyword})
+}
+
 func (c *CommandsHandler) HandleCallbackQuery(ctx context.Context, b *bot.Bot, update *models.Update) {
 	data := update.CallbackQuery.Data
 	parts := strings.Split(data, ":")
@@ -281,6 +496,15 @@ func (c *CommandsHandler) HandleCallbackQuery(ctx context.Context, b *bot.Bot, u
 	case strings.HasPrefix(constant.AlarmCallback, queryType):
 		term := parts[2]
 		c.paginatedAlarms(ctx, b, update.CallbackQuery.From.ID, term, page, messageId)
+	case strings.HasPrefix(constant.TestCallback, queryType):
+		msg := update.CallbackQuery.Message.Message
+		if cr, err := testedKeyword(msg.Text); err == nil {
+			c.paginatedTestResult(ctx, b, msg.Chat.ID, cr, page, messageId)
+		} else {
+			c.ctx.Logger.Error().Stack().Err(err).Msg("")
+		}
+	case strings.HasPrefix(constant.KeywordsCallback, queryType):
+		c.keywordsCallback(ctx, b, update.CallbackQuery.Message.Message, update.CallbackQuery.From.ID, page, parts[2:])
 	case strings.HasPrefix(constant.TodayCallback, queryType):
 		c.paginatedTodayResult(ctx, b, &models.Update{
 			Message: update.CallbackQuery.Message.Message,
@@ -304,8 +528,8 @@ func (c *CommandsHandler) ConvertURLToPDFHandler(ctx context.Context, b *bot.Bot
 		return
 	}
 
-	// Check if the domain matches BotContext.MessageServerUrl
-	if parsedURL.Host != c.ctx.Config.MessageServerUrl {
+	// Check if the domain is one of the configured servers
+	if !c.ctx.Config.IsKnownHost(parsedURL.Host) {
 		c.sendErrorMessage(ctx, b, update, "URL domain is not allowed")
 		return
 	}
@@ -354,8 +578,8 @@ func (c *CommandsHandler) ConvertURLToIMGHandler(ctx context.Context, b *bot.Bot
 		return
 	}
 
-	// Check if the domain matches BotContext.MessageServerUrl
-	if parsedURL.Host != c.ctx.Config.MessageServerUrl {
+	// Check if the domain is one of the configured servers
+	if !c.ctx.Config.IsKnownHost(parsedURL.Host) {
 		c.sendErrorMessage(ctx, b, update, "URL domain is not allowed")
 		return
 	}
@@ -448,7 +672,15 @@ func (c *CommandsHandler) StaticHandler(ctx context.Context, b *bot.Bot, update
 	if len(alarmKeywords) > 0 {
 		fmt.Fprintf(&alarmStats, "\n- Alarm Keywords: %d\n", alarmCount)
 		for idx, kw := range alarmKeywords {
-			fmt.Fprintf(&alarmStats, "\n- [%d/%d] %s", idx+1, *kw.ID, kw.Keyword)
+			fmt.Fprintf(&alarmStats, "\n- [%d/%d] %s", idx+1, kw.Number, kw.Keyword)
+		}
+	}
+	filters := keywordDao.GetByUserIdAndType(userId, model.FILTER)
+	var filterStats strings.Builder
+	if len(filters) > 0 {
+		fmt.Fprintf(&filterStats, "\n- Filters: %d\n", len(filters))
+		for idx, kw := range filters {
+			fmt.Fprintf(&filterStats, "\n- [%d/%d] %s", idx+1, kw.Number, kw.Keyword)
 		}
 	}
 	// Get keyword stats
@@ -458,9 +690,9 @@ func (c *CommandsHandler) StaticHandler(ctx context.Context, b *bot.Bot, update
 		fmt.Fprintf(&keywordStats, "\n- Keyword Match Counts: %d\n", keywordCount)
 		for idx, kw := range keywords {
 			if cr := rule.NewComplexRule(kw); cr != nil {
-				fmt.Fprintf(&keywordStats, "\n- [%d/%d] %s => [%s]: %d", idx+1, *kw.ID, kw.Keyword, cr.ToString(), kw.Counter)
+				fmt.Fprintf(&keywordStats, "\n- [%d/%d] %s => [%s]: %d", idx+1, kw.Number, kw.Keyword, cr.ToString(), kw.Counter)
 			} else {
-				fmt.Fprintf(&keywordStats, "\n- [%d/%d] %s: %d", idx+1, *kw.ID, kw.Keyword, kw.Counter)
+				fmt.Fprintf(&keywordStats, "\n- [%d/%d] %s: %d", idx+1, kw.Number, kw.Keyword, kw.Counter)
 			}
 		}
 	}
@@ -473,11 +705,13 @@ Build Time: %s
 - History Records: %d
 %s
 %s
+%s
 `,
 		constant.Version,
 		constant.BuildTime,
 		historyCount,
 		alarmStats.String(),
+		filterStats.String(),
 		keywordStats.String())
 
 	if _, err := b.SendMessage(ctx, &bot.SendMessageParams{
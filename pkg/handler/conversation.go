@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gythialy/magnet/pkg/constant"
+	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// conversationTTL is how long a dialog waits for the next message.
+const conversationTTL = 10 * time.Minute
+
+// dialog is a command that asks for its input over several messages. Each
+// step prompts for a message and checks it, the state between the messages
+// is kept in the conversations table, so a dialog survives a restart.
+type dialog struct {
+	name  string
+	steps []dialogStep
+	// done runs once the last step accepted its input.
+	done func(ctx context.Context, b *bot.Bot, update *models.Update, data map[string]string)
+}
+
+type dialogStep struct {
+	// prompt asks for the input of the step.
+	prompt func(data map[string]string) string
+	// handle checks the input of chatId and keeps what the next steps need
+	// in data. An error is sent back and the step asks again.
+	handle func(chatId int64, text string, data map[string]string) error
+}
+
+// next feeds text to the current step of conv. It returns the prompt of the
+// following step, "" when the dialog is done.
+func (d *dialog) next(conv *model.Conversation, text string) (string, map[string]string, error) {
+	data := make(map[string]string)
+	if conv.Data != "" {
+		if err := json.Unmarshal([]byte(conv.Data), &data); err != nil {
+			return "", nil, err
+		}
+	}
+	if int(conv.Step) >= len(d.steps) {
+		return "", data, nil
+	}
+	if err := d.steps[conv.Step].handle(conv.ChatID, strings.TrimSpace(text), data); err != nil {
+		return d.steps[conv.Step].prompt(data), data, err
+	}
+	conv.Step++
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", nil, err
+	}
+	conv.Data = string(raw)
+	if int(conv.Step) == len(d.steps) {
+		return "", data, nil
+	}
+	return d.steps[conv.Step].prompt(data), data, nil
+}
+
+func (c *CommandsHandler) registerDialog(d *dialog) {
+	c.dialogs[d.name] = d
+}
+
+// senderId is the user sending a message in chatId, the chat itself for
+// messages without a sender, e.g. in channels. Dialogs belong to it.
+func senderId(chatId int64, from *models.User) int64 {
+	if from != nil {
+		return from.ID
+	}
+	return chatId
+}
+
+// startDialog starts the dialog name for userId in chatId and sends its first
+// prompt, a dialog started before is dropped.
+func (c *CommandsHandler) startDialog(ctx context.Context, b *bot.Bot, chatId, userId int64, name string,
+	data map[string]string,
+) {
+	d, ok := c.dialogs[name]
+	if !ok || len(d.steps) == 0 {
+		c.ctx.Logger.Error().Msgf("unknown dialog %s", name)
+		return
+	}
+	raw, err := json.Marshal(data)
+	if err == nil {
+		if _, err = dal.Conversation.Purge(time.Now()); err != nil {
+			c.ctx.Logger.Error().Stack().Err(err).Msg("")
+		}
+		err = dal.Conversation.Replace(&model.Conversation{
+			ChatID:    chatId,
+			UserID:    userId,
+			Dialog:    name,
+			Data:      string(raw),
+			ExpiresAt: time.Now().Add(conversationTTL),
+		})
+	}
+	if err != nil {
+		c.ctx.Logger.Error().Stack().Err(err).Msg("")
+		return
+	}
+	c.sendPrompt(ctx, b, chatId, d.steps[0].prompt(data))
+}
+
+// inDialog matches the messages continuing a dialog, commands are left to
+// their own handlers.
+func (c *CommandsHandler) inDialog(update *models.Update) bool {
+	msg := update.Message
+	if msg == nil || msg.Text == "" || strings.HasPrefix(msg.Text, "/") {
+		return false
+	}
+	conv, err := dal.Conversation.Active(msg.Chat.ID, senderId(msg.Chat.ID, msg.From), time.Now())
+	if err != nil {
+		c.ctx.Logger.Error().Stack().Err(err).Msg("")
+	}
+	return conv != nil
+}
+
+// DialogHandler feeds a message to the dialog of its sender.
+func (c *CommandsHandler) DialogHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	chatId := update.Message.Chat.ID
+	userId := senderId(chatId, update.Message.From)
+	conv, err := dal.Conversation.Active(chatId, userId, time.Now())
+	if err != nil || conv == nil {
+		return
+	}
+	d, ok := c.dialogs[conv.Dialog]
+	if !ok {
+		// Left over from a version with other dialogs.
+		_, _ = dal.Conversation.Remove(chatId, userId)
+		return
+	}
+
+	prompt, data, err := d.next(conv, update.Message.Text)
+	if err != nil {
+		c.sendErrorMessage(ctx, b, update, err.Error())
+		if prompt != "" {
+			c.sendPrompt(ctx, b, chatId, prompt)
+		}
+		return
+	}
+	if prompt != "" {
+		conv.ExpiresAt = time.Now().Add(conversationTTL)
+		if err := dal.Conversation.Replace(conv); err != nil {
+			c.ctx.Logger.Error().Stack().Err(err).Msg("")
+			return
+		}
+		c.sendPrompt(ctx, b, chatId, prompt)
+		return
+	}
+	if _, err := dal.Conversation.Remove(chatId, userId); err != nil {
+		c.ctx.Logger.Error().Stack().Err(err).Msg("")
+	}
+	if d.done != nil {
+		d.done(ctx, b, update, data)
+	}
+}
+
+// CancelHandler ends the dialog of the sender.
+func (c *CommandsHandler) CancelHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	chatId := update.Message.Chat.ID
+	userId := senderId(chatId, update.Message.From)
+	text := "Nothing to cancel."
+	if conv, err := dal.Conversation.Active(chatId, userId, time.Now()); err != nil {
+		c.ctx.Logger.Error().Stack().Err(err).Msg("")
+	} else if conv != nil {
+		if _, err := dal.Conversation.Remove(chatId, userId); err != nil {
+			c.sendErrorMessage(ctx, b, update, fmt.Sprintf("%s failed, %s", constant.Cancel, err.Error()))
+			return
+		}
+		text = "Cancelled."
+	}
+	c.sendText(ctx, b, update, text)
+}
+
+func (c *CommandsHandler) sendPrompt(ctx context.Context, b *bot.Bot, chatId int64, prompt string) {
+	if _, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      chatId,
+		Text:        prompt,
+		ReplyMarkup: &models.ForceReply{ForceReply: true, Selective: true},
+	}); err != nil {
+		c.ctx.Logger.Error().Stack().Err(err).Msg("")
+	}
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
+	"gorm.io/gorm"
+)
+
+func TestDialog_Next(t *testing.T) {
+	d := &dialog{
+		name: "wizard",
+		steps: []dialogStep{
+			{
+				prompt: func(map[string]string) string { return "name?" },
+				handle: func(_ int64, text string, data map[string]string) error {
+					if text == "" {
+						return fmt.Errorf("empty name")
+					}
+					data["name"] = text
+					return nil
+				},
+			},
+			{
+				prompt: func(data map[string]string) string { return "schedule for " + data["name"] + "?" },
+				handle: func(_ int64, text string, data map[string]string) error {
+					if _, err := parseSchedule(text); err != nil {
+						return err
+					}
+					data["schedule"] = text
+					return nil
+				},
+			},
+		},
+	}
+	conv := &model.Conversation{ChatID: 1, Dialog: d.name}
+	if prompt, _, err := d.next(conv, "  "); err == nil || prompt != "name?" || conv.Step != 0 {
+		t.Errorf("expected the first step to ask again, got %q, %v", prompt, err)
+	}
+	if prompt, _, err := d.next(conv, " gear "); err != nil || prompt != "schedule for gear?" || conv.Step != 1 {
+		t.Errorf("expected the second prompt, got %q, %v", prompt, err)
+	}
+	// The state is carried in conv, as read back from the database.
+	conv = &model.Conversation{ChatID: 1, Dialog: d.name, Step: conv.Step, Data: conv.Data}
+	if _, _, err := d.next(conv, "someday"); err == nil {
+		t.Error("expected an invalid schedule to fail")
+	}
+	prompt, data, err := d.next(conv, "sat,sun")
+	if err != nil || prompt != "" || data["name"] != "gear" || data["schedule"] != "sat,sun" {
+		t.Errorf("expected the dialog done, got %q, %v, %v", prompt, data, err)
+	}
+}
+
+func TestEditKeywordDialog(t *testing.T) {
+	f := "./conversation_test.db"
+	defer func() { _ = os.Remove(f) }()
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.Keyword{})
+	dal.SetDefault(db)
+
+	userId := int64(6161)
+	dal.Keyword.Insert([]string{"服务器", "交换机"}, userId, model.PROJECT)
+	d := NewCommandsHandler(testBotContext("")).dialogs[editKeywordDialog]
+	conv := &model.Conversation{ChatID: userId, Dialog: editKeywordDialog, Data: `{"number":"1","keyword":"+服务器"}`}
+	if prompt := d.steps[0].prompt(map[string]string{"number": "1", "keyword": "+服务器"}); !strings.Contains(prompt, "[1] +服务器") {
+		t.Errorf("unexpected prompt %q", prompt)
+	}
+	for _, invalid := range []string{"budget>abc", "交换机"} {
+		if _, _, err := d.next(conv, invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+	if prompt, _, err := d.next(conv, "服务器 -维保"); err != nil || prompt != "" {
+		t.Fatalf("expected the dialog done, got %q, %v", prompt, err)
+	}
+	if kw, _ := dal.Keyword.GetByNumber(userId, 1); kw == nil || kw.Keyword != "服务器 -维保" {
+		t.Errorf("expected the keyword replaced, got %+v", kw)
+	}
+}
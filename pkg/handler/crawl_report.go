@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// maxReportErrors caps how many errors a report lists, a run against a dead
+// portal would otherwise produce one line per keyword.
+const maxReportErrors = 10
+
+// CrawlReport collects the errors of one crawl run, so a partially failed
+// crawl can be told apart from a run that simply found nothing new. It is
+// safe for concurrent use.
+type CrawlReport struct {
+	mu     sync.Mutex
+	errors []string
+}
+
+func NewCrawlReport() *CrawlReport {
+	return &CrawlReport{}
+}
+
+// Add records err under scope, e.g. the source name or the alarm keyword.
+// A nil err is ignored.
+func (r *CrawlReport) Add(scope string, err error) {
+	if err == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, fmt.Sprintf("%s: %s", scope, err))
+}
+
+// Failed reports whether any error was recorded.
+func (r *CrawlReport) Failed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.errors) > 0
+}
+
+// String summarises the recorded errors, one per line.
+func (r *CrawlReport) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.errors) == 0 {
+		return "crawl succeeded"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "crawl finished with %d error(s):", len(r.errors))
+	for i, e := range r.errors {
+		if i == maxReportErrors {
+			fmt.Fprintf(&sb, "\n... and %d more", len(r.errors)-maxReportErrors)
+			break
+		}
+		sb.WriteString("\n- ")
+		sb.WriteString(e)
+	}
+	return sb.String()
+}
@@ -2,108 +2,203 @@ package handler
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"strconv"
 	"sync"
 	"time"
 
-	"github.com/gythialy/magnet/pkg/utils"
-
+	"github.com/gythialy/magnet/pkg/config"
+	"github.com/gythialy/magnet/pkg/dal"
 	"github.com/gythialy/magnet/pkg/model"
 
 	"github.com/gythialy/magnet/pkg/constant"
 
 	"github.com/go-resty/resty/v2"
+	"golang.org/x/time/rate"
 )
 
 const (
 	contextType = "application/json"
 	userAgent   = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:98.0) Gecko/20100101 Firefox/98.0"
-	siteId      = "404bb030-5be9-4070-85bd-c94b1473e8de"
-	channelId   = "c5bff13f-21ca-4dac-b158-cb40accd3035"
 	pageSize    = "20"
 	crawlDays   = 1
+
+	retryWaitTime    = 500 * time.Millisecond
+	retryMaxWaitTime = 30 * time.Second
 )
 
+// cst is the timezone the portals publish notice times in.
+var cst = time.FixedZone("CST", 8*60*60)
+
 type Crawler struct {
-	ctx    *BotContext
-	client *resty.Client
+	ctx     *BotContext
+	client  *resty.Client
+	sources []NoticeSource
 }
 
 func NewCrawler(ctx *BotContext) *Crawler {
-	client := resty.New().EnableTrace()
+	client := newCrawlerClient(ctx.Config.CrawlRetries, ctx.Config.CrawlRate)
 	if _, exists := os.LookupEnv(constant.RestyTrace); exists {
 		client.SetDebug(true)
 	} else {
 		client.SetDebug(false)
 	}
-	return &Crawler{
+	crawler := &Crawler{
 		ctx:    ctx,
 		client: client,
 	}
+	for _, site := range ctx.Config.Sites {
+		crawler.RegisterSource(newFreecmsSource(client, site))
+	}
+	return crawler
+}
+
+// newCrawlerClient returns the HTTP client shared by every source. Requests
+// failing with a network error, 429 or 5xx are retried up to retries times
+// with exponential backoff, honouring Retry-After when the server sends it,
+// and every attempt waits for a token of a rate/s bucket shared by all
+// sources.
+func newCrawlerClient(retries int, rps float64) *resty.Client {
+	client := resty.New().EnableTrace().
+		SetRetryCount(retries).
+		SetRetryWaitTime(retryWaitTime).
+		SetRetryMaxWaitTime(retryMaxWaitTime).
+		SetRetryAfter(retryAfter).
+		AddRetryCondition(func(resp *resty.Response, err error) bool {
+			if err != nil {
+				return true
+			}
+			code := resp.StatusCode()
+			return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+		})
+	if rps > 0 {
+		limiter := rate.NewLimiter(rate.Limit(rps), 1)
+		client.OnBeforeRequest(func(_ *resty.Client, req *resty.Request) error {
+			return limiter.Wait(req.Context())
+		})
+	}
+	return client
+}
+
+// retryAfter honours a Retry-After header given in seconds and falls back to
+// the exponential backoff otherwise.
+func retryAfter(_ *resty.Client, resp *resty.Response) (time.Duration, error) {
+	if v := resp.Header().Get("Retry-After"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second, nil
+		}
+	}
+	return 0, nil
+}
+
+// checkResponse turns an unsuccessful HTTP status left after the retries
+// into an error, resty only reports transport failures on its own.
+func checkResponse(resp *resty.Response, err error) (*resty.Response, error) {
+	if err == nil && resp.IsError() {
+		err = fmt.Errorf("%s: unexpected status %s", resp.Request.URL, resp.Status())
+	}
+	return resp, err
+}
+
+// CrawlMarks holds the high-water marks a crawl reached, by source and
+// query, until Crawler.Advance saves them.
+type CrawlMarks map[string]time.Time
+
+// Projects fetches the notices matching queries published since the last
+// run. Each source and query resumes from its persisted high-water mark (the
+// newest publish time it has delivered), bounded by the crawlDays() window.
+// The marks reached are returned instead of saved: the caller advances them
+// with Advance once the notices were pushed, so a notice whose push was
+// rolled back is fetched again by the next run. A source whose fetch failed
+// reports no mark and is retried in full next time. Notices at exactly the
+// mark are fetched again and left to the history check.
+func (c *Crawler) Projects(report *CrawlReport, queries []NoticeQuery) ([]*Project, CrawlMarks) {
+	marks := make(CrawlMarks)
+	return c.fetch(report, queries, marks), marks
+}
+
+// AllProjects fetches the whole crawlDays() window regardless of the
+// high-water marks, for a user asking to re-run their keywords.
+func (c *Crawler) AllProjects(report *CrawlReport, queries []NoticeQuery) []*Project {
+	return c.fetch(report, queries, nil)
+}
+
+// Advance saves the marks returned by Projects.
+func (c *Crawler) Advance(marks CrawlMarks) {
+	for name, mark := range marks {
+		if err := dal.CrawlMark.Advance(name, mark); err != nil {
+			c.ctx.Logger.Error().Err(err).Msgf("save mark of %s", name)
+		}
+	}
 }
 
-func (c *Crawler) Projects() []*Project {
+// fetch merges the notices of every registered source and planned query into
+// one list. A URL reported more than once is kept once, preferring the copy
+// fetched with a cityOrArea filter as it tells more about the notice. A
+// failing fetch is added to the report and whatever it returned before
+// failing is still merged, so it cannot hide the others. With marks the
+// fetch is incremental and the newest publish time of every successful fetch
+// is recorded in it.
+func (c *Crawler) fetch(report *CrawlReport, queries []NoticeQuery, marks CrawlMarks) []*Project {
+	incremental := marks != nil
 	now := time.Now()
-	days := c.crawlDays()
-	url := fmt.Sprintf("https://%s/freecms/rest/v1/notice/selectInfoMoreChannel.do?operationStartTime=%s&operationEndTime=%s", c.ctx.Config.MessageServerUrl,
-		c.format(now.AddDate(0, 0, -days)), c.format(now))
-	idx := 1
+	window := now.AddDate(0, 0, -c.crawlDays())
+	logger := c.ctx.Logger
 	result := make([]*Project, 0)
-	params := map[string]string{
-		"siteId":  siteId,
-		"channel": channelId,
-		//"currPage":       string(idx),
-		"pageSize": pageSize,
-		//"noticeType":     "",
-		//"regionCode":     "",
-		//"purchaseManner": "",
-		//"title":          "",
-		//"openTenderCode": "",
-		//"selectTimeName": "",
-		//"cityOrArea":     "",
-		//"purchaseNature": "",
-		//"punishType":     "",
-	}
-	logger := c.ctx.Logger.Info()
-	for {
-		params["currPage"] = strconv.Itoa(idx)
-		if resp, err := c.client.R().
-			SetHeader("Content-Type", contextType).
-			SetHeader("User-Agent", userAgent).
-			SetQueryParams(params).SetResult(&model.ProjectResult{}).Get(url); err == nil {
-			r := resp.Result().(*model.ProjectResult)
-			size := len(r.Data)
-			if size > 0 {
-				for _, v := range r.Data {
-					content := utils.SimplifyHTML(v.Content)
-					result = append(result, &Project{
-						NoticeTime:     v.NoticeTime,
-						OpenTenderCode: v.OpenTenderCode,
-						ShortTitle:     v.Title,
-						Title:          v.Title,
-						Content:        content,
-						Pageurl:        fmt.Sprintf("%s%s", c.ctx.Config.MessageServerUrl, v.Pageurl),
-					})
+	seen := make(map[string]int)
+	for _, source := range c.sources {
+		for _, query := range PlanQueries(queries) {
+			name := source.Name()
+			if key := query.Key(); key != "" {
+				name += "?" + key
+			}
+			start := window
+			if incremental {
+				if mark, err := dal.CrawlMark.Get(name); err != nil {
+					logger.Error().Err(err).Msgf("load mark of %s", name)
+				} else if mark.After(start) {
+					start = mark
 				}
-				idx++
-			} else {
-				break
 			}
-		} else {
-			logger.Err(err).Msgf("fetch %s failed", url)
-			break
+			projects, err := source.Fetch(query, start, now)
+			if err != nil {
+				logger.Error().Err(err).Msgf("source %s failed, got %d notices", name, len(projects))
+				report.Add(name, err)
+			}
+			newest := time.Time{}
+			for _, p := range projects {
+				if p.PublishedAt.After(newest) {
+					newest = p.PublishedAt
+				}
+				if i, ok := seen[p.Pageurl]; ok {
+					if result[i].CityOrArea == "" {
+						result[i] = p
+					}
+					continue
+				}
+				seen[p.Pageurl] = len(result)
+				result = append(result, p)
+			}
+			if incremental && err == nil && !newest.IsZero() {
+				marks[name] = newest
+			}
+			logger.Debug().Msgf("%s: %d notices since %s", name, len(projects), start.Format(time.DateTime))
 		}
-
-		time.Sleep(200 * time.Millisecond)
 	}
 
-	logger.Msgf("total: %d", len(result))
+	logger.Info().Msgf("total: %d", len(result))
 
 	return result
 }
 
-func (c *Crawler) alarmListByKeywords(keywords []string, alarmType constant.CreditType) []*model.Alarm {
+// RegisterSource adds an additional notice source to the crawler. It must be
+// called before the processor starts running.
+func (c *Crawler) RegisterSource(source NoticeSource) {
+	c.sources = append(c.sources, source)
+}
+
+func (c *Crawler) alarmListByKeywords(report *CrawlReport, keywords []string, alarmType constant.CreditType) []*model.Alarm {
 	if len(keywords) == 0 {
 		return nil
 	}
@@ -117,12 +212,15 @@ func (c *Crawler) alarmListByKeywords(keywords []string, alarmType constant.Cred
 			params := map[string]string{
 				"creditName": kw,
 				"channel":    alarmType.String(),
-				"siteId":     siteId,
+				"siteId":     config.DefaultSiteId,
 			}
 			if list, err := c.alarmList(params); err == nil {
 				mu.Lock()
 				result = append(result, list...)
 				mu.Unlock()
+			} else {
+				c.ctx.Logger.Error().Err(err).Msgf("alarm list of %s", kw)
+				report.Add(fmt.Sprintf("alarm %s(%s)", kw, alarmType), err)
 			}
 		}(keyword)
 	}
@@ -134,11 +232,11 @@ func (c *Crawler) alarmList(params map[string]string) ([]*model.Alarm, error) {
 	result := make([]*model.Alarm, 0)
 	url := fmt.Sprintf("https://%s/freecms/rest/v1/punish/queryPunishList.do",
 		c.ctx.Config.MessageServerUrl)
-	if resp, err := c.client.R().
+	if resp, err := checkResponse(c.client.R().
 		SetHeader("Content-Type", contextType).
 		SetHeader("User-Agent", userAgent).
 		SetQueryParams(params).
-		SetResult(&model.AlarmList{}).Get(url); err == nil {
+		SetResult(&model.AlarmList{}).Get(url)); err == nil {
 		r := resp.Result().(*model.AlarmList)
 		for _, row := range r.Data.Rows {
 			endDate := c.parseTime(row.EndDate)
@@ -170,11 +268,11 @@ func (c *Crawler) alarm(noticeId string) (*model.AlarmDetail, error) {
 		"env":            "1",
 	}
 	url := fmt.Sprintf("https://%s/freecms/rest/v1/punish/selectByNoticeId.do", c.ctx.Config.MessageServerUrl)
-	if resp, err := c.client.R().
+	if resp, err := checkResponse(c.client.R().
 		SetHeader("Content-Type", contextType).
 		SetHeader("User-Agent", userAgent).
 		SetQueryParams(params).
-		SetResult(&model.AlarmDetail{}).Get(url); err == nil {
+		SetResult(&model.AlarmDetail{}).Get(url)); err == nil {
 		r := resp.Result().(*model.AlarmDetail)
 		return r, nil
 	} else {
@@ -182,27 +280,27 @@ func (c *Crawler) alarm(noticeId string) (*model.AlarmDetail, error) {
 	}
 }
 
-func (c *Crawler) Alarms(keywords []string, userId int64) []*model.Alarm {
+func (c *Crawler) Alarms(report *CrawlReport, keywords []string, userId int64) []*model.Alarm {
 	result := make([]*model.Alarm, 0)
 	// Cache alarm detail fetches by noticeId. Multiple alarms often share the
 	// same notice (origin notice id), and breakFaith/suspend lists overlap,
 	// so without a cache the same detail endpoint gets hit N+1 times.
 	detailCache := make(map[string]*model.AlarmDetail)
-	r1 := c.alarmListByKeywords(keywords, constant.CreditTypeBreakFaith)
+	r1 := c.alarmListByKeywords(report, keywords, constant.CreditTypeBreakFaith)
 	cache := make(map[string]interface{})
 	for _, alarm := range r1 {
 		if _, ok := cache[alarm.CreditCode]; !ok {
 			alarm.UserID = userId
-			c.alarmTitle(alarm, detailCache)
+			c.alarmTitle(report, alarm, detailCache)
 			cache[alarm.CreditCode] = alarm
 			result = append(result, alarm)
 		}
 	}
-	r2 := c.alarmListByKeywords(keywords, constant.CreditTypeSuspend)
+	r2 := c.alarmListByKeywords(report, keywords, constant.CreditTypeSuspend)
 	for _, alarm := range r2 {
 		if _, ok := cache[alarm.CreditCode]; !ok {
 			alarm.UserID = userId
-			c.alarmTitle(alarm, detailCache)
+			c.alarmTitle(report, alarm, detailCache)
 			cache[alarm.CreditCode] = alarm
 			result = append(result, alarm)
 		}
@@ -210,13 +308,14 @@ func (c *Crawler) Alarms(keywords []string, userId int64) []*model.Alarm {
 	return result
 }
 
-func (c *Crawler) alarmTitle(alarm *model.Alarm, detailCache map[string]*model.AlarmDetail) {
+func (c *Crawler) alarmTitle(report *CrawlReport, alarm *model.Alarm, detailCache map[string]*model.AlarmDetail) {
 	if detail, err := c.alarmCached(alarm.NoticeID, detailCache); err == nil {
 		alarm.Title = &detail.Data.Title
 		u := fmt.Sprintf("https://%s%s", c.ctx.Config.MessageServerUrl, detail.Data.Pageurl)
 		alarm.PageUrl1 = u
 	} else {
 		c.ctx.Logger.Error().Stack().Err(err).Msg("")
+		report.Add("alarm detail "+alarm.NoticeID, err)
 	}
 	if alarm.OriginNoticeID != nil && *alarm.OriginNoticeID != "" {
 		if detail, err := c.alarmCached(*alarm.OriginNoticeID, detailCache); err == nil {
@@ -224,6 +323,7 @@ func (c *Crawler) alarmTitle(alarm *model.Alarm, detailCache map[string]*model.A
 			alarm.PageUrl2 = &u
 		} else {
 			c.ctx.Logger.Error().Stack().Err(err).Msg("")
+			report.Add("alarm detail "+*alarm.OriginNoticeID, err)
 		}
 	}
 }
@@ -242,11 +342,6 @@ func (c *Crawler) alarmCached(noticeId string, detailCache map[string]*model.Ala
 	return detail, nil
 }
 
-func (c *Crawler) format(time time.Time) string {
-	return fmt.Sprintf("%d-%d-%d%%20%d:%d:%d", time.Year(), time.Month(), time.Day(),
-		time.Hour(), time.Minute(), time.Second())
-}
-
 func (c *Crawler) parseTime(date string) time.Time {
 	if date == "" {
 		return time.Time{}
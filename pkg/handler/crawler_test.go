@@ -2,9 +2,13 @@ package handler
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gythialy/magnet/pkg/config"
 
@@ -20,18 +24,27 @@ import (
 
 func testBotContext(serverUrl string) *BotContext {
 	nopLogger := zerolog.Nop()
+	cfg := &config.ServiceConfig{
+		MessageServerUrl: serverUrl,
+	}
+	if serverUrl != "" {
+		cfg.Sites = []config.SiteConfig{{
+			Name:      serverUrl,
+			Host:      serverUrl,
+			SiteId:    config.DefaultSiteId,
+			ChannelId: config.DefaultChannelId,
+		}}
+	}
 	return &BotContext{
 		Logger: &utils.Logger{Logger: &nopLogger},
-		Config: &config.ServiceConfig{
-			MessageServerUrl: serverUrl,
-		},
+		Config: cfg,
 	}
 }
 
 func TestCrawler_Get(t *testing.T) {
 	crawler := NewCrawler(testBotContext(os.Getenv("SERVER_URL")))
 
-	results := crawler.Projects()
+	results := crawler.AllProjects(NewCrawlReport(), nil)
 	t.Log(len(results))
 }
 
@@ -54,7 +67,7 @@ func TestCrawler_Fetch(t *testing.T) {
 	crawler := NewCrawler(testBotContext(config.MessageServerUrl()))
 
 	userId := int64(1111)
-	result := crawler.Alarms([]string{"中国"}, userId)
+	result := crawler.Alarms(NewCrawlReport(), []string{"中国"}, userId)
 
 	for idx, alarm := range result {
 		alarm.UserID = userId
@@ -88,3 +101,35 @@ func TestCrawler_Fetch(t *testing.T) {
 		idx++
 	}
 }
+
+func TestCrawlerClientRetries(t *testing.T) {
+	tests := []struct {
+		name    string
+		codes   []int
+		wantErr bool
+		hits    int
+	}{
+		{"recovers", []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}, false, 3},
+		{"gives up", []int{http.StatusInternalServerError}, true, 3},
+		{"no retry on 4xx", []int{http.StatusNotFound}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				n := int(hits.Add(1)) - 1
+				w.WriteHeader(tt.codes[min(n, len(tt.codes)-1)])
+			}))
+			defer ts.Close()
+
+			client := newCrawlerClient(2, 100).SetRetryWaitTime(time.Millisecond)
+			_, err := checkResponse(client.R().Get(ts.URL))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got := int(hits.Load()); got != tt.hits {
+				t.Errorf("expected %d requests, got %d", tt.hits, got)
+			}
+		})
+	}
+}
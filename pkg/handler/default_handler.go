@@ -20,6 +20,9 @@ func (d *defaultHandler) Handler(ctx context.Context, b *bot.Bot, update *models
 	if update.Message == nil && update.EditedMessage != nil {
 		update.Message = update.EditedMessage
 	}
+	if update.Message == nil {
+		return
+	}
 
 	message := update.Message
 	userId := message.Chat.ID
@@ -41,6 +44,11 @@ func (d *defaultHandler) Handler(ctx context.Context, b *bot.Bot, update *models
 		return
 	}
 
+	// Other messages in groups are not meant for the bot.
+	if message.Chat.Type != models.ChatTypePrivate {
+		return
+	}
+
 	// Send a default message if no command is matched
 	if _, err := b.SendMessage(ctx, &bot.SendMessageParams{
 		ChatID:    userId,
@@ -0,0 +1,452 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/gythialy/magnet/pkg/constant"
+	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	deliveryInstant = "instant"
+	deliveryHourly  = "hourly"
+	deliveryDaily   = "daily"
+	deliveryWeekly  = "weekly"
+)
+
+// delivery is how the matched notices reach a chat: pushed one by one as
+// they are found, or queued for a digest sent every hour, every day or every
+// week at a given time in the timezone of the chat. It is stored as written,
+// e.g. "daily 08:00" or "weekly mon 08:00".
+type delivery struct {
+	mode string
+	day  time.Weekday
+	at   int // minutes since midnight
+}
+
+func parseDelivery(s string) (delivery, error) {
+	d := delivery{mode: deliveryInstant}
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 {
+		return d, nil
+	}
+	d.mode = fields[0]
+	var err error
+	switch {
+	case (d.mode == deliveryInstant || d.mode == deliveryHourly) && len(fields) == 1:
+		return d, nil
+	case d.mode == deliveryDaily && len(fields) == 2:
+		d.at, err = parseClock(fields[1])
+		return d, err
+	case d.mode == deliveryWeekly && len(fields) == 3:
+		day, ok := weekdays[fields[1]]
+		if !ok {
+			return d, fmt.Errorf("invalid day %q, use mon, tue, wed, thu, fri, sat or sun", fields[1])
+		}
+		d.day = day
+		d.at, err = parseClock(fields[2])
+		return d, err
+	}
+	return d, fmt.Errorf("invalid delivery %q, use instant, hourly, daily 08:00 or weekly mon 08:00", s)
+}
+
+func (d delivery) String() string {
+	switch d.mode {
+	case deliveryDaily:
+		return fmt.Sprintf("%s %02d:%02d", d.mode, d.at/60, d.at%60)
+	case deliveryWeekly:
+		return fmt.Sprintf("%s %s %02d:%02d", d.mode, strings.ToLower(d.day.String()[:3]), d.at/60, d.at%60)
+	}
+	return d.mode
+}
+
+// describe explains d to the user.
+func (d delivery) describe() string {
+	switch d.mode {
+	case deliveryHourly:
+		return "Matched notices are sent in a digest every hour."
+	case deliveryDaily:
+		return fmt.Sprintf("Matched notices are sent in a digest every day at %02d:%02d.", d.at/60, d.at%60)
+	case deliveryWeekly:
+		return fmt.Sprintf("Matched notices are sent in a digest every %s at %02d:%02d.", d.day, d.at/60, d.at%60)
+	}
+	return "Matched notices are pushed as soon as they are found."
+}
+
+// last returns the latest digest time at or before now, taken in loc.
+// Instant delivery has no digest times, notices left queued from an earlier
+// mode are due at once.
+func (d delivery) last(now time.Time, loc *time.Location) time.Time {
+	now = now.In(loc)
+	switch d.mode {
+	case deliveryHourly:
+		return time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, loc)
+	case deliveryDaily, deliveryWeekly:
+		t := time.Date(now.Year(), now.Month(), now.Day(), d.at/60, d.at%60, 0, 0, loc)
+		step := -1
+		if d.mode == deliveryWeekly {
+			t = t.AddDate(0, 0, -((int(now.Weekday()) - int(d.day) + 7) % 7))
+			step = -7
+		}
+		if t.After(now) {
+			t = t.AddDate(0, 0, step)
+		}
+		return t
+	}
+	return now
+}
+
+// queueProjects queues the pending projects for the digest of the chat
+// instead of pushing them, with the notifier of their keyword group and the
+// group holding them until its schedule opens, if any. The queue entry is the
+// claim, the history row is only written once the digest listing the project
+// was delivered.
+func (r *InfoProcessor) queueProjects(st *projectPushState, pending []*Project) {
+	logger := r.ctx.Logger
+	for _, project := range pending {
+		raw, err := json.Marshal(project.History(st.userId, st.now))
+		if err == nil {
+			var queued bool
+			queued, err = dal.DigestItem.Enqueue(&model.DigestItem{
+				ChatID:   st.userId,
+				URL:      project.Pageurl,
+				Keyword:  project.Keyword,
+				History:  string(raw),
+				QueuedAt: st.now,
+				Notifier: st.notifier,
+				GroupID:  st.heldBy,
+			})
+			if queued {
+				logger.Info().Msgf("queue: %s[%s]", project.ShortTitle, project.OpenTenderCode)
+			}
+		}
+		if err != nil {
+			logger.Error().Stack().Err(err).Msgf("queue %s failed", project.Pageurl)
+		}
+		r.releaseLock(st.userId, project.Pageurl)
+	}
+}
+
+// SendDigests sends the digests due at now: those of the chats with a notice
+// queued before their latest digest time, right away for a chat with instant
+// delivery. A digest due in the quiet hours of its chat waits for them to
+// end, and the notices of a keyword group outside its schedule wait for the
+// group to push again.
+func (r *InfoProcessor) SendDigests(now time.Time) {
+	pending, err := dal.DigestItem.Pending()
+	if err != nil {
+		r.ctx.Logger.Error().Stack().Err(err).Msg("get pending digests failed")
+		return
+	}
+	groups := make(map[int32]bool)
+	open := func(id int32) bool {
+		if id == 0 {
+			return true
+		}
+		if active, ok := groups[id]; ok {
+			return active
+		}
+		// The notices of a deleted group are no longer held.
+		g, err := dal.KeywordGroup.Get(id)
+		groups[id] = err != nil || groupActive(g, now, chatLocation(r.settings(g.UserID)))
+		return groups[id]
+	}
+	for chatId, items := range pending {
+		s := r.settings(chatId)
+		if quietAt(s, now) {
+			continue
+		}
+		// Deliveries are validated when set.
+		d, _ := parseDelivery(s.Delivery)
+		last := d.last(now, chatLocation(s))
+		for _, item := range items {
+			if item.QueuedAt.Before(last) && open(item.GroupID) {
+				r.sendDigest(chatId, s.Notifier, now, open)
+				break
+			}
+		}
+	}
+}
+
+// Digest is one message of a digest: the notices it lists grouped by the
+// keywords matching them. A group split over two messages is listed in both
+// and numbered on.
+type Digest struct {
+	Total  int           `json:"total"` // notices of the whole digest
+	Part   int           `json:"part"`
+	Parts  int           `json:"parts"`
+	Groups []DigestGroup `json:"groups"`
+}
+
+// DigestGroup holds the notices of a digest matched by Keyword, First is the
+// number of the first one.
+type DigestGroup struct {
+	Keyword string           `json:"keyword"`
+	First   int              `json:"first"`
+	Notices []*model.History `json:"notices"`
+}
+
+// heading is the title of a message of d, e.g. "Digest: 120 notices (1/2)".
+func (d *Digest) heading() string {
+	title := fmt.Sprintf("Digest: %d notices", d.Total)
+	if d.Parts > 1 {
+		title += fmt.Sprintf(" (%d/%d)", d.Part, d.Parts)
+	}
+	return title
+}
+
+// digestMarkup writes a digest in the markup of a channel: the heading, the
+// header of a keyword and the line of a notice with its details.
+type digestMarkup struct {
+	heading func(d *Digest) string
+	group   func(keyword string) string
+	notice  func(n int, h *model.History, details string) string
+}
+
+func (d *Digest) render(m digestMarkup) string {
+	var b strings.Builder
+	b.WriteString(m.heading(d))
+	for _, g := range d.Groups {
+		b.WriteString(m.group(g.Keyword))
+		for i, h := range g.Notices {
+			b.WriteString(m.notice(g.First+i, h, digestDetails(h)))
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// digestText renders d as plain text.
+func digestText(d *Digest) string {
+	return d.render(digestMarkup{
+		heading: func(d *Digest) string { return d.heading() + "\n" },
+		group:   func(kw string) string { return fmt.Sprintf("\n[%s]\n", kw) },
+		notice: func(n int, h *model.History, details string) string {
+			return strings.TrimSpace(fmt.Sprintf("%d. %s %s %s", n, h.Title, h.URL, details)) + "\n"
+		},
+	})
+}
+
+// telegramDigestMarkup is the HTML of a digest on Telegram, the heading is
+// only on the first message.
+var telegramDigestMarkup = digestMarkup{
+	heading: func(d *Digest) string {
+		if d.Part > 1 {
+			return ""
+		}
+		return fmt.Sprintf("<b>Digest: %d notices</b>\n", d.Total)
+	},
+	group: func(kw string) string { return fmt.Sprintf("\n<b>[%s]</b>\n", html.EscapeString(kw)) },
+	notice: func(n int, h *model.History, details string) string {
+		line := fmt.Sprintf("%d. <a href=\"%s\">%s</a>", n, html.EscapeString(h.URL), html.EscapeString(h.Title))
+		if details != "" {
+			line += " " + html.EscapeString(details)
+		}
+		return line + "\n"
+	},
+}
+
+// digestEntry is a queued notice with the history row it is recorded with.
+type digestEntry struct {
+	item    *model.DigestItem
+	history *model.History
+}
+
+// digestPart is one message of a digest and the entries it lists.
+type digestPart struct {
+	digest  *Digest
+	entries []*digestEntry
+}
+
+// sendDigest sends the queued notices of chatId, the notices of a keyword
+// group with its own notifier in a digest of their own, the others with
+// the notifier of the chat, spec. Notices held by a group that is not open
+// stay queued.
+func (r *InfoProcessor) sendDigest(chatId int64, spec string, now time.Time, open func(group int32) bool) {
+	logger := r.ctx.Logger
+	items, err := dal.DigestItem.GetByChatId(chatId)
+	if err != nil {
+		logger.Error().Stack().Err(err).Msgf("get digest of %d failed", chatId)
+		return
+	}
+	var specs []string
+	entries := make(map[string][]*digestEntry)
+	var stale []int32
+	for _, item := range items {
+		if !open(item.GroupID) {
+			continue
+		}
+		h := &model.History{}
+		if err := json.Unmarshal([]byte(item.History), h); err != nil {
+			logger.Error().Stack().Err(err).Msgf("drop queued %s", item.URL)
+			stale = append(stale, *item.ID)
+			continue
+		}
+		// Pushed meanwhile, e.g. after switching back to instant delivery.
+		if exists, err := dal.History.IsUrlExist(chatId, item.URL); err == nil && exists {
+			stale = append(stale, *item.ID)
+			continue
+		}
+		to := item.Notifier
+		if to == "" {
+			to = spec
+		}
+		if _, ok := entries[to]; !ok {
+			specs = append(specs, to)
+		}
+		entries[to] = append(entries[to], &digestEntry{item: item, history: h})
+	}
+	if err := dal.DigestItem.DeleteByIds(stale); err != nil {
+		logger.Error().Stack().Err(err).Msg("")
+	}
+	for _, to := range specs {
+		r.sendDigestTo(chatId, to, entries[to], now)
+	}
+}
+
+// sendDigestTo sends a digest of entries with the notifier of spec through
+// push, so a message failing for a reason that may pass is retried from the
+// outbox. The notices of a message are marked as pushed and dropped from the
+// queue once the message was sent or queued in the outbox, even when
+// recording them failed, so a digest is never sent twice. When the notifier
+// refused the digest for good, e.g. the chat blocked the bot or a robot was
+// removed, the rest of the notices are dropped.
+func (r *InfoProcessor) sendDigestTo(chatId int64, spec string, entries []*digestEntry, now time.Time) {
+	logger := r.ctx.Logger
+	notifier, spec := r.notifierOf(chatId, spec)
+	parts := digestParts(entries)
+	for i, part := range parts {
+		msgs, err := notifier.Render(&Notification{Digest: part.digest})
+		if err != nil {
+			logger.Error().Stack().Err(err).Msgf("render digest of %d failed", chatId)
+			return
+		}
+		for _, msg := range msgs {
+			if err := r.push(chatId, spec, msg, false, false); err != nil {
+				if !refused(err) {
+					logger.Error().Stack().Err(err).Msgf("send digest to %d failed", chatId)
+					return
+				}
+				var ids []int32
+				for _, p := range parts[i:] {
+					for _, e := range p.entries {
+						ids = append(ids, *e.item.ID)
+					}
+				}
+				logger.Error().Err(err).Msgf("drop digest of %d, %d notices", chatId, len(ids))
+				if err := dal.DigestItem.DeleteByIds(ids); err != nil {
+					logger.Error().Stack().Err(err).Msg("")
+				}
+				return
+			}
+		}
+		histories := make([]*model.History, 0, len(part.entries))
+		ids := make([]int32, 0, len(part.entries))
+		for _, e := range part.entries {
+			e.history.UpdatedAt = now
+			histories = append(histories, e.history)
+			ids = append(ids, *e.item.ID)
+		}
+		if err := dal.History.Insert(histories); err != nil {
+			logger.Error().Stack().Err(err).Msg("")
+		}
+		if err := dal.DigestItem.DeleteByIds(ids); err != nil {
+			logger.Error().Stack().Err(err).Msg("")
+		}
+		logger.Info().Msgf("digest: %d notices to %d", len(part.entries), chatId)
+	}
+}
+
+// digestParts splits entries into the messages of a digest grouped by the
+// matching keywords, each short enough for Telegram. A group split over two
+// messages repeats its header.
+func digestParts(entries []*digestEntry) []digestPart {
+	var keywords []string
+	groups := make(map[string][]*digestEntry)
+	for _, e := range entries {
+		if _, ok := groups[e.item.Keyword]; !ok {
+			keywords = append(keywords, e.item.Keyword)
+		}
+		groups[e.item.Keyword] = append(groups[e.item.Keyword], e)
+	}
+
+	var parts []digestPart
+	m := telegramDigestMarkup
+	d := &Digest{Total: len(entries), Part: 1}
+	var part []*digestEntry
+	size := len(m.heading(d))
+	for _, kw := range keywords {
+		for i, e := range groups[kw] {
+			line := len(m.notice(i+1, e.history, digestDetails(e.history)))
+			header := len(m.group(kw))
+			if len(part) > 0 && d.Groups[len(d.Groups)-1].Keyword == kw {
+				header = 0
+			}
+			if size+header+line > maxMessageLength && len(part) > 0 {
+				parts = append(parts, digestPart{digest: d, entries: part})
+				d = &Digest{Total: len(entries), Part: len(parts) + 1}
+				part = nil
+				size, header = 0, len(m.group(kw))
+			}
+			if header > 0 {
+				d.Groups = append(d.Groups, DigestGroup{Keyword: kw, First: i + 1})
+			}
+			g := &d.Groups[len(d.Groups)-1]
+			g.Notices = append(g.Notices, e.history)
+			part = append(part, e)
+			size += header + line
+		}
+	}
+	if len(part) > 0 {
+		parts = append(parts, digestPart{digest: d, entries: part})
+	}
+	for _, p := range parts {
+		p.digest.Parts = len(parts)
+	}
+	return parts
+}
+
+// digestDetails are the details of a notice in the digest, e.g.
+// "50万元, 截止 01-02 15:04".
+func digestDetails(h *model.History) string {
+	var details []string
+	if h.Budget != nil {
+		details = append(details, formatAmount(h.Budget))
+	}
+	if h.ExpireTime != nil {
+		details = append(details, "截止 "+h.ExpireTime.In(cst).Format("01-02 15:04"))
+	} else if h.OpenTenderTime != nil {
+		details = append(details, "开标 "+h.OpenTenderTime.In(cst).Format("01-02 15:04"))
+	}
+	return strings.Join(details, ", ")
+}
+
+// DeliveryHandler shows or changes how the matched notices reach the chat,
+// e.g. "/delivery daily 08:00".
+func (c *CommandsHandler) DeliveryHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	chatId := update.Message.Chat.ID
+	arg := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, constant.Delivery))
+	var d delivery
+	var err error
+	if arg == "" {
+		var setting *model.ChatSetting
+		if setting, err = dal.ChatSetting.Get(chatId); err == nil {
+			d, _ = parseDelivery(setting.Delivery)
+		}
+	} else if d, err = parseDelivery(arg); err == nil {
+		err = dal.ChatSetting.SetDelivery(chatId, d.String())
+	}
+	if err != nil {
+		c.sendErrorMessage(ctx, b, update, fmt.Sprintf("%s failed, %s", constant.Delivery, err.Error()))
+		return
+	}
+	c.sendText(ctx, b, update, fmt.Sprintf("%s: %s\n%s", constant.Delivery, d, d.describe()))
+}
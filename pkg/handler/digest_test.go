@@ -0,0 +1,335 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-telegram/bot"
+	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
+	"gorm.io/gorm"
+)
+
+func TestParseDelivery(t *testing.T) {
+	// Wednesday 2024-01-03 09:30 in China Standard Time.
+	now := time.Date(2024, 1, 3, 9, 30, 0, 0, cst)
+	for _, tc := range []struct {
+		s, want string
+		last    time.Time
+	}{
+		{"", "instant", now},
+		{"Instant", "instant", now},
+		{"hourly", "hourly", time.Date(2024, 1, 3, 9, 0, 0, 0, cst)},
+		{"daily 08:00", "daily 08:00", time.Date(2024, 1, 3, 8, 0, 0, 0, cst)},
+		{"daily 18:00", "daily 18:00", time.Date(2024, 1, 2, 18, 0, 0, 0, cst)},
+		{"weekly mon 08:00", "weekly mon 08:00", time.Date(2024, 1, 1, 8, 0, 0, 0, cst)},
+		{"weekly wed 10:00", "weekly wed 10:00", time.Date(2023, 12, 27, 10, 0, 0, 0, cst)},
+		{"weekly wed 9:30", "weekly wed 09:30", now},
+	} {
+		d, err := parseDelivery(tc.s)
+		if err != nil {
+			t.Errorf("parseDelivery(%q): %v", tc.s, err)
+			continue
+		}
+		if d.String() != tc.want {
+			t.Errorf("parseDelivery(%q) = %q, want %q", tc.s, d, tc.want)
+		}
+		if last := d.last(now, cst); !last.Equal(tc.last) {
+			t.Errorf("%q: last = %s, want %s", tc.s, last, tc.last)
+		}
+	}
+	for _, s := range []string{"daily", "daily 25:00", "weekly 08:00", "weekly xyz 08:00", "hourly 08:00", "monthly"} {
+		if _, err := parseDelivery(s); err == nil {
+			t.Errorf("expected %q to be rejected", s)
+		}
+	}
+}
+
+func TestDigestParts(t *testing.T) {
+	budget := 500000.0
+	var entries []*digestEntry
+	for i := range 120 {
+		kw := "+服务器"
+		if i%2 == 1 {
+			kw = "network gear: +交换机"
+		}
+		entries = append(entries, &digestEntry{
+			item: &model.DigestItem{Keyword: kw},
+			history: &model.History{
+				URL:    "https://example.com/notice/" + strings.Repeat("x", 20),
+				Title:  "某单位服务器采购项目 <二次>",
+				Budget: &budget,
+			},
+		})
+	}
+	parts := digestParts(entries)
+	if len(parts) < 2 {
+		t.Fatalf("expected the digest to be split, got %d parts", len(parts))
+	}
+	listed := 0
+	var first string
+	for i, part := range parts {
+		text := part.digest.render(telegramDigestMarkup)
+		if i == 0 {
+			first = text
+		}
+		if len(text) > maxMessageLength {
+			t.Errorf("part %d is %d bytes long", i, len(text))
+		}
+		if strings.HasPrefix(text, "<b>Digest: 120 notices</b>") != (i == 0) {
+			t.Errorf("part %d: unexpected title\n%s", i, text)
+		}
+		if !strings.Contains(text, "<b>[") {
+			t.Errorf("part %d has no keyword header", i)
+		}
+		if part.digest.Part != i+1 || part.digest.Parts != len(parts) {
+			t.Errorf("part %d is numbered %d/%d", i, part.digest.Part, part.digest.Parts)
+		}
+		notices := 0
+		for _, g := range part.digest.Groups {
+			notices += len(g.Notices)
+		}
+		if notices != len(part.entries) {
+			t.Errorf("part %d lists %d notices, want %d", i, notices, len(part.entries))
+		}
+		listed += len(part.entries)
+	}
+	if listed != len(entries) {
+		t.Errorf("listed %d notices, want %d", listed, len(entries))
+	}
+	if !strings.Contains(first, "<b>[+服务器]</b>\n1. <a href=\"https://example.com/notice/") ||
+		!strings.Contains(first, "某单位服务器采购项目 &lt;二次&gt;</a> 50万元\n") {
+		t.Errorf("unexpected digest\n%s", first)
+	}
+	if text := digestText(parts[1].digest); !strings.HasPrefix(text, fmt.Sprintf("Digest: 120 notices (2/%d)", len(parts))) {
+		t.Errorf("unexpected plain digest\n%s", text)
+	}
+}
+
+func TestSendDigest(t *testing.T) {
+	f := "./digest_test.db"
+	defer func() { _ = os.Remove(f) }()
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.History{}, &model.ChatSetting{}, &model.DigestItem{}, &model.Outbox{})
+	dal.SetDefault(db)
+
+	var mu sync.Mutex
+	var sent []string
+	fail := http.StatusInternalServerError
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if path.Base(r.URL.Path) == "sendMessage" && fail != 0 {
+			w.WriteHeader(fail)
+			_, _ = fmt.Fprintf(w, `{"ok":false,"error_code":%d,"description":"%s"}`, fail, http.StatusText(fail))
+			return
+		}
+		sent = append(sent, r.FormValue("text"))
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1,"chat":{"id":1}}}`))
+	}))
+	defer ts.Close()
+	b, err := bot.New("1:token", bot.WithSkipGetMe(), bot.WithServerURL(ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := testBotContext("")
+	ctx.Bot = b
+	r := &InfoProcessor{ctx: ctx, urlLocks: NewKeyedLock()}
+
+	chatId := int64(4242)
+	if err := dal.ChatSetting.SetDelivery(chatId, "daily 08:00"); err != nil {
+		t.Fatal(err)
+	}
+	queuedAt := time.Date(2024, 1, 3, 12, 0, 0, 0, cst)
+	st := &projectPushState{userId: chatId, now: queuedAt}
+	projects := []*Project{
+		{Pageurl: "https://example.com/1", ShortTitle: "服务器采购", Keyword: "+服务器"},
+		{Pageurl: "https://example.com/2", ShortTitle: "交换机采购", Keyword: "+交换机"},
+	}
+	r.queueProjects(st, projects)
+	r.queueProjects(st, projects[:1]) // queued once only
+	if items, _ := dal.DigestItem.GetByChatId(chatId); len(items) != 2 {
+		t.Fatalf("expected 2 queued notices, got %d", len(items))
+	}
+
+	// Not due before 08:00 on the next day.
+	r.SendDigests(time.Date(2024, 1, 4, 7, 59, 0, 0, cst))
+	if len(sent) != 0 {
+		t.Fatalf("expected no digest yet, sent %q", sent)
+	}
+
+	// A digest failing to send is retried from the outbox, not sent again.
+	due := time.Date(2024, 1, 4, 8, 0, 0, 0, cst)
+	r.SendDigests(due)
+	if items, _ := dal.DigestItem.GetByChatId(chatId); len(items) != 0 {
+		t.Errorf("expected the queue to be empty, got %d notices", len(items))
+	}
+	if waiting, _ := dal.Outbox.GetByChatId(chatId); len(waiting) != 1 {
+		t.Fatalf("expected the digest in the outbox, got %d messages", len(waiting))
+	}
+	r.SendDigests(due.Add(time.Minute))
+
+	fail = 0
+	r.SendOutbox(time.Now().Add(time.Hour))
+	if len(sent) != 1 || !strings.Contains(sent[0], "<b>[+服务器]</b>") || !strings.Contains(sent[0], "<b>[+交换机]</b>") {
+		t.Fatalf("unexpected digest %q", sent)
+	}
+	for _, p := range projects {
+		if exists, _ := dal.History.IsUrlExist(chatId, p.Pageurl); !exists {
+			t.Errorf("expected %s to be recorded", p.Pageurl)
+		}
+	}
+	if skip, _ := r.shouldSkipProcessing(chatId, projects[0].Pageurl, false); !skip {
+		t.Error("expected a delivered notice to be skipped")
+	}
+
+	// A chat that blocked the bot gets its queue dropped instead of being
+	// tried every minute.
+	fail = http.StatusForbidden
+	r.queueProjects(st, []*Project{{Pageurl: "https://example.com/3", ShortTitle: "存储采购", Keyword: "+存储"}})
+	r.SendDigests(due.AddDate(0, 0, 1))
+	if items, _ := dal.DigestItem.GetByChatId(chatId); len(items) != 0 {
+		t.Errorf("expected the queue of a refusing chat to be dropped, got %d notices", len(items))
+	}
+}
+
+func TestSendDigestNotifier(t *testing.T) {
+	f := "./digest_notifier_test.db"
+	defer func() { _ = os.Remove(f) }()
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.History{}, &model.ChatSetting{}, &model.DigestItem{}, &model.Outbox{})
+	dal.SetDefault(db)
+
+	var mu sync.Mutex
+	var posted []webhookPayload
+	status := http.StatusOK
+	base := testNotifierServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var payload webhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		posted = append(posted, payload)
+		w.WriteHeader(status)
+	}))
+	r := &InfoProcessor{ctx: testBotContext(""), urlLocks: NewKeyedLock()}
+
+	chatId := int64(4343)
+	spec := "webhook:" + base + "/hook"
+	if err := dal.ChatSetting.Save(&model.ChatSetting{ChatID: chatId, Delivery: "hourly", Notifier: spec}); err != nil {
+		t.Fatal(err)
+	}
+	queuedAt := time.Date(2024, 1, 3, 12, 5, 0, 0, cst)
+	st := &projectPushState{userId: chatId, now: queuedAt}
+	projects := []*Project{
+		{Pageurl: "https://example.com/1", Title: "服务器采购", Keyword: "+服务器"},
+		{Pageurl: "https://example.com/2", Title: "交换机采购", Keyword: "+交换机"},
+	}
+	r.queueProjects(st, projects)
+
+	r.SendDigests(queuedAt.Add(time.Hour))
+	if len(posted) != 1 {
+		t.Fatalf("expected one digest, posted %d", len(posted))
+	}
+	d := posted[0]
+	if d.Type != "digest" || d.Title != "Digest: 2 notices" || d.Digest == nil || len(d.Digest.Groups) != 2 ||
+		d.Digest.Groups[1].Notices[0].URL != "https://example.com/2" || !strings.Contains(d.Text, "[+交换机]") {
+		t.Errorf("unexpected digest %+v", d)
+	}
+	if items, _ := dal.DigestItem.GetByChatId(chatId); len(items) != 0 {
+		t.Errorf("expected the queue to be empty, got %d notices", len(items))
+	}
+	for _, p := range projects {
+		if exists, _ := dal.History.IsUrlExist(chatId, p.Pageurl); !exists {
+			t.Errorf("expected %s to be recorded", p.Pageurl)
+		}
+	}
+
+	// A webhook refusing the digest has it dropped.
+	status = http.StatusGone
+	r.queueProjects(st, []*Project{{Pageurl: "https://example.com/3", Title: "存储采购", Keyword: "+存储"}})
+	r.SendDigests(queuedAt.Add(2 * time.Hour))
+	if items, _ := dal.DigestItem.GetByChatId(chatId); len(items) != 0 {
+		t.Errorf("expected the refused digest to be dropped, got %d notices", len(items))
+	}
+	if waiting, _ := dal.Outbox.GetByChatId(chatId); len(waiting) != 0 {
+		t.Errorf("expected nothing in the outbox, got %d messages", len(waiting))
+	}
+}
+
+func TestScheduledGroupHoldsNotices(t *testing.T) {
+	f := "./digest_schedule_test.db"
+	defer func() { _ = os.Remove(f) }()
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.Keyword{}, &model.KeywordGroup{}, &model.History{}, &model.ChatSetting{},
+		&model.DigestItem{}, &model.Outbox{})
+	dal.SetDefault(db)
+
+	var mu sync.Mutex
+	var posted []webhookPayload
+	base := testNotifierServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var payload webhookPayload
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		posted = append(posted, payload)
+	}))
+	r := &InfoProcessor{ctx: testBotContext(""), urlLocks: NewKeyedLock()}
+
+	userId := int64(4545)
+	dal.Keyword.Insert([]string{"交换机"}, userId, model.PROJECT)
+	office, _ := dal.KeywordGroup.Add(userId, "office hours")
+	office.Schedule, office.Notifier = "mon-fri 08:00-18:00", "webhook:"+base+"/hook"
+	if err := dal.KeywordGroup.Save(office); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = dal.Keyword.MoveToGroup(userId, office.ID, []int32{1})
+
+	// Saturday, the keywords of the group still match but hold the notices.
+	saturday := time.Date(2024, 1, 6, 10, 0, 0, 0, cst)
+	conf := r.get(userId, saturday)
+	if len(conf) != 2 || conf[1].HeldBy != *office.ID || len(conf[1].ProjectRules) != 1 {
+		t.Fatalf("expected the rules of the group held, got %+v", conf)
+	}
+	st := &projectPushState{userId: userId, now: saturday, notifier: conf[1].Notifier, heldBy: conf[1].HeldBy}
+	r.queueProjects(st, []*Project{{Pageurl: "https://example.com/1", Title: "交换机采购", Keyword: "交换机"}})
+
+	r.SendDigests(saturday.Add(time.Hour))
+	if len(posted) != 0 {
+		t.Fatalf("expected the notice held until monday, posted %+v", posted)
+	}
+	if items, _ := dal.DigestItem.GetByChatId(userId); len(items) != 1 {
+		t.Fatalf("expected the notice to stay queued, got %d", len(items))
+	}
+
+	monday := time.Date(2024, 1, 8, 8, 0, 0, 0, cst)
+	if conf := r.get(userId, monday); len(conf) != 2 || conf[1].HeldBy != 0 {
+		t.Errorf("expected the group open on monday, got %+v", conf)
+	}
+	r.SendDigests(monday)
+	if len(posted) != 1 || posted[0].Digest == nil || posted[0].Digest.Groups[0].Notices[0].URL != "https://example.com/1" {
+		t.Fatalf("expected the held notice sent when the group opens, posted %+v", posted)
+	}
+	if exists, _ := dal.History.IsUrlExist(userId, "https://example.com/1"); !exists {
+		t.Error("expected the held notice to be recorded")
+	}
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/gythialy/magnet/pkg/config"
+	"github.com/gythialy/magnet/pkg/model"
+	"github.com/gythialy/magnet/pkg/utils"
+)
+
+// freecmsSource crawls one channel of a freecms portal via its
+// selectInfoMoreChannel.do endpoint.
+type freecmsSource struct {
+	client *resty.Client
+	site   config.SiteConfig
+}
+
+func newFreecmsSource(client *resty.Client, site config.SiteConfig) *freecmsSource {
+	return &freecmsSource{
+		client: client,
+		site:   site,
+	}
+}
+
+func (s *freecmsSource) Name() string {
+	return fmt.Sprintf("freecms:%s/%s", s.site.Host, s.site.ChannelId)
+}
+
+// Fetch pages through the channel newest first and stops at the first page
+// that reaches notices published before start, so an incremental run with a
+// recent start only requests the first page or two. Every filter of query is
+// passed to the portal as is.
+func (s *freecmsSource) Fetch(query NoticeQuery, start, end time.Time) ([]*Project, error) {
+	url := fmt.Sprintf("https://%s/freecms/rest/v1/notice/selectInfoMoreChannel.do?operationStartTime=%s&operationEndTime=%s", s.site.Host,
+		formatTime(start), formatTime(end))
+	idx := 1
+	result := make([]*Project, 0)
+	params := map[string]string{
+		"siteId":  s.site.SiteId,
+		"channel": s.site.ChannelId,
+		//"currPage":       string(idx),
+		"pageSize": pageSize,
+		//"title":          "",
+		//"openTenderCode": "",
+		//"selectTimeName": "",
+		//"punishType":     "",
+	}
+	for key, value := range query {
+		params[key] = value
+	}
+	for {
+		params["currPage"] = strconv.Itoa(idx)
+		resp, err := checkResponse(s.client.R().
+			SetHeader("Content-Type", contextType).
+			SetHeader("User-Agent", userAgent).
+			SetQueryParams(params).SetResult(&model.ProjectResult{}).Get(url))
+		if err != nil {
+			return result, fmt.Errorf("fetch %s page %d: %w", url, idx, err)
+		}
+		r := resp.Result().(*model.ProjectResult)
+		if len(r.Data) == 0 {
+			break
+		}
+		reachedStart := false
+		for _, v := range r.Data {
+			publishedAt := publishTime(v.Addtime, v.NoticeTime)
+			if !publishedAt.IsZero() && publishedAt.Before(start) {
+				reachedStart = true
+				continue
+			}
+			content := utils.SimplifyHTML(v.Content)
+			result = append(result, &Project{
+				NoticeTime:     v.NoticeTime,
+				PublishedAt:    publishedAt,
+				OpenTenderCode: v.OpenTenderCode,
+				ShortTitle:     v.Title,
+				Title:          v.Title,
+				Content:        content,
+				Pageurl:        fmt.Sprintf("%s%s", s.site.Host, v.Pageurl),
+				Source:         s.site.Name,
+				RegionCode:     v.RegionCode,
+				PurchaseManner: v.PurchaseManner,
+				NoticeType:     v.NoticeType,
+				PurchaseNature: v.PurchaseNature,
+				CityOrArea:     query[filterCityOrArea],
+				RegionName:     v.RegionName,
+				Budget:         parseAmount(v.Budget),
+				OpenTenderTime: parseAnyTime(v.OpenTenderTime),
+				ExpireTime:     parseAnyTime(v.ExpireTime),
+				AgentName:      anyString(v.AgentManageName),
+				AgentLinkMan:   anyString(v.AgentLinkMan),
+				AgentLinkPhone: anyString(v.AgentLinkPhone),
+			})
+		}
+		if reachedStart {
+			break
+		}
+		idx++
+	}
+
+	return result, nil
+}
+
+// publishTime prefers the millisecond addtime of a notice and falls back to
+// its noticeTime, which the portal reports in China Standard Time. It returns
+// the zero time when neither can be read.
+func publishTime(addtime int64, noticeTime string) time.Time {
+	if addtime > 0 {
+		return time.UnixMilli(addtime)
+	}
+	if t, err := time.ParseInLocation(time.DateTime, noticeTime, cst); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// parseAnyTime reads a loosely typed timestamp of the notice API, either
+// milliseconds since the epoch or a CST date time string. It returns the zero
+// time when the value is missing or unreadable.
+func parseAnyTime(v any) time.Time {
+	switch t := v.(type) {
+	case float64:
+		if t > 0 {
+			return time.UnixMilli(int64(t))
+		}
+	case string:
+		t = strings.TrimSpace(t)
+		if ms, err := strconv.ParseInt(t, 10, 64); err == nil && ms > 0 {
+			return time.UnixMilli(ms)
+		}
+		for _, layout := range []string{time.DateTime, "2006-01-02 15:04", time.DateOnly} {
+			if parsed, err := time.ParseInLocation(layout, t, cst); err == nil {
+				return parsed
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// parseAmount reads a loosely typed amount in yuan, given either as a number
+// or as a string such as "1,200,000.00元". It returns nil when the amount is
+// missing, unreadable or not positive.
+func parseAmount(v any) *float64 {
+	var amount float64
+	switch a := v.(type) {
+	case float64:
+		amount = a
+	case string:
+		a = strings.NewReplacer(",", "", "，", "", "元", "").Replace(strings.TrimSpace(a))
+		f, err := strconv.ParseFloat(a, 64)
+		if err != nil {
+			return nil
+		}
+		amount = f
+	}
+	if amount <= 0 {
+		return nil
+	}
+	return &amount
+}
+
+// anyString returns v when it is a string and "" otherwise.
+func anyString(v any) string {
+	if s, ok := v.(string); ok {
+		return strings.TrimSpace(s)
+	}
+	return ""
+}
+
+func formatTime(time time.Time) string {
+	return fmt.Sprintf("%d-%d-%d%%20%d:%d:%d", time.Year(), time.Month(), time.Day(),
+		time.Hour(), time.Minute(), time.Second())
+}
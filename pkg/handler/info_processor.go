@@ -6,17 +6,15 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/gythialy/magnet/pkg/utils"
-
 	"github.com/gythialy/magnet/pkg/dal"
 	"github.com/gythialy/magnet/pkg/model"
 
 	"github.com/gythialy/magnet/pkg/rule"
 
 	"github.com/go-telegram/bot"
-	"github.com/go-telegram/bot/models"
 	"github.com/panjf2000/ants/v2"
 )
 
@@ -31,12 +29,51 @@ var (
 
 // ProcessData holds the data for processing
 type ProcessData struct {
-	UserId       int64
+	UserId int64
+	// ChatId is the chat the matched projects are pushed to and recorded
+	// for, UserId unless a keyword group targets another chat.
+	ChatId int64
+	// Notifier is the channel of the keyword group the rules belong to,
+	// empty for the one of the chat.
+	Notifier string
+	// HeldBy is the keyword group the rules belong to when it is outside
+	// its schedule, the matches are queued until it opens.
+	HeldBy       int32
 	ProjectRules []*rule.ComplexRule
+	// Matcher is ProjectRules compiled, shared between runs while the rules
+	// stay the same.
+	Matcher *rule.Matcher
+	// Labels holds the group label of the grouped keywords by keyword id.
+	Labels       map[int32]string
 	AlarmKeyword []string
-	Projects     []*Project
-	Alarms       []*model.Alarm
-	IsForced     bool
+	// Filter drops the notices the user did not subscribe to before the
+	// keyword rules run.
+	Filter   NoticeFilter
+	Projects []*Project
+	Alarms   []*model.Alarm
+	IsForced bool
+	// run tracks the scheduled run the data belongs to, nil for /retry.
+	run *processRun
+}
+
+// processRun waits for the invocations of one scheduled run and records
+// whether any of them rolled a push back, in which case the crawl marks are
+// kept so the next run fetches the notice again.
+type processRun struct {
+	wg         sync.WaitGroup
+	rolledBack atomic.Bool
+}
+
+func (r *processRun) done() {
+	if r != nil {
+		r.wg.Done()
+	}
+}
+
+func (r *processRun) rollback() {
+	if r != nil {
+		r.rolledBack.Store(true)
+	}
 }
 
 type InfoProcessor struct {
@@ -45,6 +82,7 @@ type InfoProcessor struct {
 	crawler      *Crawler
 	urlLocks     *KeyedLock // in-process guard for project URLs
 	pushPipeline *PushPipeline
+	matchers     *MatcherCache
 }
 
 func NewInfoProcessor(ctx *BotContext) (*InfoProcessor, error) {
@@ -53,6 +91,7 @@ func NewInfoProcessor(ctx *BotContext) (*InfoProcessor, error) {
 		crawler:      NewCrawler(ctx),
 		urlLocks:     NewKeyedLock(),
 		pushPipeline: NewPushPipeline(),
+		matchers:     NewMatcherCache(),
 	}
 
 	if pool, err := ants.NewPoolWithFunc(poolSize, processor.Handler); err != nil {
@@ -64,25 +103,100 @@ func NewInfoProcessor(ctx *BotContext) (*InfoProcessor, error) {
 }
 
 func (r *InfoProcessor) Process() {
-	projects := r.crawler.Projects()
+	report := NewCrawlReport()
 	conf := r.config()
+	var queries []NoticeQuery
+	for _, data := range conf {
+		queries = append(queries, data.Filter.Queries()...)
+	}
+	projects, marks := r.crawler.Projects(report, queries)
+	r.saveNotices(projects)
+	r.pruneKeywordHits()
+	run := &processRun{}
 	for _, data := range conf {
 		data.Projects = projects
-		data.Alarms = r.crawler.Alarms(data.AlarmKeyword, data.UserId)
+		data.Alarms = r.crawler.Alarms(report, data.AlarmKeyword, data.UserId)
 		data.IsForced = false
+		data.run = run
+		run.wg.Add(1)
 		if err := r.pool.Invoke(data); err != nil {
 			r.ctx.Logger.Error().Stack().Err(err).Msg("")
+			run.rollback()
+			run.done()
 		}
 	}
+	// The marks only move once every notice of the run was pushed, claimed
+	// or queued; otherwise the next run fetches from the old marks again and
+	// the history check skips what was delivered.
+	run.wg.Wait()
+	if run.rolledBack.Load() {
+		r.ctx.Logger.Warn().Msg("some pushes failed, crawl marks kept")
+	} else {
+		r.crawler.Advance(marks)
+	}
+	r.notifyReport(report)
 }
 
-func (r *InfoProcessor) Get(userId int64) {
-	results := r.crawler.Projects()
+// Get re-runs the keywords of userId against the whole crawl window and
+// returns the crawl report, so the caller can tell whether the result is
+// complete.
+func (r *InfoProcessor) Get(userId int64) *CrawlReport {
+	report := NewCrawlReport()
+	conf := r.get(userId, time.Now())
+	// The entries of a user share the notice filter.
+	results := r.crawler.AllProjects(report, conf[0].Filter.Queries())
+	r.saveNotices(results)
 	if len(results) > 0 {
-		data := r.get(userId)
-		data.Projects = results
-		data.IsForced = true
-		if err := r.pool.Invoke(data); err != nil {
+		for _, data := range conf {
+			data.Projects = results
+			data.IsForced = true
+			if err := r.pool.Invoke(data); err != nil {
+				r.ctx.Logger.Error().Stack().Err(err).Msg("")
+			}
+		}
+	}
+	return report
+}
+
+// saveNotices keeps the crawled notices for /test_keyword and drops the ones
+// older than the configured number of days.
+func (r *InfoProcessor) saveNotices(projects []*Project) {
+	now := time.Now()
+	notices := make([]*model.Notice, 0, len(projects))
+	for _, p := range projects {
+		notices = append(notices, p.Notice(now))
+	}
+	if err := dal.Notice.Save(notices); err != nil {
+		r.ctx.Logger.Error().Stack().Err(err).Msg("save notices failed")
+	}
+	if _, err := dal.Notice.Prune(now.AddDate(0, 0, -r.ctx.Config.NoticeDays)); err != nil {
+		r.ctx.Logger.Error().Stack().Err(err).Msg("prune notices failed")
+	}
+}
+
+// pruneKeywordHits drops the keyword hits older than the configured number
+// of days, /keyword_stats counts what is left as all time.
+func (r *InfoProcessor) pruneKeywordHits() {
+	before := time.Now().AddDate(0, 0, -r.ctx.Config.KeywordHitDays)
+	if _, err := dal.KeywordHit.Prune(before); err != nil {
+		r.ctx.Logger.Error().Stack().Err(err).Msg("prune keyword hits failed")
+	}
+}
+
+// notifyReport logs a partially failed scheduled crawl and forwards the
+// report to the manager, otherwise it would look just like a run without
+// new notices.
+func (r *InfoProcessor) notifyReport(report *CrawlReport) {
+	if !report.Failed() {
+		return
+	}
+	msg := report.String()
+	r.ctx.Logger.Warn().Msg(msg)
+	if managerId := r.ctx.Config.ManagerId; managerId != 0 && r.ctx.Bot != nil {
+		if _, err := r.ctx.Bot.SendMessage(context.Background(), &bot.SendMessageParams{
+			ChatID: managerId,
+			Text:   msg,
+		}); err != nil {
 			r.ctx.Logger.Error().Stack().Err(err).Msg("")
 		}
 	}
@@ -92,33 +206,82 @@ func (r *InfoProcessor) Release() {
 	r.pool.Release()
 }
 
-func (r *InfoProcessor) config() map[int64]ProcessData {
-	ids := dal.Keyword.Ids()
-	m := make(map[int64]ProcessData)
-	for _, id := range ids {
-		if _, ok := m[id]; !ok {
-			m[id] = r.get(id)
-		}
+func (r *InfoProcessor) config() []ProcessData {
+	now := time.Now()
+	var conf []ProcessData
+	for _, id := range dal.Keyword.Ids() {
+		conf = append(conf, r.get(id, now)...)
 	}
-
-	return m
+	return conf
 }
 
-func (r *InfoProcessor) get(id int64) ProcessData {
-	keywords := dal.Keyword.GetByUserIdAndType(id, model.PROJECT)
-	var rules []*rule.ComplexRule
-	for _, kw := range keywords {
-		r := rule.NewComplexRule(kw)
-		if r != nil {
-			rules = append(rules, r)
-		}
+// get returns the data of user id, one entry per chat and notifier the
+// projects are pushed to: the user's own chat first, then the target chats
+// and notifiers of the keyword groups, and one entry per group outside its
+// schedule holding its matches. Muted keywords and the keywords of a muted
+// group are left out. Alarms always go to the user's own chat.
+func (r *InfoProcessor) get(id int64, now time.Time) []ProcessData {
+	groups := make(map[int32]*model.KeywordGroup)
+	for _, g := range dal.KeywordGroup.GetByUserId(id) {
+		groups[*g.ID] = g
 	}
-	rule.SortComplexRules(rules)
-	return ProcessData{
+	filter := NewNoticeFilter(dal.Keyword.GetKeywords(id, model.FILTER))
+	labels := make(map[int32]string)
+	conf := []ProcessData{{
 		UserId:       id,
-		ProjectRules: rules,
+		ChatId:       id,
+		Labels:       labels,
 		AlarmKeyword: dal.Keyword.GetKeywords(id, model.ALARM),
+		Filter:       filter,
+	}}
+	type target struct {
+		chatId   int64
+		notifier string
+		heldBy   int32
 	}
+	targets := map[target]int{{chatId: id}: 0}
+	loc := chatLocation(r.settings(id))
+	for _, kw := range dal.Keyword.GetByUserIdAndType(id, model.PROJECT) {
+		if kw.Muted != 0 {
+			continue
+		}
+		to := target{chatId: id}
+		if kw.GroupID != nil {
+			if g, ok := groups[*kw.GroupID]; ok {
+				if g.Muted != 0 {
+					continue
+				}
+				if !groupActive(g, now, loc) {
+					to.heldBy = *g.ID
+				}
+				labels[*kw.ID] = groupLabel(g)
+				if g.TargetChatID != 0 {
+					to.chatId = g.TargetChatID
+				}
+				to.notifier = g.Notifier
+			}
+		}
+		cr, err := rule.ParseComplexRule(kw)
+		if err != nil {
+			// Shown as invalid in /keywords and /keyword_stats.
+			r.ctx.Logger.Warn().Err(err).Msgf("skip invalid keyword [%d] %q of %d", kw.Number, kw.Keyword, id)
+			continue
+		}
+		idx, ok := targets[to]
+		if !ok {
+			idx = len(conf)
+			targets[to] = idx
+			conf = append(conf, ProcessData{UserId: id, ChatId: to.chatId, Notifier: to.notifier, HeldBy: to.heldBy,
+				Labels: labels, Filter: filter})
+		}
+		conf[idx].ProjectRules = append(conf[idx].ProjectRules, cr)
+	}
+	for i := range conf {
+		rule.SortComplexRules(conf[i].ProjectRules)
+		key := fmt.Sprintf("%d:%d:%s:%d", id, conf[i].ChatId, conf[i].Notifier, conf[i].HeldBy)
+		conf[i].Matcher = r.matchers.Get(key, conf[i].ProjectRules)
+	}
+	return conf
 }
 
 // shouldSkipProcessing is the render-time pre-filter for project URLs. It is
@@ -164,6 +327,7 @@ func (r *InfoProcessor) releaseLock(userId int64, url string) {
 type contentResult struct {
 	chunks []string
 	total  int
+	err    error
 }
 
 // projectContentSem limits how many per-project content renderings run at
@@ -174,6 +338,7 @@ const projectContentSem = 5
 func (r *InfoProcessor) Handler(i interface{}) {
 	switch pd := i.(type) {
 	case ProcessData:
+		defer pd.run.done()
 		// Run the two pipelines concurrently so alarm notifications do not
 		// wait behind a large project batch. Both pipelines are safe to run
 		// in parallel: the PushPipeline's keyed lock is concurrent-safe, and
@@ -199,24 +364,38 @@ type projectPushState struct {
 	userId       int64
 	isForced     bool
 	now          time.Time
+	held         bool   // chat in its quiet hours, messages go to the outbox
+	heldBy       int32  // group outside its schedule, projects are queued
+	notifier     string // spec of the notifier the projects are sent with
 	failed       []string
 	filterFailed map[string]*Project
 	processedURL []*model.History
 }
 
 // processProjects handles the project-notice pipeline for one user: filter by
-// keyword rules, render matched content, then push each URL through the
-// PushPipeline skeleton. The DB primary key (user_id, url) acts as a
-// distributed lock: only the invocation that actually inserts the history row
-// may send, so concurrent runs — even multiple bot instances — can never push
-// the same project twice. A fully failed send rolls the claim back so the
-// next run retries. The forced path (/retry) bypasses the claim on purpose.
+// keyword rules, render matched content for the notifier of the keyword group
+// or of the chat, then push each URL through the PushPipeline skeleton. The
+// DB primary key (user_id, url) acts as a distributed lock: only the
+// invocation that actually inserts the history row may send, so concurrent
+// runs — even multiple bot instances — can never push the same project
+// twice. A chunk failing to send is queued in the outbox
+// and retried from there, only a project whose first chunk was refused for
+// good rolls the claim back. The forced path (/retry) bypasses the claim on
+// purpose.
 func (r *InfoProcessor) processProjects(pd ProcessData) {
 	historyDao := dal.History
-	projects := NewProjects(r.ctx, pd.Projects, pd.ProjectRules).Filter()
+	subscribed := make([]*Project, 0, len(pd.Projects))
+	for _, p := range pd.Projects {
+		if pd.Filter.Match(p) {
+			subscribed = append(subscribed, p)
+		}
+	}
+	filter := newProjects(r.ctx, subscribed, pd.Matcher)
+	filter.labels = pd.Labels
+	projects := filter.Filter()
 	logger := r.ctx.Logger
 	st := &projectPushState{
-		userId:       pd.UserId,
+		userId:       pd.ChatId,
 		isForced:     pd.IsForced,
 		now:          time.Now(),
 		failed:       []string{"failed:"},
@@ -239,6 +418,23 @@ func (r *InfoProcessor) processProjects(pd ProcessData) {
 		pending = append(pending, project)
 	}
 
+	// A chat reading digests gets the projects queued instead, with the
+	// notifier of their keyword group, /retry still pushes them at once. So
+	// do the projects of a group outside its schedule, until it opens. In
+	// the quiet hours of the chat the messages are held in the outbox.
+	settings := r.settings(st.userId)
+	st.notifier, st.heldBy = pd.Notifier, pd.HeldBy
+	if d, _ := parseDelivery(settings.Delivery); st.heldBy != 0 || !pd.IsForced && d.mode != deliveryInstant {
+		r.queueProjects(st, pending)
+		return
+	}
+	st.held = quietAt(settings, st.now)
+	if st.notifier == "" {
+		st.notifier = settings.Notifier
+	}
+	notifier, spec := r.notifierOf(st.userId, st.notifier)
+	st.notifier = spec
+
 	// Rendering is CPU heavy, so render the pending projects concurrently
 	// with a bounded fan-out instead of blocking on each project in turn.
 	results := make([]contentResult, len(pending))
@@ -250,8 +446,8 @@ func (r *InfoProcessor) processProjects(pd ProcessData) {
 			defer wg.Done()
 			sem <- struct{}{}
 			defer func() { <-sem }()
-			chunks, total := r.ToMessage(pj)
-			results[idx] = contentResult{chunks: chunks, total: total}
+			chunks, err := notifier.Render(&Notification{Project: pj})
+			results[idx] = contentResult{chunks: chunks, total: len(chunks), err: err}
 		}(i, pj)
 	}
 	wg.Wait()
@@ -263,6 +459,7 @@ func (r *InfoProcessor) processProjects(pd ProcessData) {
 		pageURL := project.Pageurl
 		chunks := results[j].chunks
 		total := results[j].total
+		renderErr := results[j].err
 		logger.Debug().Msgf("split content to %d parts", total)
 
 		handles = append(handles, ClaimHandle{
@@ -270,21 +467,20 @@ func (r *InfoProcessor) processProjects(pd ProcessData) {
 				if st.isForced {
 					return true, nil // forced path re-pushes without claiming
 				}
-				return historyDao.InsertIfAbsent(&model.History{
-					UserID:        st.userId,
-					URL:           pageURL,
-					Title:         project.ShortTitle,
-					UpdatedAt:     st.now,
-					HasTenderCode: btoi(project.HasTenderCode),
-				})
+				return historyDao.InsertIfAbsent(project.History(st.userId, st.now))
 			},
 			Send: func() error {
+				if renderErr != nil {
+					logger.Error().Stack().Err(renderErr).Msgf("render %s failed", pageURL)
+					return renderErr
+				}
 				return r.sendProject(st, project, chunks, total)
 			},
 			Rollback: func() error {
 				if st.isForced {
 					return nil
 				}
+				pd.run.rollback()
 				return historyDao.Remove(st.userId, pageURL)
 			},
 		})
@@ -299,25 +495,17 @@ func (r *InfoProcessor) processProjects(pd ProcessData) {
 		r.releaseLock(st.userId, project.Pageurl)
 	}
 
+	// The failure summary is a message of the bot, it goes to the Telegram
+	// chat whatever the notifier.
 	if len(st.failed) > 1 {
-		if _, err := r.ctx.Bot.SendMessage(context.Background(), &bot.SendMessageParams{
-			ChatID:    st.userId,
-			Text:      strings.Join(st.failed, "\n"),
-			ParseMode: models.ParseModeHTML,
-		}); err != nil {
+		if err := r.push(st.userId, "", strings.Join(st.failed, "\n"), st.held, false); err != nil {
 			logger.Error().Stack().Err(err).Msg("")
 		} else {
 			// The failure summary reached the user, so persist the failed
 			// URLs to avoid re-pushing them on every run. Their claims (if
 			// any) were already rolled back when the first chunk failed.
 			for _, v := range st.filterFailed {
-				if _, err := historyDao.InsertIfAbsent(&model.History{
-					UserID:        st.userId,
-					URL:           v.Pageurl,
-					Title:         v.ShortTitle,
-					HasTenderCode: btoi(v.HasTenderCode),
-					UpdatedAt:     st.now,
-				}); err != nil {
+				if _, err := historyDao.InsertIfAbsent(v.History(st.userId, st.now)); err != nil {
 					logger.Error().Stack().Err(err).Msg("")
 				}
 			}
@@ -331,10 +519,10 @@ func (r *InfoProcessor) processProjects(pd ProcessData) {
 	}
 }
 
-// sendProject sends a project's chunked message, collecting failures for the
-// summary. It returns an error only when the very first chunk failed (nothing
-// was delivered), which makes the PushPipeline roll back the claim so the
-// next run can retry.
+// sendProject sends a project's chunked message through push, collecting
+// the chunks refused for good for the summary. It returns an error only when
+// the very first chunk was neither sent nor queued, which makes the
+// PushPipeline roll back the claim so the next run can retry.
 func (r *InfoProcessor) sendProject(st *projectPushState, project *Project, chunks []string, total int) error {
 	logger := r.ctx.Logger
 	pageURL := project.Pageurl
@@ -343,11 +531,7 @@ func (r *InfoProcessor) sendProject(st *projectPushState, project *Project, chun
 
 	isSuccessful := false
 	for idx, chunk := range chunks {
-		if _, errSend := r.ctx.Bot.SendMessage(context.Background(), &bot.SendMessageParams{
-			ChatID:    st.userId,
-			Text:      chunk,
-			ParseMode: models.ParseModeHTML,
-		}); errSend != nil {
+		if errSend := r.push(st.userId, st.notifier, chunk, st.held, false); errSend != nil {
 			if !isSuccessful {
 				if _, ok := st.filterFailed[pageURL]; !ok {
 					st.filterFailed[pageURL] = project
@@ -363,19 +547,12 @@ func (r *InfoProcessor) sendProject(st *projectPushState, project *Project, chun
 			isSuccessful = true
 			logger.Info().Msgf("notify: %s[%s]-%d", shortTitle, project.OpenTenderCode, idx)
 		}
-		time.Sleep(500 * time.Millisecond)
 	}
 
 	if isSuccessful && total > 0 && st.isForced {
 		// Forced path bypasses the claim, so persist history here.
 		// Normal path already persisted the row at claim time.
-		st.processedURL = append(st.processedURL, &model.History{
-			UserID:        st.userId,
-			URL:           pageURL,
-			Title:         shortTitle,
-			UpdatedAt:     st.now,
-			HasTenderCode: btoi(project.HasTenderCode),
-		})
+		st.processedURL = append(st.processedURL, project.History(st.userId, st.now))
 	}
 	return nil
 }
@@ -461,30 +638,27 @@ func (r *InfoProcessor) claimAlarm(alarm *model.Alarm) (bool, error) {
 	return dal.Alarm.InsertIfAbsent(alarm)
 }
 
-// sendAlarm renders and sends a single alarm message.
+// sendAlarm renders a single alarm for the notifier of the chat and sends
+// it.
 func (r *InfoProcessor) sendAlarm(alarm *model.Alarm) error {
 	logger := r.ctx.Logger
-	msg, err := alarm.ToMessage()
+	s := r.settings(alarm.UserID)
+	notifier, spec := r.notifierOf(alarm.UserID, s.Notifier)
+	msgs, err := notifier.Render(&Notification{Alarm: alarm})
 	if err != nil {
 		logger.Error().Stack().Err(err).Msg("alarm to msg")
 		return err
 	}
-	if _, msgErr := r.ctx.Bot.SendMessage(context.Background(), &bot.SendMessageParams{
-		ChatID:    alarm.UserID,
-		Text:      msg,
-		ParseMode: models.ParseModeHTML,
-	}); msgErr != nil {
-		logger.Error().Stack().Err(msgErr).Msg("send alarm")
-		return msgErr
+	held := quietAt(s, time.Now()) && s.AlarmExempt == 0
+	for _, msg := range msgs {
+		if msgErr := r.push(alarm.UserID, spec, msg, held, true); msgErr != nil {
+			logger.Error().Stack().Err(msgErr).Msg("send alarm")
+			return msgErr
+		}
 	}
 	return nil
 }
 
-func (r *InfoProcessor) ToMessage(project *Project) ([]string, int) {
-	project.Content = utils.SimplifyContent(project.Content)
-	return project.SplitMessage()
-}
-
 func cleanContent(content string) string {
 	// Remove HTML attributes and tags
 	content = htmlAttrRegex.ReplaceAllString(content, "")
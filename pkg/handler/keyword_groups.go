@@ -0,0 +1,410 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gythialy/magnet/pkg/constant"
+	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// schedule is the time a keyword group pushes notices in, written as days and
+// a daily window, e.g. "mon-fri 08:00-18:00", "sat,sun" or "22:00-06:00".
+// Either part may be left out. A window ending before it starts runs over
+// midnight and belongs to the day it started on.
+type schedule struct {
+	days       [7]bool
+	start, end int // minutes since midnight
+	window     bool
+}
+
+func parseSchedule(s string) (schedule, error) {
+	var sc schedule
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 || len(fields) > 2 {
+		return sc, fmt.Errorf("invalid schedule %q, e.g. mon-fri 08:00-18:00", s)
+	}
+	hasDays := false
+	for _, f := range fields {
+		if strings.Contains(f, ":") {
+			if sc.window {
+				return sc, fmt.Errorf("invalid schedule %q, more than one window", s)
+			}
+			from, to, ok := strings.Cut(f, "-")
+			if !ok {
+				return sc, fmt.Errorf("invalid window %q, e.g. 08:00-18:00", f)
+			}
+			var err error
+			if sc.start, err = parseClock(from); err != nil {
+				return sc, err
+			}
+			if sc.end, err = parseClock(to); err != nil {
+				return sc, err
+			}
+			if sc.start == sc.end {
+				return sc, fmt.Errorf("invalid window %q, empty", f)
+			}
+			sc.window = true
+			continue
+		}
+		if hasDays {
+			return sc, fmt.Errorf("invalid schedule %q, more than one list of days", s)
+		}
+		hasDays = true
+		for _, d := range strings.Split(f, ",") {
+			from, to, isRange := strings.Cut(d, "-")
+			first, ok := weekdays[from]
+			if !ok {
+				return sc, fmt.Errorf("invalid day %q, use mon, tue, wed, thu, fri, sat or sun", from)
+			}
+			last := first
+			if isRange {
+				if last, ok = weekdays[to]; !ok {
+					return sc, fmt.Errorf("invalid day %q, use mon, tue, wed, thu, fri, sat or sun", to)
+				}
+			}
+			// A range may wrap around the week, e.g. fri-mon.
+			for w := first; ; w = (w + 1) % 7 {
+				sc.days[w] = true
+				if w == last {
+					break
+				}
+			}
+		}
+	}
+	if !hasDays {
+		sc.days = [7]bool{true, true, true, true, true, true, true}
+	}
+	return sc, nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, e.g. 08:00", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// active reports whether t, taken in loc, is within sc.
+func (sc schedule) active(t time.Time, loc *time.Location) bool {
+	t = t.In(loc)
+	day, m := t.Weekday(), t.Hour()*60+t.Minute()
+	if !sc.window {
+		return sc.days[day]
+	}
+	if sc.start < sc.end {
+		return sc.days[day] && m >= sc.start && m < sc.end
+	}
+	if m >= sc.start {
+		return sc.days[day]
+	}
+	return m < sc.end && sc.days[(day+6)%7]
+}
+
+// groupActive reports whether the keywords of g push notices at now, the
+// schedule of g is taken in loc.
+func groupActive(g *model.KeywordGroup, now time.Time, loc *time.Location) bool {
+	if g.Muted != 0 {
+		return false
+	}
+	if g.Schedule == "" {
+		return true
+	}
+	sc, err := parseSchedule(g.Schedule)
+	// Schedules are validated when set, a broken one does not silence the
+	// group.
+	return err != nil || sc.active(now, loc)
+}
+
+// groupLabel is the prefix of the [Keyword] line for the rules of g.
+func groupLabel(g *model.KeywordGroup) string {
+	if g.Label != "" {
+		return g.Label
+	}
+	return g.Name
+}
+
+// applyGroupSetting sets one "key=value" setting of /set_group on g.
+func applyGroupSetting(g *model.KeywordGroup, setting string) error {
+	key, value, ok := strings.Cut(setting, "=")
+	key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+	if !ok {
+		return fmt.Errorf("invalid setting %q, use key=value", setting)
+	}
+	switch key {
+	case "mute":
+		switch strings.ToLower(value) {
+		case "on", "true", "1":
+			g.Muted = 1
+		case "off", "false", "0":
+			g.Muted = 0
+		default:
+			return fmt.Errorf("invalid mute %q, use on or off", value)
+		}
+	case "schedule":
+		if value != "" {
+			if _, err := parseSchedule(value); err != nil {
+				return err
+			}
+		}
+		g.Schedule = strings.ToLower(value)
+	case "chat":
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid chat %q, use a chat id or 0 for this chat", value)
+		}
+		g.TargetChatID = id
+	case "label":
+		g.Label = value
+	case "notify":
+		spec, err := parseNotifier(value)
+		if err != nil {
+			return err
+		}
+		g.Notifier = spec.String()
+	default:
+		return fmt.Errorf("unknown setting %q, use mute, schedule, chat, label or notify", key)
+	}
+	return nil
+}
+
+// parseGroupId parses the leading group id of a group command.
+func parseGroupId(s string) (int32, string, error) {
+	id, rest, _ := strings.Cut(strings.TrimSpace(s), " ")
+	i, err := strconv.ParseInt(id, 10, 32)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid group id %q", id)
+	}
+	return int32(i), strings.TrimSpace(rest), nil
+}
+
+// AddGroupHandler creates a keyword group.
+func (c *CommandsHandler) AddGroupHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	name := strings.TrimSpace(strings.TrimPrefix(update.Message.Text, constant.AddGroup))
+	group, err := dal.KeywordGroup.Add(update.Message.Chat.ID, name)
+	if err != nil {
+		c.sendErrorMessage(ctx, b, update, fmt.Sprintf("%s failed, %s", constant.AddGroup, err.Error()))
+		return
+	}
+	c.sendText(ctx, b, update, fmt.Sprintf("%s: %d:%s", constant.AddGroup, *group.ID, group.Name))
+}
+
+// GroupsHandler lists the keyword groups with their settings and keywords.
+func (c *CommandsHandler) GroupsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	userId := update.Message.Chat.ID
+	groups := dal.KeywordGroup.GetByUserId(userId)
+	keywords := dal.Keyword.GetByUserIdAndType(userId, model.PROJECT)
+	if len(groups) == 0 {
+		c.sendText(ctx, b, update, fmt.Sprintf("No keyword groups, create one with %s <name>.", constant.AddGroup))
+		return
+	}
+	c.sendOrEditMessage(ctx, b, userId, defaultMessageId, formatGroups(groups, keywords), nil)
+}
+
+// formatGroups renders the groups and the keywords in each of them, the
+// ungrouped keywords last.
+func formatGroups(groups []*model.KeywordGroup, keywords []*model.Keyword) string {
+	members := make(map[int32][]*model.Keyword)
+	var ungrouped []*model.Keyword
+	for _, kw := range keywords {
+		if kw.GroupID == nil {
+			ungrouped = append(ungrouped, kw)
+			continue
+		}
+		members[*kw.GroupID] = append(members[*kw.GroupID], kw)
+	}
+
+	var b strings.Builder
+	for _, g := range groups {
+		fmt.Fprintf(&b, "<b>%d:%s</b>", *g.ID, html.EscapeString(g.Name))
+		var settings []string
+		if g.Label != "" {
+			settings = append(settings, "label="+html.EscapeString(g.Label))
+		}
+		if g.Muted != 0 {
+			settings = append(settings, "muted")
+		}
+		if g.Schedule != "" {
+			settings = append(settings, "schedule="+g.Schedule)
+		}
+		if g.TargetChatID != 0 {
+			settings = append(settings, fmt.Sprintf("chat=%d", g.TargetChatID))
+		}
+		if g.Notifier != "" {
+			settings = append(settings, "notify="+html.EscapeString(describeNotifier(g.Notifier)))
+		}
+		if len(settings) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(settings, ", "))
+		}
+		b.WriteString("\n")
+		writeGroupKeywords(&b, members[*g.ID])
+		// Keywords of a deleted group are ungrouped in Remove, this only
+		// guards against rows changed by hand.
+		delete(members, *g.ID)
+		b.WriteString("\n")
+	}
+	for _, kws := range members {
+		ungrouped = append(ungrouped, kws...)
+	}
+	if len(ungrouped) > 0 {
+		b.WriteString("<b>Ungrouped</b>\n")
+		writeGroupKeywords(&b, ungrouped)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func writeGroupKeywords(b *strings.Builder, keywords []*model.Keyword) {
+	if len(keywords) == 0 {
+		b.WriteString("- no keywords\n")
+		return
+	}
+	for _, kw := range keywords {
+		status := ""
+		if kw.Muted != 0 {
+			status = " (muted)"
+		}
+		fmt.Fprintf(b, "- [%d] %s%s\n", kw.Number, html.EscapeString(ruleString(kw)), status)
+	}
+}
+
+// RenameGroupHandler renames a keyword group, e.g. "/rename_group 1 competitors".
+func (c *CommandsHandler) RenameGroupHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	id, name, err := parseGroupId(strings.TrimPrefix(update.Message.Text, constant.RenameGroup))
+	if err == nil {
+		err = dal.KeywordGroup.Rename(update.Message.Chat.ID, id, name)
+	}
+	if err != nil {
+		c.sendErrorMessage(ctx, b, update, fmt.Sprintf("%s failed, %s", constant.RenameGroup, err.Error()))
+		return
+	}
+	c.sendText(ctx, b, update, fmt.Sprintf("%s: %d:%s", constant.RenameGroup, id, name))
+}
+
+// SetGroupHandler changes the settings of a keyword group, e.g.
+// "/set_group 1 mute=off;schedule=mon-fri 08:00-18:00;label=网络". A new target
+// chat, given by id or as @name, is checked to be one the sender administers
+// and the bot can post to. notify=<channel> sends the notices of the group
+// over another channel than the target chat, e.g. notify=email:ops@example.com.
+func (c *CommandsHandler) SetGroupHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	id, rest, err := parseGroupId(strings.TrimPrefix(update.Message.Text, constant.SetGroup))
+	var group *model.KeywordGroup
+	if err == nil {
+		group, err = dal.KeywordGroup.GetById(update.Message.Chat.ID, id)
+	}
+	var target int64
+	var notifier string
+	if group != nil {
+		target, notifier = group.TargetChatID, group.Notifier
+	}
+	if err == nil && rest == "" {
+		err = fmt.Errorf("no settings, use %s <id> mute=on|off;schedule=mon-fri 08:00-18:00;chat=<chat id>;label=<text>;"+
+			"notify=<channel>", constant.SetGroup)
+	}
+	if err == nil {
+		for _, setting := range strings.Split(rest, ";") {
+			if strings.TrimSpace(setting) == "" {
+				continue
+			}
+			if key, value, ok := strings.Cut(setting, "="); ok && strings.EqualFold(strings.TrimSpace(key), "chat") {
+				if value, err = resolveChat(ctx, b, strings.TrimSpace(value)); err != nil {
+					break
+				}
+				setting = "chat=" + value
+			}
+			if err = applyGroupSetting(group, setting); err != nil {
+				break
+			}
+		}
+	}
+	if err == nil && group.Notifier != notifier {
+		err = checkNotifier(c.ctx.Config.SMTP, group.Notifier)
+	}
+	if err == nil && group.TargetChatID != 0 && group.TargetChatID != target {
+		err = checkPushTarget(ctx, b, group.TargetChatID, senderId(update.Message.Chat.ID, update.Message.From))
+	}
+	if err == nil {
+		err = dal.KeywordGroup.Save(group)
+	}
+	if err != nil {
+		c.sendErrorMessage(ctx, b, update, fmt.Sprintf("%s failed, %s", constant.SetGroup, err.Error()))
+		return
+	}
+	c.sendText(ctx, b, update, fmt.Sprintf("%s: %d:%s updated.", constant.SetGroup, id, group.Name))
+}
+
+// MoveKeywordsHandler moves keywords into a group, group 0 moves them out of
+// their group, e.g. "/move_keywords 1 3,4".
+func (c *CommandsHandler) MoveKeywordsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	userId := update.Message.Chat.ID
+	id, rest, err := parseGroupId(strings.TrimPrefix(update.Message.Text, constant.MoveKeywords))
+	var groupId *int32
+	if err == nil && id != 0 {
+		var group *model.KeywordGroup
+		if group, err = dal.KeywordGroup.GetById(userId, id); err == nil {
+			groupId = group.ID
+		}
+	}
+	var ids []int32
+	if err == nil {
+		for _, s := range strings.Split(rest, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			i, e := strconv.ParseInt(s, 10, 32)
+			if e != nil {
+				err = fmt.Errorf("invalid keyword id %q", s)
+				break
+			}
+			ids = append(ids, int32(i))
+		}
+		if err == nil && len(ids) == 0 {
+			err = fmt.Errorf("no keyword ids, use %s <group id|0> <id1,id2>", constant.MoveKeywords)
+		}
+	}
+	var moved int64
+	if err == nil {
+		moved, err = dal.Keyword.MoveToGroup(userId, groupId, ids)
+	}
+	if err != nil {
+		c.sendErrorMessage(ctx, b, update, fmt.Sprintf("%s failed, %s", constant.MoveKeywords, err.Error()))
+		return
+	}
+	c.sendText(ctx, b, update, fmt.Sprintf("%s: %d keywords moved.", constant.MoveKeywords, moved))
+}
+
+// DeleteGroupHandler deletes a keyword group, its keywords are kept ungrouped.
+func (c *CommandsHandler) DeleteGroupHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	id, _, err := parseGroupId(strings.TrimPrefix(update.Message.Text, constant.DeleteGroup))
+	if err == nil {
+		err = dal.KeywordGroup.Remove(update.Message.Chat.ID, id)
+	}
+	if err != nil {
+		c.sendErrorMessage(ctx, b, update, fmt.Sprintf("%s failed, %s", constant.DeleteGroup, err.Error()))
+		return
+	}
+	c.sendText(ctx, b, update, fmt.Sprintf("%s: %d", constant.DeleteGroup, id))
+}
+
+func (c *CommandsHandler) sendText(ctx context.Context, b *bot.Bot, update *models.Update, text string) {
+	if _, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: update.Message.Chat.ID,
+		Text:   text,
+	}); err != nil {
+		c.ctx.Logger.Error().Stack().Err(err).Msg("")
+	}
+}
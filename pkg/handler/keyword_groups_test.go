@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
+	"gorm.io/gorm"
+)
+
+func TestSchedule_Active(t *testing.T) {
+	// 2024-06-03 is a Monday.
+	at := func(day int, clock string) time.Time {
+		t, _ := time.ParseInLocation("2006-01-02 15:04", fmt.Sprintf("2024-06-%02d %s", day, clock), cst)
+		return t
+	}
+	tests := []struct {
+		schedule string
+		at       time.Time
+		want     bool
+	}{
+		{"mon-fri 08:00-18:00", at(3, "09:30"), true},
+		{"mon-fri 08:00-18:00", at(3, "18:00"), false},
+		{"mon-fri 08:00-18:00", at(8, "09:30"), false},
+		{"sat,sun", at(8, "23:59"), true},
+		{"sat,sun", at(7, "12:00"), false},
+		{"fri-mon", at(3, "12:00"), true},
+		{"fri-mon", at(4, "12:00"), false},
+		{"22:00-06:00", at(4, "23:00"), true},
+		{"22:00-06:00", at(4, "05:59"), true},
+		{"22:00-06:00", at(4, "12:00"), false},
+		// After midnight the window still belongs to Friday.
+		{"fri 22:00-06:00", at(8, "01:00"), true},
+		{"fri 22:00-06:00", at(7, "01:00"), false},
+		// Taken in the given zone whatever the zone of t.
+		{"08:00-18:00", at(3, "09:00").UTC(), true},
+	}
+	for _, tt := range tests {
+		sc, err := parseSchedule(tt.schedule)
+		if err != nil {
+			t.Fatalf("parseSchedule(%q): %v", tt.schedule, err)
+		}
+		if got := sc.active(tt.at, cst); got != tt.want {
+			t.Errorf("%q.active(%s) = %v, want %v", tt.schedule, tt.at.Format("Mon 15:04"), got, tt.want)
+		}
+	}
+
+	for _, s := range []string{"", "weekdays", "mon-fri 8-18", "08:00-08:00", "mon tue", "08:00-09:00 10:00-11:00", "25:00-26:00"} {
+		if _, err := parseSchedule(s); err == nil {
+			t.Errorf("parseSchedule(%q) expected an error", s)
+		}
+	}
+}
+
+func TestApplyGroupSetting(t *testing.T) {
+	g := &model.KeywordGroup{Name: "network gear"}
+	for _, s := range []string{"mute=on", "schedule=Mon-Fri 08:00-18:00", "chat=-100123", "label=网络 设备",
+		"notify=Email:Ops <ops@example.com>"} {
+		if err := applyGroupSetting(g, s); err != nil {
+			t.Fatalf("applyGroupSetting(%q): %v", s, err)
+		}
+	}
+	if g.Muted != 1 || g.Schedule != "mon-fri 08:00-18:00" || g.TargetChatID != -100123 || g.Label != "网络 设备" ||
+		g.Notifier != "email:ops@example.com" {
+		t.Errorf("unexpected settings %+v", g)
+	}
+	if groupActive(g, time.Now(), cst) {
+		t.Error("expected a muted group to be inactive")
+	}
+	for _, s := range []string{"mute=maybe", "schedule=soon", "chat=me", "colour=red", "label", "notify=fax:123"} {
+		if err := applyGroupSetting(g, s); err == nil {
+			t.Errorf("applyGroupSetting(%q) expected an error", s)
+		}
+	}
+}
+
+func TestInfoProcessor_GetGroups(t *testing.T) {
+	f := "./keyword_groups_test.db"
+	defer func() { _ = os.Remove(f) }()
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.Keyword{}, &model.KeywordGroup{}, &model.ChatSetting{})
+	dal.SetDefault(db)
+
+	userId, channel := int64(4444), int64(-1005555)
+	dal.Keyword.Insert([]string{"服务器", "交换机", "路由器", "防火墙"}, userId, model.PROJECT)
+	dal.Keyword.Insert([]string{"某公司"}, userId, model.ALARM)
+	ids, numbers := make(map[string]int32), make(map[string]int32)
+	for _, kw := range dal.Keyword.GetByUserIdAndType(userId, model.PROJECT) {
+		ids[kw.Keyword], numbers[kw.Keyword] = *kw.ID, kw.Number
+	}
+	gear, _ := dal.KeywordGroup.Add(userId, "network gear")
+	gear.Label, gear.TargetChatID = "网络", channel
+	muted, _ := dal.KeywordGroup.Add(userId, "muted")
+	muted.Muted = 1
+	mail, _ := dal.KeywordGroup.Add(userId, "mail")
+	mail.Notifier = "email:ops@example.com"
+	for _, g := range []*model.KeywordGroup{gear, muted, mail} {
+		if err := dal.KeywordGroup.Save(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, _ = dal.Keyword.MoveToGroup(userId, gear.ID, []int32{numbers["交换机"]})
+	_, _ = dal.Keyword.MoveToGroup(userId, muted.ID, []int32{numbers["路由器"]})
+	_, _ = dal.Keyword.MoveToGroup(userId, mail.ID, []int32{numbers["防火墙"]})
+
+	conf := (&InfoProcessor{}).get(userId, time.Now())
+	if len(conf) != 3 {
+		t.Fatalf("expected the own chat, the channel and the mail, got %d entries", len(conf))
+	}
+	own, redirected, mailed := conf[0], conf[1], conf[2]
+	if own.ChatId != userId || len(own.ProjectRules) != 1 || own.ProjectRules[0].Rule.Keyword != "服务器" ||
+		len(own.AlarmKeyword) != 1 {
+		t.Errorf("unexpected own entry %+v", own)
+	}
+	if redirected.ChatId != channel || redirected.UserId != userId || len(redirected.ProjectRules) != 1 ||
+		redirected.ProjectRules[0].Rule.Keyword != "交换机" || len(redirected.AlarmKeyword) != 0 {
+		t.Errorf("unexpected redirected entry %+v", redirected)
+	}
+	if label := redirected.Labels[ids["交换机"]]; label != "网络" {
+		t.Errorf("expected the group label, got %q", label)
+	}
+	if own.Notifier != "" || mailed.ChatId != userId || mailed.Notifier != "email:ops@example.com" ||
+		len(mailed.ProjectRules) != 1 || mailed.ProjectRules[0].Rule.Keyword != "防火墙" {
+		t.Errorf("unexpected mailed entry %+v", mailed)
+	}
+
+	text := formatGroups(dal.KeywordGroup.GetByUserId(userId), dal.Keyword.GetByUserIdAndType(userId, model.PROJECT))
+	for _, want := range []string{"network gear</b> (label=网络, chat=-1005555)", "muted</b> (muted)",
+		"mail</b> (notify=email:ops@example.com)", "<b>Ungrouped</b>\n- ["} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gythialy/magnet/pkg/constant"
+	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
+	"github.com/gythialy/magnet/pkg/rule"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// keywordFileVersion is the version of the keyword file format.
+	keywordFileVersion = 1
+	// maxImportSize is the largest keyword file /import_keywords reads.
+	maxImportSize = 1 << 20
+)
+
+// csvHeader is the header row of a keyword file in CSV.
+var csvHeader = []string{"type", "group", "keyword"}
+
+// keywordTypes names the keyword types in keyword files.
+var keywordTypes = map[string]model.KeywordType{
+	"project": model.PROJECT,
+	"alarm":   model.ALARM,
+	"filter":  model.FILTER,
+}
+
+// keywordFile is the document /export_keywords writes and /import_keywords
+// reads, as JSON or YAML.
+type keywordFile struct {
+	Version  int            `json:"version" yaml:"version"`
+	Keywords []keywordEntry `json:"keywords" yaml:"keywords"`
+}
+
+// keywordEntry is a keyword of a keyword file. Project keywords are written
+// as Rule, the others, and project keywords that do not parse, as Keyword.
+type keywordEntry struct {
+	Type    string            `json:"type" yaml:"type"`
+	Group   string            `json:"group,omitempty" yaml:"group,omitempty"`
+	Keyword string            `json:"keyword,omitempty" yaml:"keyword,omitempty"`
+	Rule    *rule.ComplexRule `json:"rule,omitempty" yaml:"rule,omitempty"`
+}
+
+// importEntry is a keyword read from a keyword file, rule reports a project
+// keyword's rule or why it is malformed.
+type importEntry struct {
+	Type    string
+	Group   string
+	Keyword string
+	rule    func() (*rule.ComplexRule, error)
+}
+
+// exportKeywords renders the keywords of userId in format, one of json, yaml
+// or csv.
+func exportKeywords(userId int64, format string) ([]byte, error) {
+	groups := make(map[int32]string)
+	for _, g := range dal.KeywordGroup.GetByUserId(userId) {
+		groups[*g.ID] = g.Name
+	}
+	file := keywordFile{Version: keywordFileVersion}
+	for _, name := range []string{"project", "alarm", "filter"} {
+		for _, kw := range dal.Keyword.GetByUserIdAndType(userId, keywordTypes[name]) {
+			e := keywordEntry{Type: name, Keyword: kw.Keyword}
+			if kw.GroupID != nil {
+				e.Group = groups[*kw.GroupID]
+			}
+			if name == "project" {
+				if cr := rule.NewComplexRule(kw); cr != nil {
+					e.Rule, e.Keyword = cr, ""
+				}
+			}
+			file.Keywords = append(file.Keywords, e)
+		}
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file); err != nil {
+			return nil, err
+		}
+	case "yaml":
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(file); err != nil {
+			return nil, err
+		}
+	case "csv":
+		w := csv.NewWriter(&buf)
+		_ = w.Write(csvHeader)
+		for _, e := range file.Keywords {
+			keyword := e.Keyword
+			if e.Rule != nil {
+				keyword = e.Rule.Rule.Keyword
+			}
+			_ = w.Write([]string{e.Type, e.Group, keyword})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q, use json, yaml or csv", format)
+	}
+	return buf.Bytes(), nil
+}
+
+// parseKeywordFile reads a keyword file, the format is taken from the
+// extension of name and guessed from the content without one.
+func parseKeywordFile(name string, data []byte) ([]importEntry, error) {
+	format := strings.TrimPrefix(strings.ToLower(path.Ext(name)), ".")
+	if format == "" || format == "txt" {
+		trimmed := bytes.TrimSpace(data)
+		switch {
+		case bytes.HasPrefix(trimmed, []byte("{")):
+			format = "json"
+		case bytes.HasPrefix(trimmed, []byte(strings.Join(csvHeader, ","))):
+			format = "csv"
+		default:
+			format = "yaml"
+		}
+	}
+
+	var entries []importEntry
+	switch format {
+	case "json":
+		var file struct {
+			Keywords []struct {
+				Type    string          `json:"type"`
+				Group   string          `json:"group"`
+				Keyword string          `json:"keyword"`
+				Rule    json.RawMessage `json:"rule"`
+			} `json:"keywords"`
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, err
+		}
+		for _, e := range file.Keywords {
+			entry := importEntry{Type: e.Type, Group: e.Group, Keyword: e.Keyword}
+			if raw := e.Rule; len(raw) > 0 {
+				entry.rule = func() (*rule.ComplexRule, error) {
+					var cr rule.ComplexRule
+					return &cr, json.Unmarshal(raw, &cr)
+				}
+			}
+			entries = append(entries, entry)
+		}
+	case "yaml", "yml":
+		var file struct {
+			Keywords []struct {
+				Type    string    `yaml:"type"`
+				Group   string    `yaml:"group"`
+				Keyword string    `yaml:"keyword"`
+				Rule    yaml.Node `yaml:"rule"`
+			} `yaml:"keywords"`
+		}
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return nil, err
+		}
+		for _, e := range file.Keywords {
+			entry := importEntry{Type: e.Type, Group: e.Group, Keyword: e.Keyword}
+			if node := e.Rule; !node.IsZero() {
+				entry.rule = func() (*rule.ComplexRule, error) {
+					var cr rule.ComplexRule
+					return &cr, node.Decode(&cr)
+				}
+			}
+			entries = append(entries, entry)
+		}
+	case "csv":
+		r := csv.NewReader(bytes.NewReader(data))
+		r.FieldsPerRecord = len(csvHeader)
+		records, err := r.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		if len(records) > 0 && strings.Join(records[0], ",") == strings.Join(csvHeader, ",") {
+			records = records[1:]
+		}
+		for _, rec := range records {
+			entries = append(entries, importEntry{Type: rec[0], Group: rec[1], Keyword: rec[2]})
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q, use json, yaml or csv", format)
+	}
+	return entries, nil
+}
+
+// importResult is the outcome of checking a keyword file against the
+// keywords of a user.
+type importResult struct {
+	add []dal.ImportedKeyword
+	// invalid, duplicates and conflicts describe the entries that are not
+	// imported: malformed ones, repeated ones and the ones stored already.
+	invalid    []string
+	duplicates []string
+	conflicts  []string
+}
+
+// checkImport validates entries and sorts out the ones repeated in the file
+// or stored for userId already. A keyword stored in another group than the
+// file names is reported as a conflict and left where it is.
+func checkImport(userId int64, entries []importEntry) *importResult {
+	groups := make(map[int32]string)
+	for _, g := range dal.KeywordGroup.GetByUserId(userId) {
+		groups[*g.ID] = g.Name
+	}
+	type key struct {
+		t       model.KeywordType
+		keyword string
+	}
+	stored := make(map[key]string)
+	for _, t := range keywordTypes {
+		for _, kw := range dal.Keyword.GetByUserIdAndType(userId, t) {
+			group := ""
+			if kw.GroupID != nil {
+				group = groups[*kw.GroupID]
+			}
+			stored[key{t, kw.Keyword}] = group
+		}
+	}
+
+	result := &importResult{}
+	seen := make(map[key]bool)
+	for i, e := range entries {
+		n := i + 1
+		t, ok := keywordTypes[strings.ToLower(strings.TrimSpace(e.Type))]
+		if !ok {
+			result.invalid = append(result.invalid, fmt.Sprintf("%d: unknown type %q, use project, alarm or filter", n, e.Type))
+			continue
+		}
+		keyword := strings.TrimSpace(e.Keyword)
+		switch {
+		case t == model.PROJECT && e.rule != nil:
+			cr, err := e.rule()
+			if err != nil {
+				result.invalid = append(result.invalid, fmt.Sprintf("%d: %s", n, err.Error()))
+				continue
+			}
+			keyword = cr.Rule.Keyword
+		case keyword == "":
+			result.invalid = append(result.invalid, fmt.Sprintf("%d: empty keyword", n))
+			continue
+		case t == model.PROJECT:
+			if _, invalid := validateRules([]string{keyword}); len(invalid) > 0 {
+				result.invalid = append(result.invalid, fmt.Sprintf("%d: %s", n, invalid[0]))
+				continue
+			}
+		case t == model.FILTER:
+			k, v, err := ParseFilterTerm(keyword)
+			if err != nil {
+				result.invalid = append(result.invalid, fmt.Sprintf("%d: %s", n, err.Error()))
+				continue
+			}
+			keyword = k + "=" + v
+		}
+
+		k := key{t, keyword}
+		group := strings.TrimSpace(e.Group)
+		if seen[k] {
+			result.duplicates = append(result.duplicates, fmt.Sprintf("%d: %s", n, keyword))
+			continue
+		}
+		seen[k] = true
+		if current, ok := stored[k]; ok {
+			if current != group {
+				result.conflicts = append(result.conflicts,
+					fmt.Sprintf("%d: %s is in group %q, not %q", n, keyword, current, group))
+			} else {
+				result.duplicates = append(result.duplicates, fmt.Sprintf("%d: %s exists", n, keyword))
+			}
+			continue
+		}
+		result.add = append(result.add, dal.ImportedKeyword{Keyword: keyword, Type: t, Group: group})
+	}
+	return result
+}
+
+// ExportKeywordsHandler sends the keywords of the chat as a file, e.g.
+// "/export_keywords yaml", JSON by default.
+func (c *CommandsHandler) ExportKeywordsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	format := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(update.Message.Text, constant.ExportKeywords)))
+	if format == "" {
+		format = "json"
+	}
+	data, err := exportKeywords(update.Message.Chat.ID, format)
+	if err != nil {
+		c.sendErrorMessage(ctx, b, update, fmt.Sprintf("%s failed, %s", constant.ExportKeywords, err.Error()))
+		return
+	}
+	if _, err := b.SendDocument(ctx, &bot.SendDocumentParams{
+		ChatID: update.Message.Chat.ID,
+		Document: &models.InputFileUpload{
+			Filename: "keywords." + format,
+			Data:     bytes.NewReader(data),
+		},
+		Caption: fmt.Sprintf("Import it again with %s", constant.ImportKeywords),
+	}); err != nil {
+		c.ctx.Logger.Error().Stack().Err(err).Msg("")
+	}
+}
+
+// isImportDocument matches a keyword file sent with /import_keywords as its
+// caption.
+func isImportDocument(update *models.Update) bool {
+	return update.Message != nil && update.Message.Document != nil &&
+		strings.HasPrefix(update.Message.Caption, constant.ImportKeywords)
+}
+
+// ImportKeywordsHandler imports a keyword file sent with /import_keywords as
+// its caption, or replied to with /import_keywords. Nothing is imported when
+// an entry is malformed, entries stored already are skipped.
+func (c *CommandsHandler) ImportKeywordsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	doc := update.Message.Document
+	if doc == nil && update.Message.ReplyToMessage != nil {
+		doc = update.Message.ReplyToMessage.Document
+	}
+	if doc == nil {
+		c.sendErrorMessage(ctx, b, update, fmt.Sprintf("Send a JSON, YAML or CSV file of %s with %s as its caption.",
+			constant.ExportKeywords, constant.ImportKeywords))
+		return
+	}
+	if doc.FileSize > maxImportSize {
+		c.sendErrorMessage(ctx, b, update, fmt.Sprintf("%s failed, the file is larger than %d KB", constant.ImportKeywords, maxImportSize>>10))
+		return
+	}
+	data, err := c.downloadFile(ctx, b, doc.FileID)
+	var entries []importEntry
+	if err == nil {
+		entries, err = parseKeywordFile(doc.FileName, data)
+	}
+	if err != nil {
+		c.sendErrorMessage(ctx, b, update, fmt.Sprintf("%s failed, %s", constant.ImportKeywords, err.Error()))
+		return
+	}
+
+	userId := update.Message.Chat.ID
+	result := checkImport(userId, entries)
+	var text strings.Builder
+	if len(result.invalid) > 0 {
+		fmt.Fprintf(&text, "%s: nothing imported, fix these entries first:\n%s", constant.ImportKeywords,
+			strings.Join(result.invalid, "\n"))
+	} else if created, err := dal.Keyword.Import(userId, result.add); err != nil {
+		c.sendErrorMessage(ctx, b, update, fmt.Sprintf("%s failed, %s", constant.ImportKeywords, err.Error()))
+		return
+	} else {
+		fmt.Fprintf(&text, "%s: %d keywords imported", constant.ImportKeywords, len(result.add))
+		if created > 0 {
+			fmt.Fprintf(&text, ", %d groups created", created)
+		}
+		text.WriteString(".")
+		if len(result.duplicates) > 0 {
+			fmt.Fprintf(&text, "\n\nSkipped:\n%s", strings.Join(result.duplicates, "\n"))
+		}
+		if len(result.conflicts) > 0 {
+			fmt.Fprintf(&text, "\n\nConflicts, left unchanged:\n%s", strings.Join(result.conflicts, "\n"))
+		}
+	}
+	if _, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: userId,
+		Text:   text.String(),
+	}); err != nil {
+		c.ctx.Logger.Error().Stack().Err(err).Msg("")
+	}
+}
+
+// downloadFile fetches a file sent to the bot.
+func (c *CommandsHandler) downloadFile(ctx context.Context, b *bot.Bot, fileId string) ([]byte, error) {
+	f, err := b.GetFile(ctx, &bot.GetFileParams{FileID: fileId})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.FileDownloadLink(f), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed, %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxImportSize))
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
+	"gorm.io/gorm"
+)
+
+func TestKeywordFile_RoundTrip(t *testing.T) {
+	f := "./keyword_io_test.db"
+	defer func() { _ = os.Remove(f) }()
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.Keyword{}, &model.KeywordGroup{})
+	dal.SetDefault(db)
+
+	userId, other := int64(7777), int64(8888)
+	dal.Keyword.Insert([]string{"(服务器 | 存储) -维保 budget>=50万", `"a, b" content:GPU`}, userId, model.PROJECT)
+	dal.Keyword.Insert([]string{"某公司"}, userId, model.ALARM)
+	dal.Keyword.Insert([]string{"regionCode=110000"}, userId, model.FILTER)
+	gear, _ := dal.KeywordGroup.Add(userId, "network gear")
+	_, _ = dal.Keyword.MoveToGroup(userId, gear.ID, []int32{dal.Keyword.GetByUserIdAndType(userId, model.PROJECT)[0].Number})
+
+	for _, format := range []string{"json", "yaml", "csv"} {
+		data, err := exportKeywords(userId, format)
+		if err != nil {
+			t.Fatalf("export %s: %v", format, err)
+		}
+		if format != "csv" && !strings.Contains(string(data), "+(服务器 | 存储)") {
+			t.Errorf("expected the canonical rule in the %s export:\n%s", format, data)
+		}
+		entries, err := parseKeywordFile("keywords."+format, data)
+		if err != nil {
+			t.Fatalf("parse %s: %v\n%s", format, err, data)
+		}
+		// Without an extension the format is guessed.
+		if guessed, err := parseKeywordFile("keywords", data); err != nil || len(guessed) != len(entries) {
+			t.Errorf("expected the %s format to be guessed, got %d entries, %v", format, len(guessed), err)
+		}
+
+		result := checkImport(other, entries)
+		if len(result.invalid)+len(result.duplicates)+len(result.conflicts) > 0 {
+			t.Fatalf("%s: unexpected problems %+v", format, result)
+		}
+		got := make([]string, 0, len(result.add))
+		for _, kw := range result.add {
+			got = append(got, kw.Type.String()+"|"+kw.Group+"|"+kw.Keyword)
+		}
+		want := []string{
+			"PROJECT|network gear|(服务器 | 存储) -维保 budget>=50万",
+			`PROJECT||"a, b" content:GPU`,
+			"ALARM||某公司",
+			"FILTER||regionCode=110000",
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("%s: imported %q, want %q", format, got, want)
+		}
+
+		// Importing its own export changes nothing.
+		if again := checkImport(userId, entries); len(again.add) != 0 || len(again.duplicates) != len(entries) {
+			t.Errorf("%s: expected every entry to exist, got %+v", format, again)
+		}
+	}
+}
+
+func TestCheckImport(t *testing.T) {
+	f := "./check_import_test.db"
+	defer func() { _ = os.Remove(f) }()
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.Keyword{}, &model.KeywordGroup{})
+	dal.SetDefault(db)
+
+	userId := int64(9999)
+	dal.Keyword.Insert([]string{"交换机"}, userId, model.PROJECT)
+	entries, err := parseKeywordFile("keywords.yaml", []byte(`
+version: 1
+keywords:
+  - type: project
+    rule: {keyword: "服务器 -维保"}
+  - type: project
+    keyword: 服务器 -维保
+  - type: project
+    group: competitors
+    keyword: 交换机
+  - type: project
+    rule: {includeTerms: [路由器]}
+  - type: project
+    keyword: budget>abc
+  - type: filter
+    keyword: colour=red
+  - type: webhook
+    keyword: x
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := checkImport(userId, entries)
+	if len(result.add) != 2 || result.add[0].Keyword != "服务器 -维保" || result.add[1].Keyword != "+路由器" {
+		t.Errorf("unexpected keywords to add %+v", result.add)
+	}
+	if len(result.duplicates) != 1 || !strings.HasPrefix(result.duplicates[0], "2: ") {
+		t.Errorf("unexpected duplicates %q", result.duplicates)
+	}
+	if len(result.conflicts) != 1 || !strings.Contains(result.conflicts[0], `交换机 is in group "", not "competitors"`) {
+		t.Errorf("unexpected conflicts %q", result.conflicts)
+	}
+	if len(result.invalid) != 3 || !strings.HasPrefix(result.invalid[0], "5: ") ||
+		!strings.HasPrefix(result.invalid[1], "6: ") || !strings.HasPrefix(result.invalid[2], "7: ") {
+		t.Errorf("unexpected invalid entries %q", result.invalid)
+	}
+
+	if _, err := parseKeywordFile("keywords.xml", nil); err == nil {
+		t.Error("expected an unknown format to fail")
+	}
+}
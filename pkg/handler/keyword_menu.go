@@ -0,0 +1,327 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/gythialy/magnet/pkg/constant"
+	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	keywordPageSize = 10
+	// keywordButtonLength is how many characters of a rule fit a list button.
+	keywordButtonLength = 32
+	// editKeywordDialog asks for the new keyword of the edit button.
+	editKeywordDialog = "edit_keyword"
+)
+
+// The buttons of /keywords send "kw:<page>[:<action>:<number>[:<group id>]]",
+// page is the list page to go back to.
+const (
+	kwShow          = "s" // show the keyword and its buttons
+	kwDelete        = "d" // ask before deleting
+	kwConfirmDelete = "dy"
+	kwEdit          = "e" // prompt for the new keyword
+	kwMute          = "m" // mute or unmute
+	kwGroups        = "g" // list the groups to move to
+	kwMove          = "gm"
+)
+
+func keywordCallback(page int, action string, args ...int32) string {
+	data := fmt.Sprintf("%s%d", constant.KeywordsCallback, page)
+	if action != "" {
+		data += ":" + action
+	}
+	for _, arg := range args {
+		data += fmt.Sprintf(":%d", arg)
+	}
+	return data
+}
+
+// KeywordsHandler lists the keywords with buttons to manage them.
+func (c *CommandsHandler) KeywordsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	c.keywordsPage(ctx, b, update.Message.Chat.ID, 1, defaultMessageId, "")
+}
+
+// keywordsCallback handles the buttons of /keywords pressed by userId, args is
+// the callback data after the page.
+func (c *CommandsHandler) keywordsCallback(ctx context.Context, b *bot.Bot, msg *models.Message, userId int64,
+	page int, args []string,
+) {
+	chatId, messageId := msg.Chat.ID, msg.ID
+	if len(args) == 0 {
+		c.keywordsPage(ctx, b, chatId, page, messageId, "")
+		return
+	}
+	ids := make([]int32, 0, len(args)-1) // the keyword number, then the group id
+	for _, arg := range args[1:] {
+		i, err := strconv.ParseInt(arg, 10, 32)
+		if err != nil {
+			c.ctx.Logger.Error().Stack().Err(err).Msg("")
+			return
+		}
+		ids = append(ids, int32(i))
+	}
+	if len(ids) == 0 {
+		c.ctx.Logger.Error().Msgf("no keyword number in %s callback %v", constant.Keywords, args)
+		return
+	}
+	kw, err := dal.Keyword.GetByNumber(chatId, ids[0])
+	if err != nil {
+		c.keywordsPage(ctx, b, chatId, page, messageId, fmt.Sprintf("Keyword %d not found.", ids[0]))
+		return
+	}
+
+	switch args[0] {
+	case kwShow:
+		c.keywordDetail(ctx, b, chatId, messageId, page, kw, "")
+	case kwDelete:
+		text := fmt.Sprintf("Delete keyword [%d] %s?", kw.Number, html.EscapeString(ruleString(kw)))
+		c.sendOrEditMessage(ctx, b, chatId, messageId, text, &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{{
+				{Text: "🗑 Delete", CallbackData: keywordCallback(page, kwConfirmDelete, kw.Number)},
+				{Text: "Cancel", CallbackData: keywordCallback(page, kwShow, kw.Number)},
+			}},
+		})
+	case kwConfirmDelete:
+		notice := fmt.Sprintf("Keyword [%d] deleted.", kw.Number)
+		if _, err := dal.Keyword.DeleteByNumbers(chatId, strconv.Itoa(int(kw.Number))); err != nil {
+			c.ctx.Logger.Error().Stack().Err(err).Msg("")
+			notice = fmt.Sprintf("Delete keyword [%d] failed, %s", kw.Number, err.Error())
+		}
+		c.keywordsPage(ctx, b, chatId, page, messageId, notice)
+	case kwEdit:
+		c.startDialog(ctx, b, chatId, userId, editKeywordDialog, map[string]string{
+			"number":  strconv.Itoa(int(kw.Number)),
+			"keyword": ruleString(kw),
+		})
+	case kwMute:
+		notice := "Muted."
+		if kw.Muted != 0 {
+			notice = "Unmuted."
+		}
+		if err := dal.Keyword.SetMuted(chatId, kw.Number, kw.Muted == 0); err != nil {
+			c.ctx.Logger.Error().Stack().Err(err).Msg("")
+			notice = "Failed, " + err.Error()
+		} else {
+			kw.Muted = 1 - kw.Muted
+		}
+		c.keywordDetail(ctx, b, chatId, messageId, page, kw, notice)
+	case kwGroups:
+		text, markup := keywordGroupsMenu(page, kw, dal.KeywordGroup.GetByUserId(chatId))
+		c.sendOrEditMessage(ctx, b, chatId, messageId, text, markup)
+	case kwMove:
+		var groupId *int32
+		if len(ids) > 1 && ids[1] != 0 {
+			groupId = &ids[1]
+		}
+		notice := "Moved."
+		if groupId != nil {
+			if _, err = dal.KeywordGroup.GetById(chatId, *groupId); err != nil {
+				notice = fmt.Sprintf("Group %d not found.", *groupId)
+			}
+		}
+		if err == nil {
+			if _, err = dal.Keyword.MoveToGroup(chatId, groupId, []int32{kw.Number}); err != nil {
+				c.ctx.Logger.Error().Stack().Err(err).Msg("")
+				notice = "Failed, " + err.Error()
+			} else {
+				kw.GroupID = groupId
+			}
+		}
+		c.keywordDetail(ctx, b, chatId, messageId, page, kw, notice)
+	default:
+		c.ctx.Logger.Error().Msgf("unknown %s callback %v", constant.Keywords, args)
+	}
+}
+
+// keywordsPage shows a page of the keywords of chatId, notice is written
+// above the list, e.g. the result of the last button.
+func (c *CommandsHandler) keywordsPage(ctx context.Context, b *bot.Bot, chatId int64, page, messageId int, notice string) {
+	text, markup := keywordsMenu(dal.Keyword.GetByUserIdAndType(chatId, model.PROJECT), page, notice)
+	c.sendOrEditMessage(ctx, b, chatId, messageId, text, markup)
+}
+
+// keywordsMenu renders a page of keywords with a button per keyword.
+func keywordsMenu(keywords []*model.Keyword, page int, notice string) (string, *models.InlineKeyboardMarkup) {
+	var text strings.Builder
+	if notice != "" {
+		text.WriteString(notice + "\n\n")
+	}
+	if len(keywords) == 0 {
+		fmt.Fprintf(&text, "No keywords, add one with %s <keyword>.", constant.AddKeyword)
+		return text.String(), nil
+	}
+	totalPages := (len(keywords) + keywordPageSize - 1) / keywordPageSize
+	page = max(1, min(page, totalPages))
+	start := (page - 1) * keywordPageSize
+
+	fmt.Fprintf(&text, "<b>Keywords</b> (%d, page %d/%d), tap one to manage it, ⚠️ marks the invalid ones:\n",
+		len(keywords), page, totalPages)
+	var keyboard [][]models.InlineKeyboardButton
+	for _, kw := range keywords[start:min(start+keywordPageSize, len(keywords))] {
+		status := ""
+		if kw.Muted != 0 {
+			status = " 🔕"
+		}
+		s, err := parseRule(kw)
+		if err != nil {
+			status += " ⚠️"
+		}
+		fmt.Fprintf(&text, "- [%d] %s%s\n", kw.Number, html.EscapeString(s), status)
+		if r := []rune(s); len(r) > keywordButtonLength {
+			s = string(r[:keywordButtonLength-1]) + "…"
+		}
+		keyboard = append(keyboard, []models.InlineKeyboardButton{{
+			Text:         fmt.Sprintf("[%d] %s%s", kw.Number, s, status),
+			CallbackData: keywordCallback(page, kwShow, kw.Number),
+		}})
+	}
+
+	var row []models.InlineKeyboardButton
+	if page > 1 {
+		row = append(row, models.InlineKeyboardButton{
+			Text:         fmt.Sprintf("« Previous (%d)", page-1),
+			CallbackData: keywordCallback(page-1, ""),
+		})
+	}
+	if page < totalPages {
+		row = append(row, models.InlineKeyboardButton{
+			Text:         fmt.Sprintf("Next (%d) »", page+1),
+			CallbackData: keywordCallback(page+1, ""),
+		})
+	}
+	if len(row) > 0 {
+		keyboard = append(keyboard, row)
+	}
+	return text.String(), &models.InlineKeyboardMarkup{InlineKeyboard: keyboard}
+}
+
+func (c *CommandsHandler) keywordDetail(ctx context.Context, b *bot.Bot, chatId int64, messageId, page int,
+	kw *model.Keyword, notice string,
+) {
+	group := ""
+	if kw.GroupID != nil {
+		if g, err := dal.KeywordGroup.GetById(chatId, *kw.GroupID); err == nil {
+			group = g.Name
+		}
+	}
+	text, markup := keywordMenu(kw, group, page, notice)
+	c.sendOrEditMessage(ctx, b, chatId, messageId, text, markup)
+}
+
+// keywordMenu renders a keyword with the buttons to edit, mute, move and
+// delete it, group is the name of its group.
+func keywordMenu(kw *model.Keyword, group string, page int, notice string) (string, *models.InlineKeyboardMarkup) {
+	var text strings.Builder
+	if notice != "" {
+		text.WriteString(notice + "\n\n")
+	}
+	s, invalid := parseRule(kw)
+	fmt.Fprintf(&text, "<b>[%d]</b> %s\n", kw.Number, html.EscapeString(s))
+	if group == "" {
+		group = "none"
+	}
+	fmt.Fprintf(&text, "Group: %s\n", html.EscapeString(group))
+	mute, status := "🔕 Mute", "active"
+	if kw.Muted != 0 {
+		mute, status = "🔔 Unmute", "muted"
+	}
+	fmt.Fprintf(&text, "Status: %s\nMatched: %d", status, kw.Counter)
+	if invalid != nil {
+		fmt.Fprintf(&text, "\n⚠️ Invalid, matches nothing until edited: %s", html.EscapeString(invalid.Error()))
+	}
+
+	return text.String(), &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{Text: "✏️ Edit", CallbackData: keywordCallback(page, kwEdit, kw.Number)},
+				{Text: mute, CallbackData: keywordCallback(page, kwMute, kw.Number)},
+			},
+			{
+				{Text: "📁 Move", CallbackData: keywordCallback(page, kwGroups, kw.Number)},
+				{Text: "🗑 Delete", CallbackData: keywordCallback(page, kwDelete, kw.Number)},
+			},
+			{{Text: "« Back", CallbackData: keywordCallback(page, "")}},
+		},
+	}
+}
+
+// keywordGroupsMenu renders the groups kw can be moved to.
+func keywordGroupsMenu(page int, kw *model.Keyword, groups []*model.KeywordGroup) (string, *models.InlineKeyboardMarkup) {
+	text := fmt.Sprintf("Move keyword [%d] %s to:", kw.Number, html.EscapeString(ruleString(kw)))
+	if len(groups) == 0 {
+		text += fmt.Sprintf("\n\nNo keyword groups, create one with %s <name>.", constant.AddGroup)
+	}
+	var keyboard [][]models.InlineKeyboardButton
+	for _, g := range groups {
+		name := g.Name
+		if kw.GroupID != nil && *kw.GroupID == *g.ID {
+			name = "✓ " + name
+		}
+		keyboard = append(keyboard, []models.InlineKeyboardButton{{
+			Text:         name,
+			CallbackData: keywordCallback(page, kwMove, kw.Number, *g.ID),
+		}})
+	}
+	if kw.GroupID != nil {
+		keyboard = append(keyboard, []models.InlineKeyboardButton{{
+			Text:         "No group",
+			CallbackData: keywordCallback(page, kwMove, kw.Number, 0),
+		}})
+	}
+	keyboard = append(keyboard, []models.InlineKeyboardButton{{
+		Text:         "« Back",
+		CallbackData: keywordCallback(page, kwShow, kw.Number),
+	}})
+	return text, &models.InlineKeyboardMarkup{InlineKeyboard: keyboard}
+}
+
+// editKeywordDialog asks for the new keyword of the edit button and shows the
+// keyword once it is replaced.
+func (c *CommandsHandler) editKeywordDialog() *dialog {
+	return &dialog{
+		name: editKeywordDialog,
+		steps: []dialogStep{{
+			prompt: func(data map[string]string) string {
+				return fmt.Sprintf("✏️ Send the new keyword for [%s] %s, or %s to stop.",
+					data["number"], data["keyword"], constant.Cancel)
+			},
+			handle: func(chatId int64, text string, data map[string]string) error {
+				number, err := strconv.ParseInt(data["number"], 10, 32)
+				if err != nil {
+					return err
+				}
+				kw, err := dal.Keyword.GetByNumber(chatId, int32(number))
+				if err != nil {
+					return err
+				}
+				if strings.TrimSpace(text) == "" {
+					return fmt.Errorf("empty keyword")
+				}
+				if text, err = validateKeyword(model.KeywordType(kw.Type), text); err != nil {
+					return err
+				}
+				return dal.Keyword.SetKeyword(chatId, int32(number), text)
+			},
+		}},
+		done: func(ctx context.Context, b *bot.Bot, update *models.Update, data map[string]string) {
+			chatId := update.Message.Chat.ID
+			number, _ := strconv.ParseInt(data["number"], 10, 32)
+			kw, err := dal.Keyword.GetByNumber(chatId, int32(number))
+			if err != nil {
+				c.sendErrorMessage(ctx, b, update, fmt.Sprintf("Edit keyword failed, %s", err.Error()))
+				return
+			}
+			c.keywordDetail(ctx, b, chatId, defaultMessageId, 1, kw, "Updated.")
+		},
+	}
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
+	"gorm.io/gorm"
+)
+
+func TestKeywordsMenu(t *testing.T) {
+	var keywords []*model.Keyword
+	for i := int32(1); i <= 12; i++ {
+		keywords = append(keywords, &model.Keyword{Number: i, Keyword: fmt.Sprintf("服务器%d", i)})
+	}
+	keywords[0].Keyword = strings.Repeat("很长的关键词", 10)
+	keywords[1].Muted = 1
+	keywords[2].Keyword = `服务器"3`
+
+	text, markup := keywordsMenu(keywords, 1, "Keyword [13] deleted.")
+	for _, want := range []string{"Keyword [13] deleted.\n\n", "(12, page 1/2)", "- [2] +服务器2 🔕\n",
+		"- [3] 服务器&#34;3 ⚠️\n"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in\n%s", want, text)
+		}
+	}
+	rows := markup.InlineKeyboard
+	if len(rows) != keywordPageSize+1 {
+		t.Fatalf("expected %d keywords and the paging row, got %d rows", keywordPageSize, len(rows))
+	}
+	if b := rows[0][0]; len([]rune(b.Text)) > keywordButtonLength+5 || b.CallbackData != "kw:1:s:1" {
+		t.Errorf("unexpected button %+v", b)
+	}
+	if next := rows[keywordPageSize]; len(next) != 1 || next[0].CallbackData != "kw:2" {
+		t.Errorf("unexpected paging row %+v", next)
+	}
+
+	// A page past the end, e.g. after deleting its last keyword, shows the
+	// last one.
+	text, markup = keywordsMenu(keywords, 3, "")
+	if !strings.Contains(text, "page 2/2") || len(markup.InlineKeyboard) != 3 ||
+		markup.InlineKeyboard[0][0].CallbackData != "kw:2:s:11" {
+		t.Errorf("unexpected last page %s %+v", text, markup.InlineKeyboard)
+	}
+
+	if text, markup = keywordsMenu(nil, 1, ""); markup != nil || !strings.Contains(text, "No keywords") {
+		t.Errorf("unexpected empty list %q", text)
+	}
+}
+
+func TestKeywordMenu(t *testing.T) {
+	groupId := int32(2)
+	kw := &model.Keyword{Number: 3, Keyword: "服务器 -维保", Muted: 1, GroupID: &groupId}
+	text, markup := keywordMenu(kw, "network gear", 2, "")
+	if !strings.Contains(text, "<b>[3]</b> +服务器 -维保\nGroup: network gear\nStatus: muted") {
+		t.Errorf("unexpected text\n%s", text)
+	}
+	var data []string
+	for _, row := range markup.InlineKeyboard {
+		for _, b := range row {
+			data = append(data, b.CallbackData)
+		}
+	}
+	if got := strings.Join(data, " "); got != "kw:2:e:3 kw:2:m:3 kw:2:g:3 kw:2:d:3 kw:2" {
+		t.Errorf("unexpected buttons %s", got)
+	}
+	if markup.InlineKeyboard[0][1].Text != "🔔 Unmute" {
+		t.Errorf("expected an unmute button, got %q", markup.InlineKeyboard[0][1].Text)
+	}
+	if text, _ := keywordMenu(&model.Keyword{Number: 4, Keyword: `a"b`}, "", 1, ""); !strings.Contains(text,
+		"⚠️ Invalid, matches nothing until edited: unterminated quote") {
+		t.Errorf("expected the parse error in\n%s", text)
+	}
+
+	one := int32(1)
+	text, markup = keywordGroupsMenu(2, kw, []*model.KeywordGroup{{ID: &one, Name: "a"}, {ID: &groupId, Name: "b"}})
+	data = data[:0]
+	for _, row := range markup.InlineKeyboard {
+		data = append(data, row[0].Text+"="+row[0].CallbackData)
+	}
+	if got := strings.Join(data, " "); got != "a=kw:2:gm:3:1 ✓ b=kw:2:gm:3:2 No group=kw:2:gm:3:0 « Back=kw:2:s:3" {
+		t.Errorf("unexpected group buttons %s in %s", got, text)
+	}
+}
+
+func TestInfoProcessor_GetMuted(t *testing.T) {
+	f := "./keyword_menu_test.db"
+	defer func() { _ = os.Remove(f) }()
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.Keyword{}, &model.KeywordGroup{}, &model.ChatSetting{})
+	dal.SetDefault(db)
+
+	userId := int64(5151)
+	dal.Keyword.Insert([]string{"服务器", "交换机"}, userId, model.PROJECT)
+	kws := dal.Keyword.GetByUserIdAndType(userId, model.PROJECT)
+	if err := dal.Keyword.SetMuted(userId, kws[0].Number, true); err != nil {
+		t.Fatal(err)
+	}
+	conf := (&InfoProcessor{}).get(userId, time.Now())
+	if len(conf) != 1 || len(conf[0].ProjectRules) != 1 || conf[0].ProjectRules[0].Rule.Keyword != "交换机" {
+		t.Errorf("expected the muted keyword to be left out, got %+v", conf)
+	}
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
+	"github.com/gythialy/magnet/pkg/rule"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// maxOverlaps is how many of the most overlapping rule pairs /keyword_stats
+// lists.
+const maxOverlaps = 10
+
+// statWindows are the windows /keyword_stats counts hits in, the last one is
+// also the window of dormant rules and overlaps.
+var statWindows = []struct {
+	name string
+	days int
+}{
+	{"24h", 1},
+	{"7d", 7},
+	{"30d", 30},
+}
+
+// keywordStats holds the hits of the keywords of a user.
+type keywordStats struct {
+	keywords []*model.Keyword
+	// counts holds the hits per keyword id in each of statWindows, then
+	// the hits of all time.
+	counts []map[int32]int64
+	// recent holds the hits in the last of statWindows.
+	recent []*model.KeywordHit
+}
+
+func loadKeywordStats(userId int64, now time.Time) (*keywordStats, error) {
+	s := &keywordStats{keywords: dal.Keyword.GetByUserIdAndType(userId, model.PROJECT)}
+	for _, w := range statWindows {
+		counts, err := dal.KeywordHit.CountByKeyword(userId, now.AddDate(0, 0, -w.days))
+		if err != nil {
+			return nil, err
+		}
+		s.counts = append(s.counts, counts)
+	}
+	total, err := dal.KeywordHit.CountByKeyword(userId, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	s.counts = append(s.counts, total)
+	last := statWindows[len(statWindows)-1]
+	if s.recent, err = dal.KeywordHit.Since(userId, now.AddDate(0, 0, -last.days)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ruleOverlap is the number of notices matched by both rules a and b.
+type ruleOverlap struct {
+	a, b  int32
+	count int64
+}
+
+// overlaps returns the rule pairs sharing notices in hits, most shared first.
+func overlaps(hits []*model.KeywordHit) []ruleOverlap {
+	byURL := make(map[string][]int32)
+	for _, h := range hits {
+		byURL[h.URL] = append(byURL[h.URL], h.KeywordID)
+	}
+	pairs := make(map[[2]int32]int64)
+	for _, ids := range byURL {
+		slices.Sort(ids)
+		for i, a := range ids {
+			for _, b := range ids[i+1:] {
+				if a != b {
+					pairs[[2]int32{a, b}]++
+				}
+			}
+		}
+	}
+	result := make([]ruleOverlap, 0, len(pairs))
+	for p, n := range pairs {
+		result = append(result, ruleOverlap{a: p[0], b: p[1], count: n})
+	}
+	slices.SortFunc(result, func(x, y ruleOverlap) int {
+		if x.count != y.count {
+			return int(y.count - x.count)
+		}
+		if x.a != y.a {
+			return int(x.a - y.a)
+		}
+		return int(x.b - y.b)
+	})
+	return result
+}
+
+// String renders the hits per rule, busiest first, the rules without hits
+// in the last window, the rules that do not parse and the rules matching the
+// same notices.
+func (s *keywordStats) String() string {
+	if len(s.keywords) == 0 {
+		return "No keywords."
+	}
+	last := len(statWindows) - 1
+	keywords := slices.Clone(s.keywords)
+	slices.SortStableFunc(keywords, func(x, y *model.Keyword) int {
+		return int(s.counts[last][*y.ID] - s.counts[last][*x.ID])
+	})
+
+	var b strings.Builder
+	names := make([]string, 0, len(statWindows)+1)
+	for _, w := range statWindows {
+		names = append(names, w.name)
+	}
+	fmt.Fprintf(&b, "<b>Keyword hits</b> (%s / all)\n", strings.Join(names, " / "))
+	// The canonical form of each keyword and why it does not parse.
+	rules, errs := make(map[int32]string, len(keywords)), make(map[int32]error)
+	var dormant, invalid []*model.Keyword
+	for _, kw := range keywords {
+		text, err := parseRule(kw)
+		rules[*kw.ID] = text
+		if err != nil {
+			invalid = append(invalid, kw)
+			errs[*kw.ID] = err
+		}
+		counts := make([]string, 0, len(s.counts))
+		for _, c := range s.counts {
+			counts = append(counts, fmt.Sprint(c[*kw.ID]))
+		}
+		fmt.Fprintf(&b, "- [%d] %s: %s\n", kw.Number, html.EscapeString(text), strings.Join(counts, " / "))
+		if s.counts[last][*kw.ID] == 0 {
+			dormant = append(dormant, kw)
+		}
+	}
+
+	if len(dormant) > 0 {
+		fmt.Fprintf(&b, "\n<b>Dormant</b> (no hits in %s)\n", statWindows[last].name)
+		for _, kw := range dormant {
+			fmt.Fprintf(&b, "- [%d] %s\n", kw.Number, html.EscapeString(rules[*kw.ID]))
+		}
+	}
+
+	if len(invalid) > 0 {
+		b.WriteString("\n<b>Invalid</b> (match nothing, edit them in /keywords)\n")
+		for _, kw := range invalid {
+			fmt.Fprintf(&b, "- [%d] %s: %s\n", kw.Number, html.EscapeString(kw.Keyword), html.EscapeString(errs[*kw.ID].Error()))
+		}
+	}
+
+	if pairs := overlaps(s.recent); len(pairs) > 0 {
+		numbers := make(map[int32]int32, len(s.keywords))
+		for _, kw := range s.keywords {
+			numbers[*kw.ID] = kw.Number
+		}
+		fmt.Fprintf(&b, "\n<b>Overlap</b> (notices matched by both in %s)\n", statWindows[last].name)
+		for _, p := range pairs[:min(len(pairs), maxOverlaps)] {
+			// The share of the rule with fewer hits, 100% means its
+			// notices are all matched by the other rule too.
+			fewer := min(s.counts[last][p.a], s.counts[last][p.b])
+			fmt.Fprintf(&b, "- [%d] &amp; [%d]: %d (%d%%)\n", numbers[p.a], numbers[p.b], p.count, p.count*100/max(fewer, 1))
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// parseRule returns the canonical form of kw, or kw itself and why it does
+// not parse. Keywords saved before the expression grammar may no longer
+// parse, they match nothing until they are edited.
+func parseRule(kw *model.Keyword) (string, error) {
+	cr, err := rule.ParseComplexRule(kw)
+	if err != nil {
+		return kw.Keyword, err
+	}
+	return cr.ToString(), nil
+}
+
+// ruleString is the canonical form of kw, kw itself when it does not parse.
+func ruleString(kw *model.Keyword) string {
+	s, _ := parseRule(kw)
+	return s
+}
+
+// splitLines packs the lines of text into messages of at most limit bytes,
+// a longer line makes a message of its own.
+func splitLines(text string, limit int) []string {
+	var parts []string
+	var b strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		if b.Len() > 0 && b.Len()+1+len(line) > limit {
+			parts = append(parts, strings.TrimSpace(b.String()))
+			b.Reset()
+		}
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(line)
+	}
+	if s := strings.TrimSpace(b.String()); s != "" {
+		parts = append(parts, s)
+	}
+	return parts
+}
+
+// KeywordStatsHandler shows the hits per keyword over the last day, week and
+// month, the keywords that never fire, the invalid ones and the keywords
+// matching the same notices.
+func (c *CommandsHandler) KeywordStatsHandler(ctx context.Context, b *bot.Bot, update *models.Update) {
+	userId := update.Message.Chat.ID
+	stats, err := loadKeywordStats(userId, time.Now())
+	if err != nil {
+		c.ctx.Logger.Error().Stack().Err(err).Msg("load keyword stats failed")
+		c.sendErrorMessage(ctx, b, update, "Failed to load the keyword stats.")
+		return
+	}
+	// A message holds 4096 characters at most, a long report goes out in
+	// several.
+	for _, part := range splitLines(stats.String(), maxMessageLength) {
+		c.sendOrEditMessage(ctx, b, userId, defaultMessageId, part, nil)
+	}
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
+	"github.com/gythialy/magnet/pkg/rule"
+	"gorm.io/gorm"
+)
+
+func TestKeywordStats(t *testing.T) {
+	f := "./keyword_stats_test.db"
+	defer func() { _ = os.Remove(f) }()
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.Keyword{}, &model.KeywordHit{})
+	dal.SetDefault(db)
+
+	userId := int64(6666)
+	dal.Keyword.Insert([]string{"服务器", "服务器 采购", "打印机", `a"b`}, userId, model.PROJECT)
+	ids := make(map[string]int32)
+	for _, kw := range dal.Keyword.GetByUserIdAndType(userId, model.PROJECT) {
+		ids[kw.Keyword] = *kw.ID
+	}
+	// Filter records a hit per matched notice, once however often it runs.
+	var rules []*rule.ComplexRule
+	for _, kw := range dal.Keyword.GetByUserIdAndType(userId, model.PROJECT) {
+		if cr := rule.NewComplexRule(kw); cr != nil {
+			rules = append(rules, cr)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		NewProjects(testBotContext(""), []*Project{{ShortTitle: "服务器维保", Pageurl: "e"}}, rules).Filter()
+	}
+	if n, _ := dal.KeywordHit.Where(dal.KeywordHit.URL.Eq("e")).Count(); n != 1 {
+		t.Errorf("expected 1 hit recorded by Filter, got %d", n)
+	}
+	_, _ = dal.KeywordHit.Where(dal.KeywordHit.URL.Eq("e")).Delete()
+
+	now := time.Now()
+	hit := func(kw, url string, days int) *model.KeywordHit {
+		return &model.KeywordHit{KeywordID: ids[kw], UserID: userId, URL: url, HitAt: now.AddDate(0, 0, -days)}
+	}
+	if err := dal.KeywordHit.Record([]*model.KeywordHit{
+		hit("服务器", "a", 0), hit("服务器", "b", 3), hit("服务器", "c", 20),
+		hit("服务器 采购", "a", 0), hit("服务器 采购", "b", 3),
+		hit("打印机", "d", 40),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := loadKeywordStats(userId, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := stats.String()
+	for _, want := range []string{
+		"(24h / 7d / 30d / all)",
+		"] +服务器: 1 / 2 / 3 / 3\n",
+		"] +服务器 +采购: 1 / 2 / 2 / 2\n",
+		"] +打印机: 0 / 0 / 0 / 1\n",
+		"<b>Dormant</b> (no hits in 30d)\n- [",
+		"<b>Invalid</b> (match nothing, edit them in /keywords)\n- [4] a&#34;b: unterminated quote",
+		": 2 (100%)",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in\n%s", want, text)
+		}
+	}
+	// Busiest rules first.
+	if strings.Index(text, "+服务器:") > strings.Index(text, "+打印机:") {
+		t.Errorf("expected the rules ordered by hits in\n%s", text)
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	hits := []*model.KeywordHit{
+		{KeywordID: 1, URL: "a"}, {KeywordID: 2, URL: "a"}, {KeywordID: 3, URL: "a"},
+		{KeywordID: 2, URL: "b"}, {KeywordID: 1, URL: "b"},
+		{KeywordID: 3, URL: "c"},
+	}
+	got := overlaps(hits)
+	want := []ruleOverlap{{1, 2, 2}, {1, 3, 1}, {2, 3, 1}}
+	if len(got) != len(want) {
+		t.Fatalf("overlaps() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("overlaps()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	if got := splitLines("a\nbb\n\nccc\n", 5); strings.Join(got, "|") != "a\nbb|ccc" {
+		t.Errorf("splitLines() = %q", got)
+	}
+	if got := splitLines("a\n"+strings.Repeat("b", 8)+"\nc", 5); strings.Join(got, "|") != "a|bbbbbbbb|c" {
+		t.Errorf("expected a long line on its own, got %q", got)
+	}
+
+	var lines []string
+	for i := 0; i < 500; i++ {
+		lines = append(lines, fmt.Sprintf("- [%d] +服务器 +采购 +%d: 1 / 2 / 3 / 4", i, i))
+	}
+	text := strings.Join(lines, "\n")
+	parts := splitLines(text, maxMessageLength)
+	if len(parts) < 2 || strings.Join(parts, "\n") != text {
+		t.Fatalf("expected the report split at lines, got %d parts", len(parts))
+	}
+	for _, p := range parts {
+		if len(p) > maxMessageLength {
+			t.Errorf("expected at most %d bytes, got %d", maxMessageLength, len(p))
+		}
+	}
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
@@ -32,13 +33,17 @@ func (h *ManagerHandler) Retry(ctx context.Context, b *bot.Bot, update *models.U
 		}
 
 		go func() {
-			h.ctx.processor.Get(userId)
+			report := h.ctx.processor.Get(userId)
 
 			// Edit the message when processing is done
+			text := "Processing completed."
+			if report.Failed() {
+				text = fmt.Sprintf("Processing completed, %s", report)
+			}
 			if _, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
 				ChatID:    userId,
 				MessageID: sentMsg.ID,
-				Text:      "Processing completed.",
+				Text:      text,
 			}); err != nil {
 				h.ctx.Logger.Error().Stack().Err(err).Msg("")
 			}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gythialy/magnet/pkg/rule"
+)
+
+// MatcherCache keeps the compiled rule.Matcher of every rule set, so the
+// automaton of a user's keywords is only rebuilt after they changed instead
+// of on every run. A rule set is identified by the chat and notifier it is
+// pushed to and versioned by its keywords, see rulesVersion. A compiled
+// matcher is only read, so concurrent invocations share it.
+type MatcherCache struct {
+	mu      sync.Mutex
+	entries map[string]matcherEntry
+}
+
+type matcherEntry struct {
+	version string
+	matcher *rule.Matcher
+}
+
+// NewMatcherCache returns an empty MatcherCache.
+func NewMatcherCache() *MatcherCache {
+	return &MatcherCache{entries: make(map[string]matcherEntry)}
+}
+
+// Get returns the matcher of rules cached under key, compiling it when the
+// rules differ from the cached ones. A nil cache compiles rules every time.
+func (c *MatcherCache) Get(key string, rules []*rule.ComplexRule) *rule.Matcher {
+	if c == nil {
+		return rule.NewMatcher(rules)
+	}
+	version := rulesVersion(rules)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok && e.version == version {
+		return e.matcher
+	}
+	m := rule.NewMatcher(rules)
+	c.entries[key] = matcherEntry{version: version, matcher: m}
+	return m
+}
+
+// rulesVersion identifies rules by the id and text of their keywords in
+// order, which is all a compiled matcher depends on.
+func rulesVersion(rules []*rule.ComplexRule) string {
+	var b strings.Builder
+	for _, cr := range rules {
+		if cr.Rule.ID != nil {
+			fmt.Fprintf(&b, "%d", *cr.Rule.ID)
+		}
+		fmt.Fprintf(&b, ":%q\n", cr.Rule.Keyword)
+	}
+	return b.String()
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/gythialy/magnet/pkg/model"
+	"github.com/gythialy/magnet/pkg/rule"
+)
+
+func TestMatcherCache(t *testing.T) {
+	id := int32(1)
+	rules := func(keyword string) []*rule.ComplexRule {
+		return []*rule.ComplexRule{rule.NewComplexRule(&model.Keyword{ID: &id, Keyword: keyword})}
+	}
+	c := NewMatcherCache()
+	m := c.Get("1:1:", rules("服务器"))
+	if c.Get("1:1:", rules("服务器")) != m {
+		t.Error("expected the matcher reused for the same rules")
+	}
+	if c.Get("1:2:", rules("服务器")) == m {
+		t.Error("expected a matcher per key")
+	}
+	edited := c.Get("1:1:", rules("交换机"))
+	if edited == m {
+		t.Fatal("expected the matcher rebuilt after an edit")
+	}
+	doc := rule.NewDocument("交换机采购", "", "")
+	if got := edited.Match(doc); len(got) != 1 {
+		t.Errorf("expected the edited rule to match, got %v", got)
+	}
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Notice query parameters of the freecms selectInfoMoreChannel.do endpoint a
+// user can subscribe with.
+const (
+	filterRegionCode     = "regionCode"
+	filterPurchaseManner = "purchaseManner"
+	filterNoticeType     = "noticeType"
+	filterPurchaseNature = "purchaseNature"
+	filterCityOrArea     = "cityOrArea"
+)
+
+// maxFilterQueries caps the number of queries a single filter expands into.
+// A filter beyond it is only applied on the client side.
+const maxFilterQueries = 16
+
+// filterFields maps every supported filter key to the Project field it is
+// matched against on the client side.
+var filterFields = map[string]func(p *Project) string{
+	filterRegionCode:     func(p *Project) string { return p.RegionCode },
+	filterPurchaseManner: func(p *Project) string { return p.PurchaseManner },
+	filterNoticeType:     func(p *Project) string { return p.NoticeType },
+	filterPurchaseNature: func(p *Project) string { return p.PurchaseNature },
+	filterCityOrArea:     func(p *Project) string { return p.CityOrArea },
+}
+
+// FilterKeys returns the supported filter keys in a stable order.
+func FilterKeys() []string {
+	return slices.Sorted(maps.Keys(filterFields))
+}
+
+// ParseFilterTerm parses a "key=value" filter term. The key is matched case
+// insensitively against FilterKeys and returned in its canonical form.
+func ParseFilterTerm(term string) (string, string, error) {
+	key, value, ok := strings.Cut(term, "=")
+	key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+	if !ok || key == "" || value == "" {
+		return "", "", fmt.Errorf("invalid filter %q, expected key=value", term)
+	}
+	for k := range filterFields {
+		if strings.EqualFold(k, key) {
+			return k, value, nil
+		}
+	}
+	return "", "", fmt.Errorf("unknown filter key %q, expected one of %s", key, strings.Join(FilterKeys(), ", "))
+}
+
+// NoticeFilter holds the accepted values per filter key of one user. A
+// notice passes when, for every key, its value is one of the accepted ones;
+// an empty filter passes everything.
+type NoticeFilter map[string][]string
+
+// NewNoticeFilter builds a filter from stored "key=value" terms, invalid
+// terms are skipped.
+func NewNoticeFilter(terms []string) NoticeFilter {
+	f := make(NoticeFilter)
+	for _, term := range terms {
+		key, value, err := ParseFilterTerm(term)
+		if err != nil {
+			continue
+		}
+		if !slices.Contains(f[key], value) {
+			f[key] = append(f[key], value)
+		}
+	}
+	return f
+}
+
+// Match reports whether p passes the filter. A field the source did not
+// report is treated as unknown and passes, so a notice is never dropped for
+// missing data. Region codes match by prefix, "110000" accepts every notice
+// of "110105".
+func (f NoticeFilter) Match(p *Project) bool {
+	for key, values := range f {
+		actual := filterFields[key](p)
+		if actual == "" {
+			continue
+		}
+		matched := false
+		for _, v := range values {
+			if key == filterRegionCode {
+				matched = strings.HasPrefix(actual, regionPrefix(v))
+			} else {
+				matched = actual == v
+			}
+			if matched {
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// Queries expands the filter into the server side queries covering it, one
+// per combination of accepted values. A filter expanding beyond
+// maxFilterQueries only keeps its single valued keys server side.
+func (f NoticeFilter) Queries() []NoticeQuery {
+	queries := []NoticeQuery{{}}
+	for _, key := range FilterKeys() {
+		values := f[key]
+		if len(values) == 0 {
+			continue
+		}
+		next := make([]NoticeQuery, 0, len(queries)*len(values))
+		for _, q := range queries {
+			for _, v := range values {
+				c := maps.Clone(q)
+				c[key] = v
+				next = append(next, c)
+			}
+		}
+		queries = next
+	}
+	if len(queries) <= maxFilterQueries {
+		return queries
+	}
+	q := make(NoticeQuery)
+	for key, values := range f {
+		if len(values) == 1 {
+			q[key] = values[0]
+		}
+	}
+	return []NoticeQuery{q}
+}
+
+// regionPrefix drops the trailing "00" pairs of an administrative region
+// code, which stand for "the whole province/city".
+func regionPrefix(code string) string {
+	for len(code) > 2 && strings.HasSuffix(code, "00") {
+		code = code[:len(code)-2]
+	}
+	return code
+}
+
+// NoticeQuery is a set of filter parameters sent along with a notice query.
+// The empty query asks for every notice.
+type NoticeQuery map[string]string
+
+// Key returns a canonical representation of the query, empty for the empty
+// query.
+func (q NoticeQuery) Key() string {
+	parts := make([]string, 0, len(q))
+	for _, k := range slices.Sorted(maps.Keys(q)) {
+		parts = append(parts, k+"="+q[k])
+	}
+	return strings.Join(parts, "&")
+}
+
+// covers reports whether every notice returned for o is also returned for q,
+// i.e. q's parameters are a subset of o's.
+func (q NoticeQuery) covers(o NoticeQuery) bool {
+	for k, v := range q {
+		if o[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// PlanQueries merges the queries of all users into the smallest set the
+// crawler has to issue: duplicates are dropped, as is every query covered by
+// a broader one. No query at all plans the single empty query.
+func PlanQueries(queries []NoticeQuery) []NoticeQuery {
+	if len(queries) == 0 {
+		return []NoticeQuery{{}}
+	}
+	// Broader queries first, so a covering query is always kept before the
+	// queries it covers.
+	sorted := slices.Clone(queries)
+	slices.SortStableFunc(sorted, func(a, b NoticeQuery) int {
+		if len(a) != len(b) {
+			return len(a) - len(b)
+		}
+		return strings.Compare(a.Key(), b.Key())
+	})
+	result := make([]NoticeQuery, 0, len(sorted))
+	for _, q := range sorted {
+		if !slices.ContainsFunc(result, func(p NoticeQuery) bool { return p.covers(q) }) {
+			result = append(result, q)
+		}
+	}
+	return result
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseFilterTerm(t *testing.T) {
+	tests := []struct {
+		term      string
+		wantKey   string
+		wantValue string
+		wantErr   bool
+	}{
+		{"regionCode=110000", filterRegionCode, "110000", false},
+		{" regioncode = 110000 ", filterRegionCode, "110000", false},
+		{"purchaseManner=公开招标", filterPurchaseManner, "公开招标", false},
+		{"regionCode=", "", "", true},
+		{"110000", "", "", true},
+		{"title=abc", "", "", true},
+	}
+	for _, tt := range tests {
+		key, value, err := ParseFilterTerm(tt.term)
+		if (err != nil) != tt.wantErr || key != tt.wantKey || value != tt.wantValue {
+			t.Errorf("ParseFilterTerm(%q) = %q, %q, %v", tt.term, key, value, err)
+		}
+	}
+}
+
+func TestNoticeFilter_Match(t *testing.T) {
+	f := NewNoticeFilter([]string{"regionCode=110000", "regionCode=310100", "purchaseManner=公开招标", "bogus"})
+	tests := []struct {
+		name    string
+		project *Project
+		want    bool
+	}{
+		{"province prefix", &Project{RegionCode: "110105", PurchaseManner: "公开招标"}, true},
+		{"city prefix", &Project{RegionCode: "310101", PurchaseManner: "公开招标"}, true},
+		{"other city", &Project{RegionCode: "310200", PurchaseManner: "公开招标"}, false},
+		{"other manner", &Project{RegionCode: "110105", PurchaseManner: "竞争性谈判"}, false},
+		{"unknown fields pass", &Project{}, true},
+	}
+	for _, tt := range tests {
+		if got := f.Match(tt.project); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+	if !NewNoticeFilter(nil).Match(&Project{RegionCode: "110105"}) {
+		t.Error("expected an empty filter to match everything")
+	}
+}
+
+func TestPlanQueries(t *testing.T) {
+	keys := func(queries []NoticeQuery) []string {
+		var result []string
+		for _, q := range queries {
+			result = append(result, q.Key())
+		}
+		return result
+	}
+
+	regional := NewNoticeFilter([]string{"regionCode=110000", "regionCode=310000", "noticeType=1"})
+	if got, want := keys(regional.Queries()), []string{"noticeType=1&regionCode=110000", "noticeType=1&regionCode=310000"}; !slices.Equal(got, want) {
+		t.Errorf("Queries: expected %v, got %v", want, got)
+	}
+
+	tests := []struct {
+		name    string
+		queries []NoticeQuery
+		want    []string
+	}{
+		{"no users", nil, []string{""}},
+		{"unfiltered user covers all", append(regional.Queries(), NoticeQuery{}), []string{""}},
+		{"broader query covers narrower", append(regional.Queries(), NoticeQuery{filterRegionCode: "110000"}),
+			[]string{"regionCode=110000", "noticeType=1&regionCode=310000"}},
+		{"duplicates", append(regional.Queries(), regional.Queries()...),
+			[]string{"noticeType=1&regionCode=110000", "noticeType=1&regionCode=310000"}},
+	}
+	for _, tt := range tests {
+		if got := keys(PlanQueries(tt.queries)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package handler
+
+import "time"
+
+// NoticeSource fetches procurement notices published within a time window.
+//
+// Sources are registered on the Crawler and their results are merged into a
+// single Crawler.Projects() list, so an additional portal only needs its own
+// NoticeSource implementation instead of a fork of the crawler.
+type NoticeSource interface {
+	// Name identifies the source in logs.
+	Name() string
+	// Fetch returns the notices matching query published between start and
+	// end. A source that cannot filter on some parameter of the query may
+	// return the notices regardless of it, users' filters are matched again
+	// on the client side. On failure it may return the notices fetched
+	// before the error alongside it.
+	Fetch(query NoticeQuery, start, end time.Time) ([]*Project, error)
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+type fakeSource struct {
+	name     string
+	projects []*Project
+	err      error
+	start    time.Time
+	queries  []string
+}
+
+func (f *fakeSource) Name() string { return f.name }
+
+func (f *fakeSource) Fetch(query NoticeQuery, start, _ time.Time) ([]*Project, error) {
+	f.start = start
+	f.queries = append(f.queries, query.Key())
+	result := make([]*Project, 0, len(f.projects))
+	for _, p := range f.projects {
+		if p.PublishedAt.IsZero() || !p.PublishedAt.Before(start) {
+			result = append(result, p)
+		}
+	}
+	return result, f.err
+}
+
+// TestCrawlerMergesSources verifies that AllProjects merges every registered
+// source, keeps a URL reported twice only once, and still uses the partial
+// result of a failing source.
+func TestCrawlerMergesSources(t *testing.T) {
+	c := &Crawler{ctx: testBotContext("")}
+	c.RegisterSource(&fakeSource{name: "a", projects: []*Project{
+		{Title: "a1", Pageurl: "http://a/1"},
+		{Title: "shared", Pageurl: "http://shared/1"},
+	}})
+	c.RegisterSource(&fakeSource{name: "b", projects: []*Project{
+		{Title: "shared", Pageurl: "http://shared/1"},
+		{Title: "b1", Pageurl: "http://b/1"},
+	}, err: errors.New("page 2 failed")})
+	c.RegisterSource(&fakeSource{name: "c", err: errors.New("down")})
+
+	report := NewCrawlReport()
+	got := c.AllProjects(report, nil)
+
+	want := []string{"http://a/1", "http://shared/1", "http://b/1"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d projects, got %d", len(want), len(got))
+	}
+	for i, p := range got {
+		if p.Pageurl != want[i] {
+			t.Errorf("project %d: expected %s, got %s", i, want[i], p.Pageurl)
+		}
+	}
+	if msg := report.String(); !strings.Contains(msg, "2 error(s)") ||
+		!strings.Contains(msg, "b: page 2 failed") || !strings.Contains(msg, "c: down") {
+		t.Errorf("unexpected report %q", msg)
+	}
+}
+
+// TestCrawlerIncremental verifies that Projects resumes each source from its
+// persisted mark, that the marks only move on Advance and that a failing
+// source keeps its old mark.
+func TestCrawlerIncremental(t *testing.T) {
+	f := "./crawl_mark.db"
+	defer func() {
+		_ = os.Remove(f)
+	}()
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.CrawlMark{})
+	dal.SetDefault(db)
+
+	now := time.Now().Truncate(time.Second)
+	ok := &fakeSource{name: "ok", projects: []*Project{
+		{Pageurl: "http://ok/2", PublishedAt: now.Add(-time.Hour)},
+		{Pageurl: "http://ok/1", PublishedAt: now.Add(-2 * time.Hour)},
+	}}
+	broken := &fakeSource{name: "broken", projects: []*Project{
+		{Pageurl: "http://broken/1", PublishedAt: now.Add(-time.Hour)},
+	}, err: errors.New("page 2 failed")}
+	c := &Crawler{ctx: testBotContext("")}
+	c.RegisterSource(ok)
+	c.RegisterSource(broken)
+
+	got, marks := c.Projects(NewCrawlReport(), nil)
+	if len(got) != 3 {
+		t.Fatalf("first run: expected 3 projects, got %d", len(got))
+	}
+	if mark, _ := dal.CrawlMark.Get("ok"); !mark.IsZero() {
+		t.Errorf("expected no mark before Advance, got %s", mark)
+	}
+	c.Advance(marks)
+
+	ok.projects = append([]*Project{{Pageurl: "http://ok/3", PublishedAt: now}}, ok.projects...)
+	got, _ = c.Projects(NewCrawlReport(), nil)
+	if !ok.start.Equal(now.Add(-time.Hour)) {
+		t.Errorf("expected ok to resume from its mark, got %s", ok.start)
+	}
+	if len(got) != 3 || got[0].Pageurl != "http://ok/3" || got[1].Pageurl != "http://ok/2" {
+		t.Errorf("second run: unexpected projects %v", got)
+	}
+	if ok.start.Equal(broken.start) {
+		t.Errorf("expected broken to keep fetching the whole window, got %s", broken.start)
+	}
+	if mark, _ := dal.CrawlMark.Get("broken"); !mark.IsZero() {
+		t.Errorf("expected no mark for a failing source, got %s", mark)
+	}
+}
+
+// TestCrawlerPlansQueries verifies that every source is asked once per
+// planned query.
+func TestCrawlerPlansQueries(t *testing.T) {
+	source := &fakeSource{name: "a"}
+	c := &Crawler{ctx: testBotContext("")}
+	c.RegisterSource(source)
+
+	c.AllProjects(NewCrawlReport(), []NoticeQuery{
+		{filterRegionCode: "110000", filterNoticeType: "1"},
+		{filterRegionCode: "110000"},
+		{filterRegionCode: "310000"},
+	})
+
+	want := []string{"regionCode=110000", "regionCode=310000"}
+	if !slices.Equal(source.queries, want) {
+		t.Errorf("expected queries %v, got %v", want, source.queries)
+	}
+}
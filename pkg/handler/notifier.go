@@ -0,0 +1,445 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gythialy/magnet/pkg/config"
+	"github.com/gythialy/magnet/pkg/constant"
+	"github.com/gythialy/magnet/pkg/model"
+	"github.com/gythialy/magnet/pkg/utils"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+const (
+	notifierTelegram = "telegram"
+	notifierEmail    = "email"
+	notifierWebhook  = "webhook"
+	notifierWeCom    = "wecom"
+	notifierDingTalk = "dingtalk"
+	notifierFeishu   = "feishu"
+	notifierSlack    = "slack"
+)
+
+// errRejected marks a message a channel refused for good, e.g. a robot that
+// was removed, so it is not tried again.
+var errRejected = errors.New("rejected")
+
+// notifierClient posts to the URLs chats set as their notifier. Any chat
+// can set one, so it only connects to public addresses, checked when dialing
+// to cover DNS answers and redirects, and not through a proxy that would
+// hide the address.
+var notifierClient = &http.Client{
+	Timeout: 30 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 30 * time.Second,
+			Control: func(_, address string, _ syscall.RawConn) error {
+				return checkPublicAddr(address)
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConnsPerHost: 2,
+	},
+}
+
+// nonPublicPrefixes are the shared, benchmarking and reserved IPv4 ranges,
+// the other addresses that are not public are told by the net/netip methods.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// isPublic reports whether addr is a public unicast address, not a loopback,
+// private, link-local (e.g. the 169.254.169.254 of cloud metadata) or
+// otherwise internal one.
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkPublicAddr refuses to connect to the host:port address when its IP is
+// not public.
+func checkPublicAddr(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !isPublic(addr) {
+		return fmt.Errorf("%w: %s is not a public address", errRejected, host)
+	}
+	return nil
+}
+
+// Notification is what a push tells a chat about: a matched project, an
+// alarm or a message of a digest.
+type Notification struct {
+	Project *Project
+	Alarm   *model.Alarm
+	Digest  *Digest
+}
+
+// Notifier delivers the notifications of a chat over one channel. Render
+// turns a notification into the messages of the channel, Send delivers one
+// of them; the outbox keeps the rendered messages until they were sent.
+type Notifier interface {
+	Render(n *Notification) ([]string, error)
+	Send(ctx context.Context, msg string) error
+}
+
+// notifierSpec is the channel the notifications of a chat or a keyword group
+// go to, written "<kind>:<target>", e.g. "email:ops@example.com" or
+// "dingtalk:https://oapi.dingtalk.com/robot/send?access_token=... SEC...".
+// Empty or "telegram" is the Telegram chat itself.
+type notifierSpec struct {
+	kind   string
+	target string // the addresses or the URL
+	secret string // signing secret of a DingTalk or Feishu robot
+}
+
+func parseNotifier(s string) (notifierSpec, error) {
+	s = strings.TrimSpace(s)
+	kind, target, _ := strings.Cut(s, ":")
+	spec := notifierSpec{kind: strings.ToLower(kind)}
+	fields := strings.Fields(target)
+	switch spec.kind {
+	case "", notifierTelegram:
+		if target != "" {
+			return spec, fmt.Errorf("invalid notifier %q, telegram takes no target", s)
+		}
+		spec.kind = notifierTelegram
+		return spec, nil
+	case notifierEmail:
+		addresses, err := mail.ParseAddressList(target)
+		if err != nil {
+			return spec, fmt.Errorf("invalid email addresses %q, e.g. email:ops@example.com,sales@example.com", target)
+		}
+		to := make([]string, 0, len(addresses))
+		for _, a := range addresses {
+			to = append(to, a.Address)
+		}
+		spec.target = strings.Join(to, ",")
+		return spec, nil
+	case notifierWebhook, notifierWeCom, notifierSlack:
+		if len(fields) != 1 {
+			return spec, fmt.Errorf("invalid notifier %q, use %s:<url>", s, spec.kind)
+		}
+	case notifierDingTalk, notifierFeishu:
+		if len(fields) != 1 && len(fields) != 2 {
+			return spec, fmt.Errorf("invalid notifier %q, use %s:<url> [secret]", s, spec.kind)
+		}
+		if len(fields) == 2 {
+			spec.secret = fields[1]
+		}
+	default:
+		return spec, fmt.Errorf("unknown notifier %q, use telegram, email, webhook, wecom, dingtalk, feishu or slack", kind)
+	}
+	u, err := url.Parse(fields[0])
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return spec, fmt.Errorf("invalid %s url %q, use https://<host>/...", spec.kind, fields[0])
+	}
+	// Names are checked when connecting, see notifierClient.
+	host := strings.ToLower(u.Hostname())
+	if addr, err := netip.ParseAddr(host); (err == nil && !isPublic(addr)) ||
+		host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return spec, fmt.Errorf("invalid %s url %q, the host is not public", spec.kind, fields[0])
+	}
+	spec.target = u.String()
+	return spec, nil
+}
+
+// checkNotifier checks that the stored spec can be used on this server:
+// mails only go to the domains of SMTP_ALLOWED_DOMAINS.
+func checkNotifier(smtp *config.SMTPConfig, spec string) error {
+	s, err := parseNotifier(spec)
+	if err != nil || s.kind != notifierEmail {
+		return err
+	}
+	for _, to := range strings.Split(s.target, ",") {
+		if smtp == nil || !smtp.Allows(to) {
+			return fmt.Errorf("mails to %s are not allowed, see %s", to, constant.SMTPDomains)
+		}
+	}
+	return nil
+}
+
+// String returns spec as it is stored, empty for the Telegram chat.
+func (s notifierSpec) String() string {
+	switch {
+	case s.kind == notifierTelegram:
+		return ""
+	case s.secret != "":
+		return fmt.Sprintf("%s:%s %s", s.kind, s.target, s.secret)
+	}
+	return s.kind + ":" + s.target
+}
+
+// describe shows spec to the user without the keys and secrets in robot
+// URLs, everyone in a group chat reads it.
+func (s notifierSpec) describe() string {
+	switch s.kind {
+	case notifierTelegram:
+		return s.kind
+	case notifierEmail:
+		return s.kind + ":" + s.target
+	}
+	if u, err := url.Parse(s.target); err == nil {
+		return s.kind + ":" + u.Host
+	}
+	return s.kind
+}
+
+// describeNotifier shows the stored notifier spec to the user.
+func describeNotifier(spec string) string {
+	s, err := parseNotifier(spec)
+	if err != nil {
+		return "invalid"
+	}
+	return s.describe()
+}
+
+// notifier returns the notifier of chatId for the stored spec.
+func (r *InfoProcessor) notifier(chatId int64, spec string) (Notifier, error) {
+	s, err := parseNotifier(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNotifier(r.ctx.Config.SMTP, spec); err != nil {
+		return nil, err
+	}
+	switch s.kind {
+	case notifierEmail:
+		if r.ctx.Config.SMTP == nil || r.ctx.Config.SMTP.Host == "" {
+			return nil, fmt.Errorf("email notifications need %s", constant.SMTPHost)
+		}
+		return &emailNotifier{config: r.ctx.Config.SMTP, to: strings.Split(s.target, ",")}, nil
+	case notifierWebhook:
+		return &webhookNotifier{url: s.target}, nil
+	case notifierWeCom:
+		return &wecomNotifier{url: s.target}, nil
+	case notifierDingTalk:
+		return &dingtalkNotifier{url: s.target, secret: s.secret}, nil
+	case notifierFeishu:
+		return &feishuNotifier{url: s.target, secret: s.secret}, nil
+	case notifierSlack:
+		return &slackNotifier{url: s.target}, nil
+	}
+	return &telegramNotifier{bot: r.ctx.Bot, chatId: chatId}, nil
+}
+
+// notifierOf returns the notifier of chatId for spec and the spec it used.
+// A spec that can not be used, e.g. email without a mail server, falls back
+// to the Telegram chat rather than losing the notifications.
+func (r *InfoProcessor) notifierOf(chatId int64, spec string) (Notifier, string) {
+	n, err := r.notifier(chatId, spec)
+	if err != nil {
+		r.ctx.Logger.Warn().Err(err).Msgf("notify %d on telegram", chatId)
+		return &telegramNotifier{bot: r.ctx.Bot, chatId: chatId}, ""
+	}
+	return n, spec
+}
+
+// telegramNotifier sends the notifications to a Telegram chat, a project is
+// split into as many messages as it takes.
+type telegramNotifier struct {
+	bot    *bot.Bot
+	chatId int64
+}
+
+func (t *telegramNotifier) Render(n *Notification) ([]string, error) {
+	if n.Digest != nil {
+		return []string{n.Digest.render(telegramDigestMarkup)}, nil
+	}
+	if n.Alarm != nil {
+		msg, err := n.Alarm.ToMessage()
+		if err != nil {
+			return nil, err
+		}
+		return []string{msg}, nil
+	}
+	n.Project.Content = utils.SimplifyContent(n.Project.Content)
+	chunks, _ := n.Project.SplitMessage()
+	return chunks, nil
+}
+
+func (t *telegramNotifier) Send(ctx context.Context, msg string) error {
+	_, err := t.bot.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    t.chatId,
+		Text:      msg,
+		ParseMode: models.ParseModeHTML,
+	})
+	return err
+}
+
+// summary is the gist of a notification the channels besides Telegram show:
+// a title linking to the notice, the matched keyword or the listed company,
+// and labelled details.
+type summary struct {
+	title, url, tag string
+	details         [][2]string
+}
+
+// summaryDetailLength caps a detail, e.g. the reason of an alarm, robots
+// take short messages only.
+const summaryDetailLength = 300
+
+func summarize(n *Notification) summary {
+	var s summary
+	add := func(label, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			if r := []rune(value); len(r) > summaryDetailLength {
+				value = string(r[:summaryDetailLength]) + "…"
+			}
+			s.details = append(s.details, [2]string{label, value})
+		}
+	}
+	datetime := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.In(cst).Format("2006-01-02 15:04")
+	}
+	if a := n.Alarm; a != nil {
+		s.title, s.url = a.CreditName, a.PageUrl1
+		if a.Title != nil && *a.Title != "" {
+			s.title = *a.Title
+		}
+		s.tag = fmt.Sprintf("%s (%s)", a.CreditName, a.CreditCode)
+		add("开始时间", a.StartDate.In(cst).Format("2006-01-02"))
+		if a.EndDate != nil {
+			add("结束时间", a.EndDate.In(cst).Format("2006-01-02"))
+		}
+		for _, v := range []struct {
+			label string
+			value *string
+		}{
+			{"处罚部门", a.HandleDepartment},
+			{"具体情形", a.DetailReason},
+			{"处罚结果", a.HandleResult},
+			{"相关链接", a.PageUrl2},
+		} {
+			if v.value != nil {
+				add(v.label, *v.value)
+			}
+		}
+		return s
+	}
+	p := n.Project
+	s.title, s.url, s.tag = p.Title, p.Pageurl, p.Keyword
+	if s.title == "" {
+		s.title = p.ShortTitle
+	}
+	add("发布时间", p.NoticeTime)
+	add("来源", p.Source)
+	add("地区", p.RegionName)
+	if p.Budget != nil {
+		add("预算", formatAmount(p.Budget))
+	}
+	add("开标时间", datetime(p.OpenTenderTime))
+	add("截止时间", datetime(p.ExpireTime))
+	add("代理机构", strings.Join([]string{p.AgentName, p.AgentLinkMan, p.AgentLinkPhone}, " "))
+	return s
+}
+
+// text renders s as plain text.
+func (s summary) text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s\n%s\n", s.tag, s.title, s.url)
+	for _, d := range s.details {
+		fmt.Fprintf(&b, "%s: %s\n", d[0], d[1])
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// postJSON posts payload to url and returns the body of the response. A
+// response refusing the payload, a 4xx other than 429, is errRejected.
+func postJSON(ctx context.Context, url string, payload []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := notifierClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return body, fmt.Errorf("%s: %s", resp.Status, body)
+	case resp.StatusCode >= 400:
+		return body, fmt.Errorf("%w: %s: %s", errRejected, resp.Status, body)
+	}
+	return body, nil
+}
+
+// webhookNotifier posts the notifications as JSON to any URL, e.g.
+//
+//	{"type":"project","title":"...","url":"...","text":"...","project":{...}}
+//
+// The type of a digest is "digest", its notices are in "digest".
+type webhookNotifier struct {
+	url string
+}
+
+// webhookPayload is the body posted by webhookNotifier. Text is the plain
+// text summary, Project, Alarm or Digest the data it was rendered from.
+type webhookPayload struct {
+	Type    string       `json:"type"`
+	Title   string       `json:"title"`
+	URL     string       `json:"url"`
+	Text    string       `json:"text"`
+	Project *Project     `json:"project,omitempty"`
+	Alarm   *model.Alarm `json:"alarm,omitempty"`
+	Digest  *Digest      `json:"digest,omitempty"`
+}
+
+func (w *webhookNotifier) Render(n *Notification) ([]string, error) {
+	if d := n.Digest; d != nil {
+		return renderJSON(webhookPayload{Type: "digest", Title: d.heading(), Text: digestText(d), Digest: d})
+	}
+	s := summarize(n)
+	payload := webhookPayload{Type: "alarm", Title: s.title, URL: s.url, Text: s.text(), Alarm: n.Alarm}
+	if n.Project != nil {
+		p := *n.Project
+		p.Content = cleanContent(p.Content)
+		payload.Type, payload.Project = "project", &p
+	}
+	return renderJSON(payload)
+}
+
+func (w *webhookNotifier) Send(ctx context.Context, msg string) error {
+	_, err := postJSON(ctx, w.url, []byte(msg))
+	return err
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/gythialy/magnet/pkg/config"
+	"github.com/gythialy/magnet/pkg/model"
+	"github.com/gythialy/magnet/pkg/utils"
+)
+
+// smtpsPort is the port of SMTP over TLS, the other ports use STARTTLS when
+// the server offers it.
+const smtpsPort = 465
+
+// emailNotifier mails the notifications as HTML through the SMTP server of
+// the configuration.
+type emailNotifier struct {
+	config *config.SMTPConfig
+	to     []string
+}
+
+// emailDigestMarkup is the HTML of a digest in a mail.
+var emailDigestMarkup = digestMarkup{
+	heading: func(d *Digest) string { return fmt.Sprintf("<h3>%s</h3>\n", html.EscapeString(d.heading())) },
+	group:   func(kw string) string { return fmt.Sprintf("<p><b>[%s]</b></p>\n", html.EscapeString(kw)) },
+	notice: func(n int, h *model.History, details string) string {
+		return fmt.Sprintf("<div>%d. <a href=\"%s\">%s</a> %s</div>\n",
+			n, html.EscapeString(h.URL), html.EscapeString(h.Title), html.EscapeString(details))
+	},
+}
+
+// Render returns the whole mail, headers included.
+func (e *emailNotifier) Render(n *Notification) ([]string, error) {
+	if d := n.Digest; d != nil {
+		return []string{e.mail(d.heading(), d.render(emailDigestMarkup))}, nil
+	}
+	s := summarize(n)
+	var body strings.Builder
+	fmt.Fprintf(&body, "<p><b>[%s]</b></p>\n<h3><a href=\"%s\">%s</a></h3>\n<table>\n",
+		html.EscapeString(s.tag), html.EscapeString(s.url), html.EscapeString(s.title))
+	for _, d := range s.details {
+		fmt.Fprintf(&body, "<tr><td>%s</td><td>%s</td></tr>\n", html.EscapeString(d[0]), html.EscapeString(d[1]))
+	}
+	body.WriteString("</table>\n")
+	if n.Project != nil {
+		fmt.Fprintf(&body, "<div style=\"white-space: pre-wrap\">%s</div>\n",
+			html.EscapeString(utils.SimplifyContent(n.Project.Content)))
+	}
+	return []string{e.mail(fmt.Sprintf("[%s] %s", s.tag, s.title), body.String())}, nil
+}
+
+// mail builds a mail of the HTML body, headers included.
+func (e *emailNotifier) mail(subject, body string) string {
+	var b strings.Builder
+	for _, h := range [][2]string{
+		{"From", e.config.From},
+		{"To", strings.Join(e.to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/html; charset=UTF-8"},
+		{"Content-Transfer-Encoding", "base64"},
+	} {
+		fmt.Fprintf(&b, "%s: %s\r\n", h[0], h[1])
+	}
+	b.WriteString("\r\n")
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return b.String()
+}
+
+// Send mails msg. A mail the server refused for good, with a 5xx reply, is
+// errRejected.
+func (e *emailNotifier) Send(ctx context.Context, msg string) error {
+	err := sendMail(ctx, e.config, e.to, []byte(msg))
+	var reply *textproto.Error
+	if errors.As(err, &reply) && reply.Code >= 500 {
+		return fmt.Errorf("%w: %v", errRejected, err)
+	}
+	return err
+}
+
+func sendMail(ctx context.Context, c *config.SMTPConfig, to []string, msg []byte) error {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", c.Addr())
+	if err != nil {
+		return err
+	}
+	if c.Port == smtpsPort {
+		conn = tls.Client(conn, &tls.Config{ServerName: c.Host})
+	}
+	_ = conn.SetDeadline(time.Now().Add(time.Minute))
+	client, err := smtp.NewClient(conn, c.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer func() { _ = client.Close() }()
+	if ok, _ := client.Extension("STARTTLS"); ok && c.Port != smtpsPort {
+		if err := client.StartTLS(&tls.Config{ServerName: c.Host}); err != nil {
+			return err
+		}
+	}
+	if c.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.Username, c.Password, c.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(c.From); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
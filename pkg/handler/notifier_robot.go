@@ -0,0 +1,264 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+// robotResult is the answer of a WeCom, DingTalk or Feishu robot, they
+// answer failures with status 200 as well.
+type robotResult struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+	Code    int    `json:"code"`
+	Msg     string `json:"msg"`
+}
+
+func (r robotResult) err() error {
+	switch {
+	case r.ErrCode != 0:
+		return fmt.Errorf("robot error %d: %s", r.ErrCode, r.ErrMsg)
+	case r.Code != 0:
+		return fmt.Errorf("robot error %d: %s", r.Code, r.Msg)
+	}
+	return nil
+}
+
+// postRobot posts payload to a robot and checks its answer.
+func postRobot(ctx context.Context, url string, payload []byte) error {
+	body, err := postJSON(ctx, url, payload)
+	if err != nil {
+		return err
+	}
+	var result robotResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("invalid robot response %q", body)
+	}
+	return result.err()
+}
+
+// markdownLink escapes the text of a Markdown link.
+var markdownLink = strings.NewReplacer("[", `\[`, "]", `\]`)
+
+// wecomNotifier sends the notifications to a WeCom group robot as Markdown.
+type wecomNotifier struct {
+	url string
+}
+
+// wecomDigestMarkup is the Markdown of a digest on WeCom.
+var wecomDigestMarkup = digestMarkup{
+	heading: func(d *Digest) string { return fmt.Sprintf("**%s**\n", d.heading()) },
+	group:   func(kw string) string { return fmt.Sprintf("\n**[%s]**\n", kw) },
+	notice: func(n int, h *model.History, details string) string {
+		return fmt.Sprintf("%d. [%s](%s) <font color=\"comment\">%s</font>\n", n, markdownLink.Replace(h.Title), h.URL, details)
+	},
+}
+
+func (w *wecomNotifier) Render(n *Notification) ([]string, error) {
+	if n.Digest != nil {
+		return renderJSON(map[string]any{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"content": n.Digest.render(wecomDigestMarkup)},
+		})
+	}
+	s := summarize(n)
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**\n[%s](%s)\n", s.tag, markdownLink.Replace(s.title), s.url)
+	for _, d := range s.details {
+		fmt.Fprintf(&b, "> %s: <font color=\"comment\">%s</font>\n", d[0], d[1])
+	}
+	return renderJSON(map[string]any{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"content": strings.TrimSpace(b.String())},
+	})
+}
+
+func (w *wecomNotifier) Send(ctx context.Context, msg string) error {
+	return postRobot(ctx, w.url, []byte(msg))
+}
+
+// dingtalkNotifier sends the notifications to a DingTalk group robot as
+// Markdown, signed when the robot has a secret.
+type dingtalkNotifier struct {
+	url    string
+	secret string
+}
+
+// dingtalkDigestMarkup is the Markdown of a digest on DingTalk.
+var dingtalkDigestMarkup = digestMarkup{
+	heading: func(d *Digest) string { return fmt.Sprintf("#### %s\n", d.heading()) },
+	group:   func(kw string) string { return fmt.Sprintf("\n**[%s]**\n\n", kw) },
+	notice: func(n int, h *model.History, details string) string {
+		return fmt.Sprintf("%d. [%s](%s) %s\n", n, markdownLink.Replace(h.Title), h.URL, details)
+	},
+}
+
+func (d *dingtalkNotifier) Render(n *Notification) ([]string, error) {
+	if dg := n.Digest; dg != nil {
+		return renderJSON(map[string]any{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"title": dg.heading(), "text": dg.render(dingtalkDigestMarkup)},
+		})
+	}
+	s := summarize(n)
+	var b strings.Builder
+	fmt.Fprintf(&b, "#### [%s](%s)\n\n**%s**\n\n", markdownLink.Replace(s.title), s.url, s.tag)
+	for _, v := range s.details {
+		fmt.Fprintf(&b, "- %s: %s\n", v[0], v[1])
+	}
+	return renderJSON(map[string]any{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"title": s.title, "text": strings.TrimSpace(b.String())},
+	})
+}
+
+func (d *dingtalkNotifier) Send(ctx context.Context, msg string) error {
+	target := d.url
+	if d.secret != "" {
+		// https://open.dingtalk.com/document/robots/customize-robot-security-settings
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		mac := hmac.New(sha256.New, []byte(d.secret))
+		mac.Write([]byte(timestamp + "\n" + d.secret))
+		sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		u, err := url.Parse(d.url)
+		if err != nil {
+			return err
+		}
+		q := u.Query()
+		q.Set("timestamp", timestamp)
+		q.Set("sign", sign)
+		u.RawQuery = q.Encode()
+		target = u.String()
+	}
+	return postRobot(ctx, target, []byte(msg))
+}
+
+// feishuNotifier sends the notifications to a Feishu (Lark) group robot as
+// a rich text post, signed when the robot has a secret.
+type feishuNotifier struct {
+	url    string
+	secret string
+}
+
+type feishuElement map[string]string
+
+func (f *feishuNotifier) Render(n *Notification) ([]string, error) {
+	if d := n.Digest; d != nil {
+		var content [][]feishuElement
+		for _, g := range d.Groups {
+			content = append(content, []feishuElement{{"tag": "text", "text": "[" + g.Keyword + "]"}})
+			for i, h := range g.Notices {
+				content = append(content, []feishuElement{
+					{"tag": "text", "text": fmt.Sprintf("%d. ", g.First+i)},
+					{"tag": "a", "text": h.Title, "href": h.URL},
+					{"tag": "text", "text": " " + digestDetails(h)},
+				})
+			}
+		}
+		return feishuPost(d.heading(), content)
+	}
+	s := summarize(n)
+	content := [][]feishuElement{
+		{{"tag": "text", "text": "[" + s.tag + "] "}, {"tag": "a", "text": s.title, "href": s.url}},
+	}
+	for _, d := range s.details {
+		content = append(content, []feishuElement{{"tag": "text", "text": d[0] + ": " + d[1]}})
+	}
+	return feishuPost(s.title, content)
+}
+
+// feishuPost renders a rich text post of the lines in content.
+func feishuPost(title string, content [][]feishuElement) ([]string, error) {
+	return renderJSON(map[string]any{
+		"msg_type": "post",
+		"content": map[string]any{
+			"post": map[string]any{
+				"zh_cn": map[string]any{"title": title, "content": content},
+			},
+		},
+	})
+}
+
+func (f *feishuNotifier) Send(ctx context.Context, msg string) error {
+	if f.secret != "" {
+		// https://open.feishu.cn/document/client-docs/bot-v3/add-custom-bot
+		var payload map[string]any
+		if err := json.Unmarshal([]byte(msg), &payload); err != nil {
+			return err
+		}
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(timestamp+"\n"+f.secret))
+		payload["timestamp"] = timestamp
+		payload["sign"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		msg = string(raw)
+	}
+	return postRobot(ctx, f.url, []byte(msg))
+}
+
+// slackNotifier sends the notifications to a Slack incoming webhook as
+// mrkdwn.
+type slackNotifier struct {
+	url string
+}
+
+// slackText escapes text for Slack.
+var slackText = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// slackDigestMarkup is the mrkdwn of a digest on Slack.
+var slackDigestMarkup = digestMarkup{
+	heading: func(d *Digest) string { return fmt.Sprintf("*%s*\n", d.heading()) },
+	group:   func(kw string) string { return fmt.Sprintf("\n`%s`\n", slackText.Replace(kw)) },
+	notice: func(n int, h *model.History, details string) string {
+		return fmt.Sprintf("%d. <%s|%s> %s\n", n, h.URL, slackText.Replace(h.Title), slackText.Replace(details))
+	},
+}
+
+func (s *slackNotifier) Render(n *Notification) ([]string, error) {
+	if n.Digest != nil {
+		// Longer than a block takes, the text of a message takes up to
+		// 40000 characters.
+		return renderJSON(map[string]any{"text": n.Digest.render(slackDigestMarkup)})
+	}
+	sum := summarize(n)
+	var b strings.Builder
+	fmt.Fprintf(&b, "*<%s|%s>*\n`%s`\n", sum.url, slackText.Replace(sum.title), slackText.Replace(sum.tag))
+	for _, d := range sum.details {
+		fmt.Fprintf(&b, "%s: %s\n", d[0], slackText.Replace(d[1]))
+	}
+	return renderJSON(map[string]any{
+		"text": sum.title,
+		"blocks": []any{map[string]any{
+			"type": "section",
+			"text": map[string]string{"type": "mrkdwn", "text": strings.TrimSpace(b.String())},
+		}},
+	})
+}
+
+// Send posts msg, Slack answers "ok" with status 200 or an error status.
+func (s *slackNotifier) Send(ctx context.Context, msg string) error {
+	_, err := postJSON(ctx, s.url, []byte(msg))
+	return err
+}
+
+// renderJSON renders the payload of a robot as the only message.
+func renderJSON(payload any) ([]string, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return []string{string(raw)}, nil
+}
@@ -0,0 +1,360 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/textproto"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gythialy/magnet/pkg/config"
+	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+func TestParseNotifier(t *testing.T) {
+	for _, tc := range []struct {
+		s, spec, describe string
+	}{
+		{"", "", "telegram"},
+		{"Telegram", "", "telegram"},
+		{"email:Ops <ops@example.com>, sales@example.com", "email:ops@example.com,sales@example.com",
+			"email:ops@example.com,sales@example.com"},
+		{"webhook:https://example.com/hook?token=abc", "webhook:https://example.com/hook?token=abc", "webhook:example.com"},
+		{"WeCom:https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=abc",
+			"wecom:https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=abc", "wecom:qyapi.weixin.qq.com"},
+		{"dingtalk:https://oapi.dingtalk.com/robot/send?access_token=abc  SECdef",
+			"dingtalk:https://oapi.dingtalk.com/robot/send?access_token=abc SECdef", "dingtalk:oapi.dingtalk.com"},
+		{"feishu:https://open.feishu.cn/open-apis/bot/v2/hook/abc", "feishu:https://open.feishu.cn/open-apis/bot/v2/hook/abc",
+			"feishu:open.feishu.cn"},
+		{"slack:https://hooks.slack.com/services/T/B/x", "slack:https://hooks.slack.com/services/T/B/x", "slack:hooks.slack.com"},
+	} {
+		spec, err := parseNotifier(tc.s)
+		if err != nil {
+			t.Errorf("parseNotifier(%q): %v", tc.s, err)
+			continue
+		}
+		if spec.String() != tc.spec || spec.describe() != tc.describe {
+			t.Errorf("parseNotifier(%q) = %q (%s), want %q (%s)", tc.s, spec, spec.describe(), tc.spec, tc.describe)
+		}
+	}
+	for _, s := range []string{"telegram:123", "email:", "email:nobody", "webhook:", "webhook:ftp://example.com",
+		"wecom:example.com/hook", "slack:https://hooks.slack.com/x secret", "dingtalk:https://a b c", "sms:123",
+		"webhook:http://example.com/hook", "webhook:https://127.0.0.1:8080/hook", "webhook:https://localhost/hook",
+		"slack:https://169.254.169.254/latest/meta-data", "wecom:https://10.0.0.8/hook", "feishu:https://[::1]/hook",
+		"webhook:https://[::ffff:192.168.1.1]/hook", "webhook:https://100.64.0.1/hook"} {
+		if _, err := parseNotifier(s); err == nil {
+			t.Errorf("expected %q to be rejected", s)
+		}
+	}
+}
+
+func testNotification() (*Notification, *Notification) {
+	budget := 500000.0
+	reason := "提供虚假材料"
+	project := &Project{
+		Title:          "某单位服务器采购项目 <二次>",
+		Pageurl:        "https://example.com/notice/1",
+		Keyword:        "+服务器",
+		RegionName:     "成都市",
+		Budget:         &budget,
+		ExpireTime:     time.Date(2024, 1, 10, 9, 30, 0, 0, cst),
+		Content:        "<p>采购内容</p>",
+		NoticeTime:     "2024-01-03 10:00:00",
+		AgentName:      "某代理公司",
+		AgentLinkPhone: "028-12345678",
+	}
+	alarm := &model.Alarm{
+		CreditName:   "某公司",
+		CreditCode:   "91510100MA0000000X",
+		StartDate:    time.Date(2024, 1, 2, 0, 0, 0, 0, cst),
+		DetailReason: &reason,
+		PageUrl1:     "https://example.com/alarm/1",
+	}
+	return &Notification{Project: project}, &Notification{Alarm: alarm}
+}
+
+// robotServer answers the robots the way they answer, the path picks the
+// robot, and records the requests.
+type robotServer struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []map[string]any
+}
+
+func (s *robotServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	raw, _ := io.ReadAll(r.Body)
+	var body map[string]any
+	_ = json.Unmarshal(raw, &body)
+	s.requests, s.bodies = append(s.requests, r), append(s.bodies, body)
+	switch r.URL.Path {
+	case "/wecom", "/dingtalk":
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	case "/feishu":
+		_, _ = w.Write([]byte(`{"code":0,"msg":"success"}`))
+	case "/busy":
+		_, _ = w.Write([]byte(`{"errcode":45009,"errmsg":"api freq out of limit"}`))
+	case "/gone":
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("no_service"))
+	default:
+		_, _ = w.Write([]byte("ok"))
+	}
+}
+
+func (s *robotServer) last() (*http.Request, map[string]any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[len(s.requests)-1], s.bodies[len(s.bodies)-1]
+}
+
+func TestRobotNotifiers(t *testing.T) {
+	srv := &robotServer{}
+	base := testNotifierServer(t, srv)
+	r := &InfoProcessor{ctx: testBotContext("")}
+	project, alarm := testNotification()
+
+	for _, tc := range []struct {
+		spec  string
+		check func(body map[string]any) string
+	}{
+		{"webhook:" + base + "/webhook", func(body map[string]any) string {
+			return body["type"].(string) + " " + body["title"].(string)
+		}},
+		{"wecom:" + base + "/wecom", func(body map[string]any) string {
+			return body["msgtype"].(string) + " " + body["markdown"].(map[string]any)["content"].(string)
+		}},
+		{"dingtalk:" + base + "/dingtalk?access_token=abc SECdef", func(body map[string]any) string {
+			return body["msgtype"].(string) + " " + body["markdown"].(map[string]any)["text"].(string)
+		}},
+		{"feishu:" + base + "/feishu secret", func(body map[string]any) string {
+			post := body["content"].(map[string]any)["post"].(map[string]any)["zh_cn"].(map[string]any)
+			raw, _ := json.Marshal(post["content"])
+			return body["msg_type"].(string) + " " + body["sign"].(string) + " " + string(raw)
+		}},
+		{"slack:" + base + "/slack", func(body map[string]any) string {
+			raw, _ := json.Marshal(body["blocks"])
+			return body["text"].(string) + " " + string(raw)
+		}},
+	} {
+		n, err := r.notifier(1, tc.spec)
+		if err != nil {
+			t.Fatalf("notifier(%q): %v", tc.spec, err)
+		}
+		for _, notification := range []*Notification{project, alarm} {
+			msgs, err := n.Render(notification)
+			if err != nil || len(msgs) != 1 {
+				t.Fatalf("%s: unexpected messages %q, %v", tc.spec, msgs, err)
+			}
+			if err := n.Send(context.Background(), msgs[0]); err != nil {
+				t.Fatalf("%s: %v", tc.spec, err)
+			}
+			req, body := srv.last()
+			if body == nil {
+				t.Fatalf("%s: expected a JSON body", tc.spec)
+			}
+			got := tc.check(body)
+			want := "某单位服务器采购项目"
+			if notification.Alarm != nil {
+				want = "某公司"
+			}
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: expected %q in %s", tc.spec, want, got)
+			}
+			if strings.HasPrefix(tc.spec, "dingtalk") && (req.URL.Query().Get("sign") == "" ||
+				req.URL.Query().Get("access_token") != "abc") {
+				t.Errorf("expected a signed DingTalk request, got %s", req.URL)
+			}
+		}
+	}
+
+	for path, gone := range map[string]bool{"/busy": false, "/gone": true} {
+		n, _ := r.notifier(1, "wecom:"+base+path)
+		err := n.Send(context.Background(), `{}`)
+		if err == nil || refused(err) != gone {
+			t.Errorf("%s: unexpected error %v", path, err)
+		}
+	}
+	if _, err := r.notifier(1, "email:ops@example.com"); err == nil {
+		t.Error("expected email to need a mail server")
+	}
+}
+
+// testNotifierServer serves the notifier requests of the test with h over
+// TLS, at the returned URL. It stands in for public hosts, the client of the
+// notifiers only connects to public addresses.
+func testNotifierServer(t *testing.T, h http.Handler) string {
+	ts := httptest.NewTLSServer(h)
+	t.Cleanup(ts.Close)
+	client := ts.Client()
+	client.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, ts.Listener.Addr().String())
+	}
+	saved := notifierClient
+	notifierClient = client
+	t.Cleanup(func() { notifierClient = saved })
+	// The certificate of the test server is for example.com.
+	return "https://example.com"
+}
+
+func TestNotifierClient(t *testing.T) {
+	ts := httptest.NewTLSServer(&robotServer{})
+	defer ts.Close()
+	_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+	for _, u := range []string{ts.URL + "/webhook", "https://localhost:" + port + "/webhook"} {
+		_, err := postJSON(context.Background(), u, []byte(`{}`))
+		if err == nil || !refused(err) || !strings.Contains(err.Error(), "is not a public address") {
+			t.Errorf("%s: expected the loopback address to be refused, got %v", u, err)
+		}
+	}
+	for addr, public := range map[string]bool{
+		"8.8.8.8": true, "2001:4860:4860::8888": true, "127.0.0.1": false, "::1": false, "10.1.2.3": false,
+		"172.16.0.1": false, "192.168.0.1": false, "169.254.169.254": false, "fe80::1": false, "fd00::1": false,
+		"0.0.0.0": false, "100.64.0.1": false, "::ffff:127.0.0.1": false, "224.0.0.1": false,
+	} {
+		if got := isPublic(netip.MustParseAddr(addr)); got != public {
+			t.Errorf("isPublic(%s) = %v, want %v", addr, got, public)
+		}
+	}
+}
+
+// smtpServer accepts one mail the way a mail server without TLS and
+// authentication does and returns it.
+func smtpServer(t *testing.T) (int, <-chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	mails := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		c := textproto.NewConn(conn)
+		_ = c.PrintfLine("220 localhost ready")
+		var mail strings.Builder
+		for {
+			line, err := c.ReadLine()
+			if err != nil {
+				return
+			}
+			switch strings.ToUpper(strings.Fields(line + " x")[0]) {
+			case "EHLO", "HELO":
+				_ = c.PrintfLine("250 localhost")
+			case "DATA":
+				_ = c.PrintfLine("354 go ahead")
+				data, _ := io.ReadAll(bufio.NewReader(c.DotReader()))
+				mail.Write(data)
+				_ = c.PrintfLine("250 ok")
+			case "QUIT":
+				_ = c.PrintfLine("221 bye")
+				mails <- mail.String()
+				return
+			default:
+				mail.WriteString(line + "\n")
+				_ = c.PrintfLine("250 ok")
+			}
+		}
+	}()
+	return l.Addr().(*net.TCPAddr).Port, mails
+}
+
+func TestEmailNotifier(t *testing.T) {
+	port, mails := smtpServer(t)
+	ctx := testBotContext("")
+	ctx.Config.SMTP = &config.SMTPConfig{Host: "127.0.0.1", Port: port, From: "bot@example.com", Domains: []string{"example.com"}}
+	r := &InfoProcessor{ctx: ctx}
+	if _, err := r.notifier(1, "email:ops@example.com,someone@example.org"); err == nil {
+		t.Error("expected mails to other domains to be refused")
+	}
+	n, err := r.notifier(1, "email:ops@example.com,sales@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, _ := testNotification()
+	msgs, err := n.Render(project)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("unexpected messages %q, %v", msgs, err)
+	}
+	if err := n.Send(context.Background(), msgs[0]); err != nil {
+		t.Fatal(err)
+	}
+	mail := <-mails
+	for _, want := range []string{"MAIL FROM:<bot@example.com>", "RCPT TO:<ops@example.com>", "RCPT TO:<sales@example.com>",
+		"To: ops@example.com, sales@example.com", "Subject: =?utf-8?q?", "Content-Type: text/html; charset=UTF-8"} {
+		if !strings.Contains(mail, want) {
+			t.Errorf("expected %q in\n%s", want, mail)
+		}
+	}
+	// The dot reader of the server turned the line ends into "\n".
+	_, encoded, _ := strings.Cut(mail, "\n\n")
+	body, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(encoded, "\n", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<a href="https://example.com/notice/1">某单位服务器采购项目 &lt;二次&gt;</a>`,
+		"<td>预算</td><td>50万元</td>", "<td>截止时间</td><td>2024-01-10 09:30</td>", "采购内容"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected %q in\n%s", want, body)
+		}
+	}
+}
+
+func TestPushNotifier(t *testing.T) {
+	f := "./notifier_test.db"
+	defer func() { _ = os.Remove(f) }()
+	r, srv := testOutbox(t, f)
+	robot := &robotServer{}
+	base := testNotifierServer(t, robot)
+
+	chatId := int64(5353)
+	if err := dal.ChatSetting.Save(&model.ChatSetting{ChatID: chatId, Notifier: "wecom:" + base + "/busy"}); err != nil {
+		t.Fatal(err)
+	}
+	_, alarm := testNotification()
+	alarm.Alarm.UserID = chatId
+	if err := r.sendAlarm(alarm.Alarm); err != nil {
+		t.Fatal(err)
+	}
+	// Telegram does not wait behind the robot.
+	if err := r.push(chatId, "", "summary", false, false); err != nil {
+		t.Fatal(err)
+	}
+	waiting, _ := dal.Outbox.GetByChatId(chatId)
+	if srv.texts() != "summary" || len(waiting) != 1 || waiting[0].Notifier != "wecom:"+base+"/busy" ||
+		waiting[0].Attempts != 1 {
+		t.Fatalf("expected the alarm to wait for the robot, sent %q, outbox %+v", srv.texts(), waiting)
+	}
+
+	waiting[0].Notifier = "wecom:" + base + "/wecom"
+	if err := dal.Outbox.Save(waiting[0]); err != nil {
+		t.Fatal(err)
+	}
+	r.SendOutbox(waiting[0].NextAttemptAt.Add(time.Second))
+	if waiting, _ := dal.Outbox.GetByChatId(chatId); len(waiting) != 0 {
+		t.Errorf("expected the outbox to be empty, got %d messages", len(waiting))
+	}
+	req, body := robot.last()
+	if req.URL.Path != "/wecom" || !strings.Contains(body["markdown"].(map[string]any)["content"].(string),
+		"91510100MA0000000X") {
+		t.Errorf("unexpected robot message %s %v", req.URL, body)
+	}
+	if srv.texts() != "summary" {
+		t.Errorf("unexpected telegram messages %q", srv.texts())
+	}
+}
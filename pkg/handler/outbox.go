@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
+
+	"github.com/go-telegram/bot"
+)
+
+const (
+	// outboxAttempts is how often a message is tried before it is dropped.
+	outboxAttempts = 10
+	// outboxBackoff is the wait after the first failed attempt, it doubles
+	// with every further one up to outboxMaxBackoff.
+	outboxBackoff    = 30 * time.Second
+	outboxMaxBackoff = time.Hour
+)
+
+// push sends the message text rendered by the notifier of spec to chatId.
+// The message is queued in the outbox instead when held, e.g. in the quiet
+// hours of the chat, while earlier messages to the chat over the same
+// notifier wait there, so they keep their order, or when sending failed for
+// a reason that may pass; SendOutbox sends it later. push only fails when
+// the message was refused for good or it could not be queued.
+func (r *InfoProcessor) push(chatId int64, spec, text string, held, alarm bool) error {
+	now := time.Now()
+	msg := &model.Outbox{ChatID: chatId, Text: text, Alarm: btoi(alarm), CreatedAt: now, Notifier: spec}
+	if !held {
+		waiting, err := dal.Outbox.Waiting(chatId, spec)
+		if err != nil {
+			r.ctx.Logger.Error().Stack().Err(err).Msg("")
+		}
+		if !waiting {
+			err := r.deliver(chatId, spec, text)
+			if err == nil || refused(err) {
+				return err
+			}
+			r.ctx.Logger.Warn().Err(err).Msgf("queue message to %d", chatId)
+			postpone(msg, err, now)
+		}
+	}
+	return dal.Outbox.Create(msg)
+}
+
+// deliver sends the message text rendered by the notifier of spec to chatId.
+func (r *InfoProcessor) deliver(chatId int64, spec, text string) error {
+	n, err := r.notifier(chatId, spec)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errRejected, err)
+	}
+	return n.Send(context.Background(), text)
+}
+
+// refused reports whether a message was refused for good, e.g. the bot was
+// blocked, the chat is gone or a robot was removed, so trying again is
+// pointless.
+func refused(err error) bool {
+	return errors.Is(err, bot.ErrorForbidden) || errors.Is(err, bot.ErrorBadRequest) ||
+		bot.IsMigrateError(err) || errors.Is(err, errRejected)
+}
+
+// postpone records the failed attempt err of msg at now and schedules the
+// next one: after the retry_after Telegram asked for, otherwise with an
+// exponential backoff.
+func postpone(msg *model.Outbox, err error, now time.Time) {
+	msg.Attempts++
+	msg.LastError = err.Error()
+	wait := outboxMaxBackoff
+	if n := msg.Attempts - 1; n < 8 {
+		wait = min(outboxBackoff<<n, outboxMaxBackoff)
+	}
+	var tooMany *bot.TooManyRequestsError
+	if errors.As(err, &tooMany) && tooMany.RetryAfter > 0 {
+		wait = time.Duration(tooMany.RetryAfter) * time.Second
+	}
+	next := now.Add(wait)
+	msg.NextAttemptAt = &next
+}
+
+// SendOutbox sends the messages of the outbox due at now, per chat and
+// notifier in the order they were queued. The messages of a chat in its
+// quiet hours wait for them to end, except the alarms of a chat exempting
+// them. A message failing to send holds back the ones after it over the same
+// notifier until its next attempt, it is dropped after outboxAttempts
+// attempts or when it was refused for good.
+func (r *InfoProcessor) SendOutbox(now time.Time) {
+	logger := r.ctx.Logger
+	chats, err := dal.Outbox.Chats()
+	if err != nil {
+		logger.Error().Stack().Err(err).Msg("get outbox failed")
+		return
+	}
+	for _, chatId := range chats {
+		s := r.settings(chatId)
+		quiet := quietAt(s, now)
+		waiting, err := dal.Outbox.GetByChatId(chatId)
+		if err != nil {
+			logger.Error().Stack().Err(err).Msgf("get outbox of %d failed", chatId)
+			continue
+		}
+		sent := 0
+		blocked := make(map[string]bool)
+		for _, msg := range waiting {
+			if blocked[msg.Notifier] || quiet && (msg.Alarm == 0 || s.AlarmExempt == 0) {
+				continue
+			}
+			if msg.NextAttemptAt != nil && msg.NextAttemptAt.After(now) {
+				blocked[msg.Notifier] = true
+				continue
+			}
+			err := r.deliver(chatId, msg.Notifier, msg.Text)
+			if err == nil {
+				sent++
+				if err := dal.Outbox.Remove(*msg.ID); err != nil {
+					logger.Error().Stack().Err(err).Msg("")
+				}
+				continue
+			}
+			postpone(msg, err, now)
+			if refused(err) || msg.Attempts >= outboxAttempts {
+				logger.Error().Err(err).Msgf("drop message to %d after %d attempts: %s", chatId, msg.Attempts, msg.Text)
+				if err := dal.Outbox.Remove(*msg.ID); err != nil {
+					logger.Error().Stack().Err(err).Msg("")
+				}
+				continue
+			}
+			logger.Warn().Err(err).Msgf("message to %d failed, attempt %d", chatId, msg.Attempts)
+			if err := dal.Outbox.Save(msg); err != nil {
+				logger.Error().Stack().Err(err).Msg("")
+			}
+			blocked[msg.Notifier] = true
+		}
+		if sent > 0 {
+			logger.Info().Msgf("outbox: sent %d messages to %d", sent, chatId)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-telegram/bot"
+	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
+	"gorm.io/gorm"
+)
+
+// outboxServer answers sendMessage with the queued responses, then with
+// success, and records the texts it accepted.
+type outboxServer struct {
+	mu        sync.Mutex
+	responses []string
+	sent      []string
+}
+
+func (s *outboxServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if path.Base(r.URL.Path) == "sendMessage" && len(s.responses) > 0 {
+		resp := s.responses[0]
+		s.responses = s.responses[1:]
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(resp))
+		return
+	}
+	s.sent = append(s.sent, r.FormValue("text"))
+	_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1,"chat":{"id":1}}}`))
+}
+
+func (s *outboxServer) fail(responses ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = append(s.responses, responses...)
+}
+
+func (s *outboxServer) texts() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return strings.Join(s.sent, ",")
+}
+
+const (
+	tooManyRequests = `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 7","parameters":{"retry_after":7}}`
+	serverError     = `{"ok":false,"error_code":502,"description":"Bad Gateway"}`
+	chatNotFound    = `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`
+)
+
+func testOutbox(t *testing.T, f string) (*InfoProcessor, *outboxServer) {
+	db, err := gorm.Open(sqlite.Open(f), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.ChatSetting{}, &model.Outbox{})
+	dal.SetDefault(db)
+
+	srv := &outboxServer{}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	b, err := bot.New("1:token", bot.WithSkipGetMe(), bot.WithServerURL(ts.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := testBotContext("")
+	ctx.Bot = b
+	return &InfoProcessor{ctx: ctx}, srv
+}
+
+func TestPostpone(t *testing.T) {
+	now := time.Now()
+	msg := &model.Outbox{}
+	for _, want := range []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute} {
+		postpone(msg, bot.ErrorNotFound, now)
+		if got := msg.NextAttemptAt.Sub(now); got != want {
+			t.Errorf("attempt %d: wait %s, want %s", msg.Attempts, got, want)
+		}
+	}
+	msg.Attempts = 20
+	if postpone(msg, bot.ErrorNotFound, now); msg.NextAttemptAt.Sub(now) != outboxMaxBackoff {
+		t.Errorf("expected the wait to be capped, got %s", msg.NextAttemptAt.Sub(now))
+	}
+	postpone(msg, &bot.TooManyRequestsError{Message: "too many requests", RetryAfter: 7}, now)
+	if msg.NextAttemptAt.Sub(now) != 7*time.Second || msg.Attempts != 22 || !strings.Contains(msg.LastError, "retry_after 7") {
+		t.Errorf("expected retry_after to be used, got %+v", msg)
+	}
+}
+
+func TestSendOutbox(t *testing.T) {
+	f := "./outbox_test.db"
+	defer func() { _ = os.Remove(f) }()
+	r, srv := testOutbox(t, f)
+
+	chatId := int64(5151)
+	srv.fail(tooManyRequests)
+	if err := r.push(chatId, "", "first", false, false); err != nil {
+		t.Fatal(err)
+	}
+	// Queued behind the first message to keep the order.
+	if err := r.push(chatId, "", "second", false, false); err != nil {
+		t.Fatal(err)
+	}
+	if srv.texts() != "" {
+		t.Fatalf("unexpected messages %q", srv.texts())
+	}
+	waiting, _ := dal.Outbox.GetByChatId(chatId)
+	if len(waiting) != 2 || waiting[0].Attempts != 1 || waiting[0].NextAttemptAt == nil || waiting[1].Attempts != 0 {
+		t.Fatalf("unexpected outbox %+v", waiting)
+	}
+	retry := *waiting[0].NextAttemptAt
+
+	r.SendOutbox(retry.Add(-time.Second))
+	if srv.texts() != "" {
+		t.Fatalf("expected to wait for retry_after, sent %q", srv.texts())
+	}
+	srv.fail(serverError)
+	r.SendOutbox(retry)
+	waiting, _ = dal.Outbox.GetByChatId(chatId)
+	if srv.texts() != "" || len(waiting) != 2 || waiting[0].Attempts != 2 || waiting[0].LastError == "" {
+		t.Fatalf("expected the failed attempt to be recorded, sent %q, outbox %+v", srv.texts(), waiting)
+	}
+	r.SendOutbox(retry.Add(outboxMaxBackoff))
+	if srv.texts() != "first,second" {
+		t.Errorf("unexpected messages %q", srv.texts())
+	}
+	if waiting, _ := dal.Outbox.GetByChatId(chatId); len(waiting) != 0 {
+		t.Errorf("expected the outbox to be empty, got %d messages", len(waiting))
+	}
+
+	// Refused for good: reported at once, dropped from the outbox.
+	srv.fail(chatNotFound)
+	if err := r.push(chatId, "", "lost", false, false); err == nil {
+		t.Error("expected a refused message to fail")
+	}
+	if err := r.push(chatId, "", "dropped", true, false); err != nil {
+		t.Fatal(err)
+	}
+	srv.fail(chatNotFound)
+	r.SendOutbox(time.Now())
+	if waiting, _ := dal.Outbox.GetByChatId(chatId); len(waiting) != 0 {
+		t.Errorf("expected the refused message to be dropped, got %d messages", len(waiting))
+	}
+}
+
+func TestSendOutboxQuietHours(t *testing.T) {
+	f := "./outbox_quiet_test.db"
+	defer func() { _ = os.Remove(f) }()
+	r, srv := testOutbox(t, f)
+
+	// Quiet around the clock, except for the hour starting now.
+	chatId := int64(5252)
+	now := time.Now().In(cst)
+	from := now.Add(time.Hour).Format("15:04")
+	if err := dal.ChatSetting.Save(&model.ChatSetting{ChatID: chatId, QuietHours: from + "-" + now.Format("15:04"),
+		AlarmExempt: 1}); err != nil {
+		t.Fatal(err)
+	}
+	later := now.Add(90 * time.Minute)
+	if !quietAt(r.settings(chatId), later) || quietAt(r.settings(chatId), now) {
+		t.Fatal("unexpected quiet hours")
+	}
+
+	for _, text := range []string{"first", "second"} {
+		if err := r.push(chatId, "", text, true, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.push(chatId, "", "alarm", true, true); err != nil {
+		t.Fatal(err)
+	}
+	r.SendOutbox(later)
+	if srv.texts() != "alarm" {
+		t.Fatalf("expected only the exempt alarm to be sent, sent %q", srv.texts())
+	}
+	r.SendOutbox(now)
+	if srv.texts() != "alarm,first,second" {
+		t.Errorf("unexpected messages %q", srv.texts())
+	}
+	if waiting, _ := dal.Outbox.GetByChatId(chatId); len(waiting) != 0 {
+		t.Errorf("expected the outbox to be empty, got %d messages", len(waiting))
+	}
+}
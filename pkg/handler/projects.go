@@ -2,21 +2,30 @@ package handler
 
 import (
 	"bytes"
+	"fmt"
 	"html/template"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gythialy/magnet/pkg/utils"
 
 	"github.com/gythialy/magnet/pkg/dal"
+	"github.com/gythialy/magnet/pkg/model"
 
 	"github.com/gythialy/magnet/pkg/rule"
 )
 
 const (
-	keywordTemplate = `{{if .HasTenderCode}}🔥{{end}}<a href="{{.Pageurl}}">{{.Title}}</a> @ {{.NoticeTime}}
+	keywordTemplate = `{{if .HasTenderCode}}🔥{{end}}<a href="{{.Pageurl}}">{{.Title}}</a> @ {{.NoticeTime}}{{if .Source}} ({{.Source}}){{end}}
 <b>[{{.Keyword}}]</b>
-
+{{if .RegionName}}地区: {{.RegionName}}
+{{end}}{{if .Budget}}预算: {{amount .Budget}}
+{{end}}{{if not .OpenTenderTime.IsZero}}开标时间: {{datetime .OpenTenderTime}}
+{{end}}{{if not .ExpireTime.IsZero}}截止时间: {{datetime .ExpireTime}}
+{{end}}{{if .AgentName}}代理机构: {{.AgentName}}{{if .AgentLinkMan}} {{.AgentLinkMan}}{{end}}{{if .AgentLinkPhone}} {{.AgentLinkPhone}}{{end}}
+{{end}}
 {{ .Content | noescape }} `
 	maxMessageLength = 4090
 )
@@ -26,18 +35,129 @@ var keywordRender = template.Must(template.New("keyword_template").
 		"noescape": func(str string) template.HTML {
 			return template.HTML(str)
 		},
+		"amount": formatAmount,
+		"datetime": func(t time.Time) string {
+			return t.In(cst).Format("2006-01-02 15:04")
+		},
 	}).
 	Parse(keywordTemplate))
 
 type Project struct {
-	NoticeTime     string `json:"noticeTime,omitempty"`
-	OpenTenderCode string `json:"openTenderCode,omitempty"`
-	Title          string `json:"title,omitempty"`
-	ShortTitle     string `json:"-"`
-	Content        string `json:"content,omitempty"`
-	Pageurl        string `json:"pageurl,omitempty"`
-	Keyword        string `json:"keyword,omitempty"`
-	HasTenderCode  bool   `json:"-"`
+	NoticeTime     string    `json:"noticeTime,omitempty"`
+	PublishedAt    time.Time `json:"-"`
+	OpenTenderCode string    `json:"openTenderCode,omitempty"`
+	Title          string    `json:"title,omitempty"`
+	ShortTitle     string    `json:"-"`
+	Content        string    `json:"content,omitempty"`
+	Pageurl        string    `json:"pageurl,omitempty"`
+	Source         string    `json:"source,omitempty"`
+	RegionCode     string    `json:"regionCode,omitempty"`
+	PurchaseManner string    `json:"purchaseManner,omitempty"`
+	NoticeType     string    `json:"noticeType,omitempty"`
+	PurchaseNature string    `json:"purchaseNature,omitempty"`
+	RegionName     string    `json:"regionName,omitempty"`
+	Budget         *float64  `json:"budget,omitempty"`
+	OpenTenderTime time.Time `json:"openTenderTime,omitzero"`
+	ExpireTime     time.Time `json:"expireTime,omitzero"`
+	AgentName      string    `json:"agentName,omitempty"`
+	AgentLinkMan   string    `json:"agentLinkMan,omitempty"`
+	AgentLinkPhone string    `json:"agentLinkPhone,omitempty"`
+	Keyword        string    `json:"keyword,omitempty"`
+	HasTenderCode  bool      `json:"-"`
+	// CityOrArea is not part of the notice, it is the cityOrArea filter the
+	// notice was fetched with, empty when fetched without one.
+	CityOrArea string `json:"cityOrArea,omitempty"`
+}
+
+// formatAmount renders an amount in yuan, switching to 万元 from ten
+// thousand on.
+func formatAmount(amount *float64) string {
+	if *amount >= 10000 {
+		return strconv.FormatFloat(*amount/10000, 'f', -1, 64) + "万元"
+	}
+	return strconv.FormatFloat(*amount, 'f', -1, 64) + "元"
+}
+
+// Deadline is the end of the bidding window: the expire time when the portal
+// published one, the open tender time otherwise.
+func (p *Project) Deadline() time.Time {
+	if !p.ExpireTime.IsZero() {
+		return p.ExpireTime
+	}
+	return p.OpenTenderTime
+}
+
+// History returns the history row recording that p was pushed to userId.
+func (p *Project) History(userId int64, updatedAt time.Time) *model.History {
+	h := &model.History{
+		UserID:         userId,
+		URL:            p.Pageurl,
+		Title:          p.ShortTitle,
+		UpdatedAt:      updatedAt,
+		HasTenderCode:  btoi(p.HasTenderCode),
+		Source:         p.Source,
+		RegionName:     p.RegionName,
+		Budget:         p.Budget,
+		AgentName:      p.AgentName,
+		AgentLinkMan:   p.AgentLinkMan,
+		AgentLinkPhone: p.AgentLinkPhone,
+	}
+	if t := p.OpenTenderTime; !t.IsZero() {
+		h.OpenTenderTime = &t
+	}
+	if t := p.ExpireTime; !t.IsZero() {
+		h.ExpireTime = &t
+	}
+	return h
+}
+
+// Notice returns the notices row keeping p for /test_keyword. Only the
+// fields keyword rules read are kept, the content as plain text.
+func (p *Project) Notice(crawledAt time.Time) *model.Notice {
+	n := &model.Notice{
+		URL:         p.Pageurl,
+		Title:       p.ShortTitle,
+		TenderCode:  p.OpenTenderCode,
+		Content:     cleanContent(p.Content),
+		Source:      p.Source,
+		RegionName:  p.RegionName,
+		Budget:      p.Budget,
+		PublishedAt: p.PublishedAt,
+		CrawledAt:   crawledAt,
+	}
+	if n.PublishedAt.IsZero() {
+		n.PublishedAt = crawledAt
+	}
+	if t := p.OpenTenderTime; !t.IsZero() {
+		n.OpenTenderTime = &t
+	}
+	if t := p.ExpireTime; !t.IsZero() {
+		n.ExpireTime = &t
+	}
+	return n
+}
+
+// projectFromNotice restores the Project stored by Project.Notice.
+func projectFromNotice(n *model.Notice) *Project {
+	p := &Project{
+		NoticeTime:     n.PublishedAt.In(cst).Format(time.DateTime),
+		PublishedAt:    n.PublishedAt,
+		OpenTenderCode: n.TenderCode,
+		Title:          n.Title,
+		ShortTitle:     n.Title,
+		Content:        n.Content,
+		Pageurl:        n.URL,
+		Source:         n.Source,
+		RegionName:     n.RegionName,
+		Budget:         n.Budget,
+	}
+	if n.OpenTenderTime != nil {
+		p.OpenTenderTime = *n.OpenTenderTime
+	}
+	if n.ExpireTime != nil {
+		p.ExpireTime = *n.ExpireTime
+	}
+	return p
 }
 
 func (p *Project) ToMessage() string {
@@ -96,18 +216,44 @@ func (p *Project) SplitMessage() ([]string, int) {
 	return chunks, len(chunks)
 }
 
+// matchLabel describes a matched rule in the pushed message, e.g.
+// "+服务器 +content:GPU (title, content)", prefixed with the label of the
+// rule's keyword group, e.g. "network gear: +交换机".
+func matchLabel(cr *rule.ComplexRule, scopes []rule.Scope, group string) string {
+	label := cr.ToString()
+	if group != "" {
+		label = group + ": " + label
+	}
+	if len(scopes) == 0 {
+		return label
+	}
+	names := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		names = append(names, string(s))
+	}
+	return fmt.Sprintf("%s (%s)", label, strings.Join(names, ", "))
+}
+
 type Projects struct {
 	Projects        []*Project
 	keywordProjects []*Project
-	rules           []*rule.ComplexRule
+	matcher         *rule.Matcher
 	ctx             *BotContext
 	counters        *sync.Map
+	// labels holds the group label of the rules by keyword id.
+	labels map[int32]string
 }
 
 func NewProjects(ctx *BotContext, projects []*Project, rules []*rule.ComplexRule) *Projects {
+	return newProjects(ctx, projects, rule.NewMatcher(rules))
+}
+
+// newProjects filters projects with an already compiled matcher, see
+// MatcherCache.
+func newProjects(ctx *BotContext, projects []*Project, matcher *rule.Matcher) *Projects {
 	return &Projects{
 		Projects:        projects,
-		rules:           rules,
+		matcher:         matcher,
 		keywordProjects: make([]*Project, 0),
 		ctx:             ctx,
 		counters:        &sync.Map{},
@@ -116,14 +262,34 @@ func NewProjects(ctx *BotContext, projects []*Project, rules []*rule.ComplexRule
 
 func (r *Projects) Filter() []*Project {
 	logger := r.ctx.Logger
+	now := time.Now()
+	needsContent := r.matcher.NeedsContent()
+	var hits []*model.KeywordHit
 	for _, v := range r.Projects {
 		logger.Debug().Msgf("process: %s,%s[%s]", v.ShortTitle, v.OpenTenderCode, v.NoticeTime)
-		matched := make([]string, 0, len(r.rules))
-		for _, cr := range r.rules {
-			if cr.IsMatch(v.ShortTitle) || cr.IsMatch(v.OpenTenderCode) {
-				matched = append(matched, cr.ToString())
+		var matched []string
+		target := rule.Target{Budget: v.Budget, Deadline: v.Deadline(), Now: now}
+		content := ""
+		if needsContent {
+			content = cleanContent(v.Content)
+		}
+		doc := rule.NewDocument(v.ShortTitle, v.OpenTenderCode, content)
+		for _, m := range r.matcher.Match(doc) {
+			cr := m.Rule
+			if cr.MatchTarget(target) {
+				group := ""
+				if cr.Rule != nil && cr.Rule.ID != nil {
+					group = r.labels[*cr.Rule.ID]
+				}
+				matched = append(matched, matchLabel(cr, m.Scopes, group))
 				if cr.Rule != nil && cr.Rule.ID != nil {
 					key := *cr.Rule.ID
+					hits = append(hits, &model.KeywordHit{
+						KeywordID: key,
+						UserID:    cr.Rule.UserID,
+						URL:       v.Pageurl,
+						HitAt:     now,
+					})
 					if val, ok := r.counters.Load(key); ok {
 						counter := val.(int32)
 						r.counters.Store(key, counter+1)
@@ -140,6 +306,10 @@ func (r *Projects) Filter() []*Project {
 		}
 	}
 
+	if err := dal.KeywordHit.Record(hits); err != nil {
+		logger.Error().Err(err).Msg("failed to record keyword hits")
+	}
+
 	// Update counters in database
 	r.counters.Range(func(key, value interface{}) bool {
 		keyId, ok := key.(int32)
@@ -3,10 +3,16 @@ package handler
 import (
 	"fmt"
 	"index/suffixarray"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/gythialy/magnet/pkg/dal"
 	"github.com/gythialy/magnet/pkg/model"
 
 	"github.com/gythialy/magnet/pkg/rule"
@@ -111,3 +117,190 @@ func TestMergeLines(t *testing.T) {
 		})
 	}
 }
+
+func TestProject_ToMessageMetadata(t *testing.T) {
+	budget := 1250000.0
+	p := &Project{
+		Title:          "某部仓储建设公告",
+		Pageurl:        "http://www.baidu.com/1",
+		NoticeTime:     "2024-05-20 10:00:00",
+		Keyword:        "仓储",
+		Content:        "content",
+		RegionName:     "北京市",
+		Budget:         &budget,
+		ExpireTime:     time.Date(2024, 6, 1, 9, 30, 0, 0, cst),
+		AgentName:      "某代理公司",
+		AgentLinkPhone: "010-12345678",
+	}
+	msg := p.ToMessage()
+	for _, want := range []string{"地区: 北京市\n", "预算: 125万元\n", "截止时间: 2024-06-01 09:30\n", "代理机构: 某代理公司 010-12345678\n"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in message:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "开标时间") {
+		t.Errorf("expected no open tender time in message:\n%s", msg)
+	}
+
+	plain := &Project{Title: "t", Pageurl: "http://www.baidu.com/2", Keyword: "k", Content: "content"}
+	if got, want := plain.ToMessage(), "<a href=\"http://www.baidu.com/2\">t</a> @ \n<b>[k]</b>\n\ncontent "; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestParseNoticeMetadata(t *testing.T) {
+	if got := parseAnyTime(float64(1716170400000)); !got.Equal(time.UnixMilli(1716170400000)) {
+		t.Errorf("unexpected time from millis: %s", got)
+	}
+	if got := parseAnyTime("2024-05-20 10:00"); !got.Equal(time.Date(2024, 5, 20, 10, 0, 0, 0, cst)) {
+		t.Errorf("unexpected time from string: %s", got)
+	}
+	if got := parseAnyTime(nil); !got.IsZero() {
+		t.Errorf("expected zero time, got %s", got)
+	}
+	for v, want := range map[any]float64{float64(1200): 1200, "1,200,000.00元": 1200000} {
+		if got := parseAmount(v); got == nil || *got != want {
+			t.Errorf("parseAmount(%v) = %v, expected %v", v, got, want)
+		}
+	}
+	for _, v := range []any{nil, "", "面议", float64(0)} {
+		if got := parseAmount(v); got != nil {
+			t.Errorf("parseAmount(%v) = %v, expected nil", v, *got)
+		}
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	valid, invalid := validateRules([]string{" (服务器 | 存储) -维保", "", "(服务器", "a | budget>1"})
+	if len(valid) != 1 || valid[0] != "(服务器 | 存储) -维保" {
+		t.Errorf("valid = %v", valid)
+	}
+	if len(invalid) != 2 || !strings.HasPrefix(invalid[0], "(服务器: ") {
+		t.Errorf("invalid = %v", invalid)
+	}
+}
+
+func TestValidateKeyword(t *testing.T) {
+	tests := []struct {
+		t       model.KeywordType
+		keyword string
+		want    string
+		wantErr bool
+	}{
+		{model.PROJECT, " 服务器 | 存储 ", "服务器 | 存储", false},
+		{model.PROJECT, "(服务器", "", true},
+		{model.ALARM, " (某公司 ", "(某公司", false},
+		{model.FILTER, " REGIONCODE = 110000 ", "regionCode=110000", false},
+		{model.FILTER, "colour=red", "", true},
+		{model.FILTER, "北京", "", true},
+	}
+	for _, tt := range tests {
+		got, err := validateKeyword(tt.t, tt.keyword)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("validateKeyword(%d, %q) = %q, %v", tt.t, tt.keyword, got, err)
+		}
+	}
+}
+
+func TestProject_Notice(t *testing.T) {
+	budget := 1.5e6
+	crawledAt := time.Date(2024, 5, 2, 9, 0, 0, 0, cst)
+	p := &Project{
+		PublishedAt:    time.Date(2024, 5, 1, 10, 30, 0, 0, cst),
+		OpenTenderCode: "ZB-2024-001",
+		ShortTitle:     "服务器采购",
+		Content:        "<p>GPU <b>服务器</b></p>",
+		Pageurl:        "https://example.com/1",
+		Source:         "北京",
+		Budget:         &budget,
+		ExpireTime:     time.Date(2024, 5, 20, 9, 0, 0, 0, cst),
+	}
+	n := p.Notice(crawledAt)
+	if n.Content != "GPU 服务器" || n.OpenTenderTime != nil || !n.ExpireTime.Equal(p.ExpireTime) {
+		t.Errorf("unexpected notice %+v", n)
+	}
+	got := projectFromNotice(n)
+	if got.ShortTitle != p.ShortTitle || got.OpenTenderCode != p.OpenTenderCode || got.Pageurl != p.Pageurl ||
+		*got.Budget != budget || !got.Deadline().Equal(p.ExpireTime) || got.NoticeTime != "2024-05-01 10:30:00" {
+		t.Errorf("unexpected project %+v", got)
+	}
+	if n := (&Project{Pageurl: "u"}).Notice(crawledAt); !n.PublishedAt.Equal(crawledAt) {
+		t.Errorf("expected an unknown publish time to fall back to the crawl time, got %s", n.PublishedAt)
+	}
+}
+
+func TestTestedKeyword(t *testing.T) {
+	cr, err := rule.ParseComplexRule(&model.Keyword{Keyword: "(服务器 | 存储) -维保 budget<1万"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := testHeader + cr.ToString() + "\n3 notices matched in the last 30 days\n\n1. ..."
+	got, err := testedKeyword(text)
+	if err != nil || got.ToString() != cr.ToString() {
+		t.Errorf("testedKeyword() = %v, %v, want %q", got, err, cr.ToString())
+	}
+	if _, err := testedKeyword("No matching history found."); err == nil {
+		t.Error("expected an error for another reply")
+	}
+}
+
+func TestTestKeyword(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test_keyword.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.AutoMigrate(&model.Notice{}, &model.Keyword{}, &model.KeywordHit{})
+	dal.SetDefault(db)
+
+	now := time.Now()
+	notice := func(url, title string, age time.Duration) *model.Notice {
+		return &model.Notice{URL: url, Title: title, PublishedAt: now.Add(-age), CrawledAt: now}
+	}
+	if err := dal.Notice.Save([]*model.Notice{
+		notice("a", "服务器采购", time.Hour), notice("b", "服务器维保", 2*time.Hour),
+		notice("c", "打印机采购", time.Hour), notice("d", "服务器采购", 40*24*time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	dal.Keyword.Insert([]string{"服务器"}, 7777, model.PROJECT)
+	kw := dal.Keyword.GetByUserIdAndType(7777, model.PROJECT)[0]
+	cr, err := rule.ParseComplexRule(kw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := testBotContext("")
+	ctx.Config.NoticeDays, ctx.Store = 30, NewStore()
+	c := &CommandsHandler{ctx: ctx}
+	urls := func(fresh bool) string {
+		matched, err := c.testKeyword(7777, cr, fresh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, p := range matched {
+			got = append(got, p.Pageurl)
+		}
+		return strings.Join(got, ",")
+	}
+	if got := urls(true); got != "a,b" {
+		t.Errorf("expected the recent notices matched newest first, got %s", got)
+	}
+	if n, _ := dal.KeywordHit.Count(); n != 0 {
+		t.Errorf("expected no hits recorded, got %d", n)
+	}
+	if kw, _ := dal.Keyword.GetByNumber(7777, kw.Number); kw.Counter != 0 {
+		t.Errorf("expected the counter untouched, got %d", kw.Counter)
+	}
+
+	// The pages of a reply reuse its matches, a new reply sees new notices.
+	if err := dal.Notice.Save([]*model.Notice{notice("e", "服务器扩容", 0)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := urls(false); got != "a,b" {
+		t.Errorf("expected the kept matches, got %s", got)
+	}
+	if got := urls(true); got != "e,a,b" {
+		t.Errorf("expected the new notice matched, got %s", got)
+	}
+}
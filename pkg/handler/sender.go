@@ -0,0 +1,236 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// senderRate is how many messages a second the bot sends at most to all
+	// chats together.
+	senderRate = 30
+	// privateChatInterval and groupChatInterval space the messages to one
+	// chat: one a second in a private chat, 20 a minute in a group or a
+	// channel.
+	privateChatInterval = time.Second
+	groupChatInterval   = 3 * time.Second
+	// senderMaxRetryWait is the longest retry_after a message waits for
+	// before it is sent again, on longer ones the 429 is left to the caller,
+	// e.g. the outbox.
+	senderMaxRetryWait = 10 * time.Second
+	// senderMaxChats is the number of chats kept before the idle ones are
+	// forgotten.
+	senderMaxChats = 1000
+)
+
+// Sender is the HTTP client of the bot. Every API call goes through it, so
+// the pushes and the command replies share the limits Telegram puts on
+// sending messages: globally and per chat. After a 429 response the chat is
+// held back for the retry_after Telegram asked for, and the message is sent
+// once more if that is short.
+type Sender struct {
+	client   bot.HttpClient
+	global   *rate.Limiter
+	private  time.Duration
+	group    time.Duration
+	maxRetry time.Duration
+
+	mu    sync.Mutex
+	chats map[string]*chatLimit
+}
+
+// chatLimit spaces the messages to one chat.
+type chatLimit struct {
+	limiter     *rate.Limiter
+	pausedUntil time.Time
+}
+
+// NewSender returns a Sender doing the requests with client.
+func NewSender(client bot.HttpClient) *Sender {
+	return newSender(client, senderRate, privateChatInterval, groupChatInterval, senderMaxRetryWait)
+}
+
+func newSender(client bot.HttpClient, global rate.Limit, private, group, maxRetry time.Duration) *Sender {
+	return &Sender{
+		client:   client,
+		global:   rate.NewLimiter(global, 1),
+		private:  private,
+		group:    group,
+		maxRetry: maxRetry,
+		chats:    make(map[string]*chatLimit),
+	}
+}
+
+// sendsMessage reports whether the API method sends a message to a chat.
+func sendsMessage(method string) bool {
+	return strings.HasPrefix(method, "send") || strings.HasPrefix(method, "copyMessage") ||
+		strings.HasPrefix(method, "forwardMessage")
+}
+
+// Do implements bot.HttpClient.
+func (s *Sender) Do(req *http.Request) (*http.Response, error) {
+	if !sendsMessage(path.Base(req.URL.Path)) {
+		return s.client.Do(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	chatId := formValue(req.Header.Get("Content-Type"), body, "chat_id")
+	for retried := false; ; retried = true {
+		if wait := s.paused(chatId); wait > s.maxRetry {
+			return throttled(req, wait), nil
+		} else if wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-req.Context().Done():
+				return nil, req.Context().Err()
+			}
+		}
+		if err := s.wait(req.Context(), chatId); err != nil {
+			return nil, err
+		}
+
+		r := req.Clone(req.Context())
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		resp, err := s.client.Do(r)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+			return resp, err
+		}
+		data, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		wait := retryAfterOf(data)
+		s.pause(chatId, wait)
+		if retried || wait > s.maxRetry {
+			return resp, nil
+		}
+	}
+}
+
+// chat returns the limit of chatId, a negative id or an @name is a group or
+// a channel.
+func (s *Sender) chat(chatId string) *chatLimit {
+	if l, ok := s.chats[chatId]; ok {
+		return l
+	}
+	if len(s.chats) >= senderMaxChats {
+		now := time.Now()
+		for k, l := range s.chats {
+			if l.pausedUntil.Before(now) && l.limiter.TokensAt(now) >= 1 {
+				delete(s.chats, k)
+			}
+		}
+	}
+	interval := s.private
+	if strings.HasPrefix(chatId, "-") || strings.HasPrefix(chatId, "@") {
+		interval = s.group
+	}
+	l := &chatLimit{limiter: rate.NewLimiter(rate.Every(interval), 1)}
+	s.chats[chatId] = l
+	return l
+}
+
+// paused returns how long chatId is still held back after a 429.
+func (s *Sender) paused(chatId string) time.Duration {
+	if chatId == "" {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Until(s.chat(chatId).pausedUntil)
+}
+
+func (s *Sender) pause(chatId string, wait time.Duration) {
+	if chatId == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if until := time.Now().Add(wait); until.After(s.chat(chatId).pausedUntil) {
+		s.chat(chatId).pausedUntil = until
+	}
+}
+
+// wait blocks until a message may be sent to chatId.
+func (s *Sender) wait(ctx context.Context, chatId string) error {
+	if chatId != "" {
+		s.mu.Lock()
+		limiter := s.chat(chatId).limiter
+		s.mu.Unlock()
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return s.global.Wait(ctx)
+}
+
+// formValue returns the field name of a multipart/form-data body.
+func formValue(contentType string, body []byte, name string) string {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	mr := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			return ""
+		}
+		if part.FormName() == name {
+			value, _ := io.ReadAll(part)
+			return string(value)
+		}
+	}
+}
+
+// retryAfterOf returns the retry_after of a 429 response, a second when it
+// has none.
+func retryAfterOf(data []byte) time.Duration {
+	var r struct {
+		Parameters struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil || r.Parameters.RetryAfter <= 0 {
+		return time.Second
+	}
+	return time.Duration(r.Parameters.RetryAfter) * time.Second
+}
+
+// throttled answers req for a chat held back for wait, the same way
+// Telegram would, without sending it.
+func throttled(req *http.Request, wait time.Duration) *http.Response {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	body := fmt.Sprintf(`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after %d","parameters":{"retry_after":%d}}`,
+		seconds, seconds)
+	return &http.Response{
+		Status:        "429 Too Many Requests",
+		StatusCode:    http.StatusTooManyRequests,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-telegram/bot"
+	"golang.org/x/time/rate"
+)
+
+// senderServer answers sendMessage with the queued 429 responses, then with
+// success, and counts the calls per method.
+type senderServer struct {
+	mu      sync.Mutex
+	limited []string
+	calls   map[string]int
+}
+
+func (s *senderServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	method := path.Base(r.URL.Path)
+	s.calls[method]++
+	if method == "sendMessage" && len(s.limited) > 0 {
+		resp := s.limited[0]
+		s.limited = s.limited[1:]
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(resp))
+		return
+	}
+	_, _ = w.Write([]byte(`{"ok":true,"result":{"message_id":1,"chat":{"id":1}}}`))
+}
+
+func (s *senderServer) count(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+func testSender(t *testing.T, maxRetry time.Duration, limited ...string) (*bot.Bot, *senderServer) {
+	s := &senderServer{limited: limited, calls: make(map[string]int)}
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	sender := newSender(http.DefaultClient, rate.Inf, 100*time.Millisecond, 300*time.Millisecond, maxRetry)
+	b, err := bot.New("1:token", bot.WithSkipGetMe(), bot.WithServerURL(ts.URL),
+		bot.WithHTTPClient(time.Second, sender))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, s
+}
+
+func TestSenderSpacesMessages(t *testing.T) {
+	b, s := testSender(t, time.Second)
+	ctx := context.Background()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, chatId := range []int64{1, 1, 1, 2, -100} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := b.SendMessage(ctx, &bot.SendMessageParams{ChatID: chatId, Text: "x"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond || elapsed > time.Second {
+		t.Errorf("three messages to a private chat took %s, want about 200ms", elapsed)
+	}
+	if n := s.count("sendMessage"); n != 5 {
+		t.Errorf("sent %d messages, want 5", n)
+	}
+
+	// A group waits longer between messages.
+	start = time.Now()
+	for range 2 {
+		if _, err := b.SendMessage(ctx, &bot.SendMessageParams{ChatID: -200, Text: "x"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("two messages to a group took %s, want about 300ms", elapsed)
+	}
+}
+
+func TestSenderRetryAfter(t *testing.T) {
+	short := `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 1","parameters":{"retry_after":1}}`
+	b, s := testSender(t, 2*time.Second, short)
+	ctx := context.Background()
+	start := time.Now()
+	if _, err := b.SendMessage(ctx, &bot.SendMessageParams{ChatID: 1, Text: "x"}); err != nil {
+		t.Fatalf("expected a short retry_after to be waited out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %s, want 1s", elapsed)
+	}
+	if n := s.count("sendMessage"); n != 2 {
+		t.Errorf("got %d calls, want 2", n)
+	}
+
+	// A retry_after longer than the sender waits is left to the caller, the
+	// chat stays held back without asking Telegram again.
+	long := `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 30","parameters":{"retry_after":30}}`
+	s.mu.Lock()
+	s.limited = []string{long}
+	s.mu.Unlock()
+	for range 2 {
+		_, err := b.SendMessage(ctx, &bot.SendMessageParams{ChatID: 1, Text: "x"})
+		var tooMany *bot.TooManyRequestsError
+		if !errors.As(err, &tooMany) || tooMany.RetryAfter < 29 || tooMany.RetryAfter > 30 {
+			t.Errorf("expected retry_after 30, got %v", err)
+		}
+	}
+	if n := s.count("sendMessage"); n != 3 {
+		t.Errorf("got %d calls, want 3", n)
+	}
+
+	// Other chats and other methods are not held back.
+	if _, err := b.SendMessage(ctx, &bot.SendMessageParams{ChatID: 2, Text: "x"}); err != nil {
+		t.Error(err)
+	}
+	if _, err := b.GetMe(ctx); err != nil {
+		t.Error(err)
+	}
+	if n := s.count("getMe"); n != 1 {
+		t.Errorf("got %d getMe calls, want 1", n)
+	}
+}
@@ -12,11 +12,19 @@ const TableNameHistory = "histories"
 
 // History mapped from table <histories>
 type History struct {
-	UserID        int64     `gorm:"column:user_id;primaryKey;autoIncrement:false" json:"userId"`
-	URL           string    `gorm:"column:url;primaryKey;not null" json:"url"`
-	UpdatedAt     time.Time `gorm:"column:updated_at;not null" json:"updatedAt"`
-	Title         string    `gorm:"column:title;not null" json:"title"`
-	HasTenderCode int32     `gorm:"column:has_tender_code;not null;default:0" json:"hasTenderCode"`
+	UserID         int64      `gorm:"column:user_id;primaryKey;autoIncrement:false" json:"userId"`
+	URL            string     `gorm:"column:url;primaryKey;not null" json:"url"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at;not null" json:"updatedAt"`
+	Title          string     `gorm:"column:title;not null" json:"title"`
+	HasTenderCode  int32      `gorm:"column:has_tender_code;not null;default:0" json:"hasTenderCode"`
+	Source         string     `gorm:"column:source;not null;default:''" json:"source"`
+	RegionName     string     `gorm:"column:region_name;not null;default:''" json:"regionName"`
+	Budget         *float64   `gorm:"column:budget" json:"budget"`
+	OpenTenderTime *time.Time `gorm:"column:open_tender_time" json:"openTenderTime"`
+	ExpireTime     *time.Time `gorm:"column:expire_time" json:"expireTime"`
+	AgentName      string     `gorm:"column:agent_name;not null;default:''" json:"agentName"`
+	AgentLinkMan   string     `gorm:"column:agent_link_man;not null;default:''" json:"agentLinkMan"`
+	AgentLinkPhone string     `gorm:"column:agent_link_phone;not null;default:''" json:"agentLinkPhone"`
 }
 
 // TableName History's table name
@@ -5,11 +5,13 @@ type KeywordType int
 const (
 	PROJECT KeywordType = iota
 	ALARM
+	// FILTER rows hold a "key=value" notice filter instead of a keyword.
+	FILTER
 )
 
 func (k KeywordType) String() string {
-	names := [...]string{"PROJECT", "ALARM"}
-	if k < PROJECT || k > ALARM {
+	names := [...]string{"PROJECT", "ALARM", "FILTER"}
+	if k < PROJECT || k > FILTER {
 		return "Unknown"
 	}
 	return names[k]
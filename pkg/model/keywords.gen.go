@@ -19,9 +19,12 @@ type Keyword struct {
 	UpdatedAt *time.Time     `gorm:"column:updated_at" json:"updatedAt"`
 	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at;index:idx_keywords_deleted_at,priority:1" json:"deletedAt"`
 	Keyword   string         `gorm:"column:keyword;not null" json:"keyword"`
-	UserID    int64          `gorm:"column:user_id;not null" json:"userId"`
+	UserID    int64          `gorm:"column:user_id;not null;uniqueIndex:idx_keywords_user_id_number,priority:1" json:"userId"`
 	Type      int32          `gorm:"column:type;not null" json:"type"`
 	Counter   int32          `gorm:"column:counter;not null" json:"counter"`
+	GroupID   *int32         `gorm:"column:group_id" json:"groupId"`
+	Muted     int32          `gorm:"column:muted;not null;default:0" json:"muted"`
+	Number    int32          `gorm:"column:number;not null;default:0;uniqueIndex:idx_keywords_user_id_number,priority:2" json:"number"`
 }
 
 // TableName Keyword's table name
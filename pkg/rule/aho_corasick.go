@@ -0,0 +1,88 @@
+package rule
+
+// ahoCorasick finds every occurrence of a set of patterns in a single pass
+// over a text. It works on bytes, which is exact for UTF-8 as no encoded
+// character is a substring of another. The automaton is built as a full DFA
+// over the byte classes occurring in the patterns, so scanning takes one
+// table lookup per byte.
+type ahoCorasick struct {
+	// classes maps a byte to its class, 0 for bytes that occur in no
+	// pattern and always lead back to the root.
+	classes [256]int32
+	width   int32
+	// next holds the transitions, the state after reading class c in state
+	// s is next[s*width+c].
+	next []int32
+	// out lists the patterns ending in each state, including the ones
+	// reached through fail links.
+	out [][]int32
+}
+
+func newAhoCorasick(patterns []string) *ahoCorasick {
+	ac := &ahoCorasick{width: 1}
+	for _, p := range patterns {
+		for i := 0; i < len(p); i++ {
+			if ac.classes[p[i]] == 0 {
+				ac.classes[p[i]] = ac.width
+				ac.width++
+			}
+		}
+	}
+	ac.addState()
+	for id, p := range patterns {
+		s := int32(0)
+		for i := 0; i < len(p); i++ {
+			edge := s*ac.width + ac.classes[p[i]]
+			if ac.next[edge] == 0 {
+				// addState grows the table, index it only afterwards.
+				t := ac.addState()
+				ac.next[edge] = t
+			}
+			s = ac.next[edge]
+		}
+		ac.out[s] = append(ac.out[s], int32(id))
+	}
+
+	// Breadth first, so the fail target of a state is complete before the
+	// state itself. A missing transition takes the one of the fail target.
+	fail := make([]int32, len(ac.out))
+	var queue []int32
+	for c := int32(1); c < ac.width; c++ {
+		if t := ac.next[c]; t != 0 {
+			queue = append(queue, t)
+		}
+	}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for c := int32(1); c < ac.width; c++ {
+			t := ac.next[s*ac.width+c]
+			f := ac.next[fail[s]*ac.width+c]
+			if t == 0 {
+				ac.next[s*ac.width+c] = f
+				continue
+			}
+			fail[t] = f
+			ac.out[t] = append(ac.out[t], ac.out[f]...)
+			queue = append(queue, t)
+		}
+	}
+	return ac
+}
+
+func (ac *ahoCorasick) addState() int32 {
+	ac.next = append(ac.next, make([]int32, ac.width)...)
+	ac.out = append(ac.out, nil)
+	return int32(len(ac.out) - 1)
+}
+
+// scan marks found[id] for every pattern occurring in s.
+func (ac *ahoCorasick) scan(s string, found []bool) {
+	state := int32(0)
+	for i := 0; i < len(s); i++ {
+		state = ac.next[state*ac.width+ac.classes[s[i]]]
+		for _, id := range ac.out[state] {
+			found[id] = true
+		}
+	}
+}
@@ -1,8 +1,9 @@
 package rule
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -16,7 +17,18 @@ import (
 type ComplexRule struct {
 	IncludeTerms map[string]struct{}
 	ExcludeTerms map[string]struct{}
-	Rule         *model.Keyword
+	// IncludeTerms and ExcludeTerms hold every unscoped term of the rule,
+	// ScopedTerms the terms restricted to a notice field. Matching evaluates
+	// the parsed expression, see MatchDocument.
+	ScopedTerms []ScopedTerm
+	// Conditions must all hold for MatchTarget, see Condition.
+	Conditions []Condition
+	// Options select how terms are normalized and compared, see Options.
+	Options Options
+	Rule    *model.Keyword
+	// expr is the parsed keyword without its conditions, nil when the
+	// keyword has no terms. The term sets above are derived from it.
+	expr node
 }
 
 type ComplexRules []*ComplexRule
@@ -83,11 +95,24 @@ func SortComplexRules(rules []*ComplexRule) {
 // Use sync.Pool to reuse slices during marshaling and unmarshaling
 var slicePool = sync.Pool{
 	New: func() interface{} {
-		return make([]string, 0, 30)
+		s := make([]string, 0, 30)
+		return &s
 	},
 }
 
-func (cr *ComplexRule) MarshalJSON() ([]byte, error) {
+// ruleDocument is the portable form of a rule: the keyword as written, which
+// is all that is needed to restore the rule, plus its canonical form and
+// terms for the reader.
+type ruleDocument struct {
+	Keyword      string   `json:"keyword" yaml:"keyword"`
+	Canonical    string   `json:"canonical,omitempty" yaml:"canonical,omitempty"`
+	IncludeTerms []string `json:"includeTerms" yaml:"includeTerms,omitempty,flow"`
+	ExcludeTerms []string `json:"excludeTerms" yaml:"excludeTerms,omitempty,flow"`
+}
+
+// marshal passes the document of cr to fn, its term slices are only valid
+// during the call.
+func (cr *ComplexRule) marshal(fn func(doc ruleDocument) error) error {
 	include := keysToSlice(cr.IncludeTerms)
 	exclude := keysToSlice(cr.ExcludeTerms)
 	// Put the exact same slices back that were used for marshaling, otherwise
@@ -96,76 +121,122 @@ func (cr *ComplexRule) MarshalJSON() ([]byte, error) {
 	defer slicePool.Put(include)
 	defer slicePool.Put(exclude)
 
-	temp := struct {
-		IncludeTerms []string `json:"includeTerms"`
-		ExcludeTerms []string `json:"excludeTerms"`
-	}{
+	doc := ruleDocument{
+		Canonical:    cr.ToString(),
 		IncludeTerms: *include,
 		ExcludeTerms: *exclude,
 	}
+	if cr.Rule != nil {
+		doc.Keyword = cr.Rule.Keyword
+	} else {
+		doc.Keyword = doc.Canonical
+	}
+	return fn(doc)
+}
 
-	return json.Marshal(temp)
+// unmarshal restores cr from doc by parsing its keyword. Documents written
+// before the keyword was kept only have the plain terms, the rule is rebuilt
+// from them then.
+func (cr *ComplexRule) unmarshal(doc ruleDocument) error {
+	keyword := doc.Keyword
+	if keyword == "" {
+		var terms []string
+		for _, t := range doc.IncludeTerms {
+			terms = append(terms, "+"+legacyTerm(t))
+		}
+		for _, t := range doc.ExcludeTerms {
+			terms = append(terms, "-"+legacyTerm(t))
+		}
+		keyword = strings.Join(terms, " ")
+	}
+	rule, err := ParseComplexRule(&model.Keyword{Keyword: keyword})
+	if err != nil {
+		return err
+	}
+	*cr = *rule
+	return nil
+}
+
+// legacyTerm writes a term of the term sets so it parses back as the same
+// term, regex terms are kept as they are.
+func legacyTerm(t string) string {
+	if strings.HasPrefix(t, regexPrefix) {
+		return t
+	}
+	return quoteTerm(t)
+}
+
+func (cr *ComplexRule) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	err := cr.marshal(func(doc ruleDocument) error {
+		// Keep conditions such as budget>=50万 readable.
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		return enc.Encode(doc)
+	})
+	return bytes.TrimSpace(buf.Bytes()), err
 }
 
 func (cr *ComplexRule) UnmarshalJSON(data []byte) error {
-	var temp struct {
-		IncludeTerms []string `json:"includeTerms"`
-		ExcludeTerms []string `json:"excludeTerms"`
+	var doc ruleDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
 	}
-	if err := json.Unmarshal(data, &temp); err != nil {
+	return cr.unmarshal(doc)
+}
+
+// MarshalYAML implements yaml.Marshaler with the fields of MarshalJSON.
+func (cr *ComplexRule) MarshalYAML() (interface{}, error) {
+	var doc ruleDocument
+	err := cr.marshal(func(d ruleDocument) error {
+		// The pooled term slices are reused after marshal returns.
+		doc = d
+		doc.IncludeTerms = slices.Clone(d.IncludeTerms)
+		doc.ExcludeTerms = slices.Clone(d.ExcludeTerms)
+		return nil
+	})
+	return doc, err
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler with the fields of
+// UnmarshalJSON.
+func (cr *ComplexRule) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var doc ruleDocument
+	if err := unmarshal(&doc); err != nil {
 		return err
 	}
-	cr.IncludeTerms = sliceToMap(&temp.IncludeTerms)
-	cr.ExcludeTerms = sliceToMap(&temp.ExcludeTerms)
-	return nil
+	return cr.unmarshal(doc)
 }
 
-// IsMatch checks if the given data matches the rule.
-//
-// It first checks if any of the exclude terms are present in the data. If so,
-// it returns false. Then it checks if all the include terms are present in
-// the data. If any of them are not, it returns false. If both checks pass, it
-// returns true.
+// IsMatch checks if the given data matches the expression of the rule,
+// reading data as the title of a notice.
 func (cr *ComplexRule) IsMatch(data string) bool {
-	data = normalizeString(data)
-	for term := range cr.ExcludeTerms {
-		if strings.Contains(data, term) {
-			return false
-		}
-	}
+	ok, _ := cr.MatchDocument(NewDocument(data, "", ""))
+	return ok
+}
 
-	for term := range cr.IncludeTerms {
-		if !strings.Contains(data, term) {
+// MatchTarget checks the conditions of the rule against the notice
+// attributes in t. A rule without conditions matches every target.
+func (cr *ComplexRule) MatchTarget(t Target) bool {
+	for _, c := range cr.Conditions {
+		if !c.Match(t) {
 			return false
 		}
 	}
-
 	return true
 }
 
+// ToString renders the rule in its canonical form: "+include -exclude"
+// terms, groups as "+(a | b)", then the conditions and options.
 func (cr *ComplexRule) ToString() string {
 	var parts []string
-
-	// Handle include terms
-	var includeTerms []string
-	for term := range cr.IncludeTerms {
-		includeTerms = append(includeTerms, term)
-	}
-	sort.Strings(includeTerms)
-	for _, term := range includeTerms {
-		parts = append(parts, fmt.Sprintf("+%s", term))
-	}
-
-	// Handle exclude terms
-	var excludeTerms []string
-	for term := range cr.ExcludeTerms {
-		excludeTerms = append(excludeTerms, term)
+	if cr.expr != nil {
+		parts = append(parts, format(cr.expr, true))
 	}
-	sort.Strings(excludeTerms)
-	for _, term := range excludeTerms {
-		parts = append(parts, fmt.Sprintf("-%s", term))
+	for _, c := range cr.Conditions {
+		parts = append(parts, c.String())
 	}
-
+	parts = append(parts, cr.Options.terms()...)
 	return strings.Join(parts, " ")
 }
 
@@ -175,66 +246,80 @@ func keysToSlice(m map[string]struct{}) *[]string {
 	for k := range m {
 		*slice = append(*slice, k)
 	}
+	sort.Strings(*slice)
 	return slice
 }
 
-func sliceToMap(s *[]string) map[string]struct{} {
-	m := make(map[string]struct{}, len(*s)) // Pre-allocate map with known size
-	for _, v := range *s {
-		m[v] = struct{}{}
+// NewComplexRule parses the keyword of k, it returns nil when the keyword is
+// malformed. Use ParseComplexRule to get the reason.
+func NewComplexRule(k *model.Keyword) *ComplexRule {
+	rule, err := ParseComplexRule(k)
+	if err != nil {
+		return nil
 	}
-	return m
+	return rule
 }
 
-func NewComplexRule(k *model.Keyword) *ComplexRule {
+// ParseComplexRule parses the keyword of k into a rule, see parser.go for
+// the grammar.
+func ParseComplexRule(k *model.Keyword) (*ComplexRule, error) {
+	expr, err := parse(k.Keyword)
+	if err != nil {
+		return nil, err
+	}
+	expr, modifiers, err := extractConditions(expr)
+	if err != nil {
+		return nil, err
+	}
 	rule := &ComplexRule{
 		IncludeTerms: make(map[string]struct{}),
 		ExcludeTerms: make(map[string]struct{}),
 		Rule:         k,
+		expr:         expr,
 	}
-
-	// Split the input string, but keep quoted parts together
-	terms := splitPreservingQuotes(rule.Rule.Keyword)
-	for _, term := range terms {
-		term = normalizeString(term)
-		if term == "" {
-			continue
-		}
-		if strings.HasPrefix(term, "-") {
-			rule.ExcludeTerms[strings.TrimPrefix(term, "-")] = struct{}{}
-		} else {
-			// Remove '+' if present, otherwise keep the term as is
-			rule.IncludeTerms[strings.TrimPrefix(term, "+")] = struct{}{}
+	for _, m := range modifiers {
+		if m.option == "" {
+			rule.Conditions = append(rule.Conditions, m.cond)
+		} else if _, err := rule.Options.parseOption(m.option); err != nil {
+			return nil, &ParseError{Msg: err.Error()}
 		}
 	}
-
-	return rule
+	if err := applyOptions(expr, rule.Options); err != nil {
+		return nil, &ParseError{Msg: err.Error()}
+	}
+	rule.collectTerms(expr, false)
+	return rule, nil
 }
 
-// splitPreservingQuotes splits a string by whitespace but preserves quoted parts
-func splitPreservingQuotes(s string) []string {
-	var result []string
-	var current strings.Builder
-	inQuotes := false
-
-	for _, r := range s {
-		if unicode.IsSpace(r) && !inQuotes {
-			if current.Len() > 0 {
-				result = append(result, current.String())
-				current.Reset()
-			}
-		} else if r == '"' {
-			inQuotes = !inQuotes
-		} else {
-			current.WriteRune(r)
+// collectTerms fills IncludeTerms, ExcludeTerms and ScopedTerms with every
+// term of the expression, by whether it appears negated.
+func (cr *ComplexRule) collectTerms(n node, negated bool) {
+	switch v := n.(type) {
+	case *termNode:
+		// Patterns are kept in their written form, "re:/.../" or "a*b".
+		term := v.text
+		if v.kind == termRegex {
+			term = v.term()
+		}
+		switch {
+		case v.scope != "":
+			cr.ScopedTerms = append(cr.ScopedTerms, ScopedTerm{Scope: v.scope, Term: term, Exclude: negated})
+		case negated:
+			cr.ExcludeTerms[term] = struct{}{}
+		default:
+			cr.IncludeTerms[term] = struct{}{}
+		}
+	case *notNode:
+		cr.collectTerms(v.child, !negated)
+	case *andNode:
+		for _, c := range v.children {
+			cr.collectTerms(c, negated)
+		}
+	case *orNode:
+		for _, c := range v.children {
+			cr.collectTerms(c, negated)
 		}
 	}
-
-	if current.Len() > 0 {
-		result = append(result, current.String())
-	}
-
-	return result
 }
 
 // normalizeString removes all types of spaces from a string
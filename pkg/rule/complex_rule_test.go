@@ -1,6 +1,7 @@
 package rule
 
 import (
+	"encoding/json"
 	"reflect"
 	"testing"
 
@@ -493,3 +494,37 @@ func TestSortComplexRules(t *testing.T) {
 		})
 	}
 }
+
+func TestComplexRule_JSON(t *testing.T) {
+	cr := NewComplexRule(&model.Keyword{Keyword: "(服务器 | 存储) -维保 budget>=50万 opt:trad"})
+	data, err := cr.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"keyword":"(服务器 | 存储) -维保 budget>=50万 opt:trad",` +
+		`"canonical":"-维保 +(服务器 | 存储) budget>=50万 opt:trad",` +
+		`"includeTerms":["存储","服务器"],"excludeTerms":["维保"]}`
+	if string(data) != want {
+		t.Errorf("MarshalJSON() = %s, want %s", data, want)
+	}
+
+	var restored ComplexRule
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored.ToString() != cr.ToString() || !restored.IsMatch("某单位伺服器采购") || restored.IsMatch("服务器维保") {
+		t.Errorf("UnmarshalJSON() restored %q", restored.ToString())
+	}
+
+	// Documents without the keyword are rebuilt from the terms.
+	legacy := `{"includeTerms":["GPU 服务器","re:/DL\\d+/"],"excludeTerms":["维保"]}`
+	if err := json.Unmarshal([]byte(legacy), &restored); err != nil {
+		t.Fatal(err)
+	}
+	if got := restored.ToString(); got != `+GPU服务器 +re:/DL\d+/ -维保` {
+		t.Errorf("UnmarshalJSON(legacy) restored %q", got)
+	}
+	if err := json.Unmarshal([]byte(`{"keyword":"budget>abc"}`), &restored); err == nil {
+		t.Error("expected a malformed keyword to fail")
+	}
+}
@@ -0,0 +1,137 @@
+package rule
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Condition fields.
+const (
+	FieldBudget   = "budget"
+	FieldDeadline = "deadline"
+)
+
+var conditionRegex = regexp.MustCompile(`^(?i)(budget|deadline)(<=|>=|<|>|=)(.+)$`)
+
+// Target holds the notice attributes conditions are evaluated against. A nil
+// Budget or a zero Deadline is unknown.
+type Target struct {
+	Budget   *float64
+	Deadline time.Time
+	// Now is the reference time of deadline conditions, time.Now() when zero.
+	Now time.Time
+}
+
+// Condition is a numeric predicate of a rule such as "budget>=500000" or
+// "deadline<7d". A budget is compared in yuan, a deadline as the time left
+// until it.
+type Condition struct {
+	Field string
+	Op    string
+	// Value is the amount in yuan for budget and the duration for deadline.
+	Value float64
+	raw   string
+}
+
+// parseCondition parses a condition term, ok is false when term is not a
+// condition at all. A term that looks like a condition but has an invalid
+// value returns an error.
+func parseCondition(term string) (c Condition, ok bool, err error) {
+	m := conditionRegex.FindStringSubmatch(term)
+	if m == nil {
+		return Condition{}, false, nil
+	}
+	c = Condition{Field: strings.ToLower(m[1]), Op: m[2], raw: m[3]}
+	switch c.Field {
+	case FieldBudget:
+		c.Value, err = parseAmount(m[3])
+	case FieldDeadline:
+		var d time.Duration
+		d, err = parseDays(m[3])
+		c.Value = float64(d)
+	}
+	if err != nil {
+		return Condition{}, true, fmt.Errorf("invalid condition %q: %w", term, err)
+	}
+	return c, true, nil
+}
+
+// parseAmount parses "500000", "2e6" or "50万".
+func parseAmount(s string) (float64, error) {
+	scale := 1.0
+	if v, ok := strings.CutSuffix(s, "万"); ok {
+		s, scale = v, 10000
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return f * scale, nil
+}
+
+// parseDays parses a duration given in days ("7d"), hours ("12h") or weeks
+// ("2w").
+func parseDays(s string) (time.Duration, error) {
+	if len(s) < 2 {
+		return 0, fmt.Errorf("expected a number followed by d, h or w")
+	}
+	n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+	if err != nil {
+		return 0, err
+	}
+	switch strings.ToLower(s[len(s)-1:]) {
+	case "h":
+		return time.Duration(n * float64(time.Hour)), nil
+	case "d":
+		return time.Duration(n * 24 * float64(time.Hour)), nil
+	case "w":
+		return time.Duration(n * 7 * 24 * float64(time.Hour)), nil
+	}
+	return 0, fmt.Errorf("expected a number followed by d, h or w")
+}
+
+// Match evaluates the condition against t. An unknown value passes, so a
+// notice is never dropped for data the portal did not publish. A deadline
+// that has already passed never matches.
+func (c Condition) Match(t Target) bool {
+	var actual float64
+	switch c.Field {
+	case FieldBudget:
+		if t.Budget == nil {
+			return true
+		}
+		actual = *t.Budget
+	case FieldDeadline:
+		if t.Deadline.IsZero() {
+			return true
+		}
+		now := t.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+		left := t.Deadline.Sub(now)
+		if left <= 0 {
+			return false
+		}
+		actual = float64(left)
+	}
+	switch c.Op {
+	case "<":
+		return actual < c.Value
+	case "<=":
+		return actual <= c.Value
+	case ">":
+		return actual > c.Value
+	case ">=":
+		return actual >= c.Value
+	default:
+		return actual == c.Value
+	}
+}
+
+func (c Condition) String() string {
+	return c.Field + c.Op + c.raw
+}
@@ -0,0 +1,58 @@
+package rule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+func TestComplexRule_Conditions(t *testing.T) {
+	now := time.Date(2024, 5, 20, 10, 0, 0, 0, time.UTC)
+	budget := func(f float64) *float64 { return &f }
+	tests := []struct {
+		name   string
+		rule   string
+		target Target
+		want   bool
+	}{
+		{"budget above minimum", "budget>=500000", Target{Budget: budget(500000)}, true},
+		{"budget below minimum", "budget>=500000", Target{Budget: budget(499999)}, false},
+		{"budget in range", "budget>=50万 budget<2e6", Target{Budget: budget(1e6)}, true},
+		{"budget above range", "budget>=50万 budget<2e6", Target{Budget: budget(2e6)}, false},
+		{"unknown budget passes", "budget>=500000", Target{}, true},
+		{"deadline soon", "deadline<7d", Target{Deadline: now.Add(3 * 24 * time.Hour), Now: now}, true},
+		{"deadline far", "deadline<7d", Target{Deadline: now.Add(10 * 24 * time.Hour), Now: now}, false},
+		{"deadline closed", "deadline<7d", Target{Deadline: now.Add(-time.Hour), Now: now}, false},
+		{"enough time left", "deadline>=48h", Target{Deadline: now.Add(72 * time.Hour), Now: now}, true},
+		{"unknown deadline passes", "deadline<7d", Target{Now: now}, true},
+		{"no conditions", "apple", Target{Budget: budget(1)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := NewComplexRule(&model.Keyword{Keyword: tt.rule})
+			if got := cr.MatchTarget(tt.target); got != tt.want {
+				t.Errorf("MatchTarget() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewComplexRule_Conditions(t *testing.T) {
+	if _, err := ParseComplexRule(&model.Keyword{Keyword: "服务器 budget>abc"}); err == nil {
+		t.Error("expected a malformed condition to be rejected")
+	}
+	cr := NewComplexRule(&model.Keyword{Keyword: "服务器 -维保 Budget>=500000 deadline<7d"})
+	if len(cr.IncludeTerms) != 1 || len(cr.ExcludeTerms) != 1 {
+		t.Errorf("expected conditions to be kept out of the terms, got %v %v", cr.IncludeTerms, cr.ExcludeTerms)
+	}
+	if len(cr.Conditions) != 2 {
+		t.Fatalf("expected two conditions, got %v", cr.Conditions)
+	}
+	if got, want := cr.ToString(), "+服务器 -维保 budget>=500000 deadline<7d"; got != want {
+		t.Errorf("ToString() = %q, want %q", got, want)
+	}
+	if !cr.IsMatch("服务器采购") {
+		t.Error("expected conditions not to affect term matching")
+	}
+}
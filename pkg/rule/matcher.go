@@ -0,0 +1,166 @@
+package rule
+
+// Matcher matches a whole rule set against notices. The plain terms of all
+// rules are compiled into one Aho–Corasick automaton per folding, so every
+// field of a notice is scanned once instead of once per term. Only the rules
+// whose anchor terms were found are evaluated then, see anchor.
+type Matcher struct {
+	rules  []*ComplexRule
+	groups map[folding]*termIndex
+	// always lists the rules without an anchor, they are evaluated for
+	// every notice.
+	always []int
+	// needsContent is set when a rule reads the notice content, the content
+	// is not scanned otherwise.
+	needsContent bool
+}
+
+// RuleMatch is a rule matching a notice and the fields its terms were found
+// in, see MatchDocument.
+type RuleMatch struct {
+	Rule   *ComplexRule
+	Scopes []Scope
+}
+
+// termIndex numbers the plain terms of the rules sharing a folding.
+type termIndex struct {
+	ids map[string]int32
+	ac  *ahoCorasick
+	// anchored lists per term the rules anchored on it.
+	anchored [][]int
+}
+
+// fieldIndex holds the plain terms found in a field of a Document.
+type fieldIndex struct {
+	ids   map[string]int32
+	found []bool
+}
+
+// NewMatcher compiles rules, which are matched in the given order.
+func NewMatcher(rules []*ComplexRule) *Matcher {
+	m := &Matcher{rules: rules, groups: make(map[folding]*termIndex)}
+	patterns := make(map[folding][]string)
+	for _, cr := range rules {
+		walkTerms(cr.expr, func(t *termNode) {
+			if t.re != nil || t.fuzzy > 0 {
+				return
+			}
+			g, ok := m.groups[t.fold]
+			if !ok {
+				g = &termIndex{ids: make(map[string]int32)}
+				m.groups[t.fold] = g
+			}
+			if _, ok := g.ids[t.text]; !ok {
+				g.ids[t.text] = int32(len(patterns[t.fold]))
+				patterns[t.fold] = append(patterns[t.fold], t.text)
+			}
+		})
+	}
+	for fold, g := range m.groups {
+		g.ac = newAhoCorasick(patterns[fold])
+		g.anchored = make([][]int, len(g.ids))
+	}
+	for i, cr := range rules {
+		m.needsContent = m.needsContent || cr.NeedsContent()
+		ids, ok := anchor(cr.expr, m.groups)
+		if !ok {
+			m.always = append(m.always, i)
+			continue
+		}
+		g := m.groups[cr.Options.folding()]
+		for _, id := range ids {
+			g.anchored[id] = append(g.anchored[id], i)
+		}
+	}
+	return m
+}
+
+// anchor returns the ids of plain terms of which at least one must be found
+// in the notice for n to hold, ok is false when there is no such set, e.g.
+// for a negation or a regex term.
+func anchor(n node, groups map[folding]*termIndex) (ids []int32, ok bool) {
+	switch v := n.(type) {
+	case *termNode:
+		if v.re != nil || v.fuzzy > 0 || v.pinyin {
+			return nil, false
+		}
+		return []int32{groups[v.fold].ids[v.text]}, true
+	case *andNode:
+		// Any child's anchor will do, the smallest one skips the most rules.
+		for _, c := range v.children {
+			if a, ok := anchor(c, groups); ok && (ids == nil || len(a) < len(ids)) {
+				ids = a
+			}
+		}
+		return ids, ids != nil
+	case *orNode:
+		for _, c := range v.children {
+			a, ok := anchor(c, groups)
+			if !ok {
+				return nil, false
+			}
+			ids = append(ids, a...)
+		}
+		return ids, true
+	}
+	return nil, false
+}
+
+// NeedsContent reports whether any rule reads the notice content.
+func (m *Matcher) NeedsContent() bool {
+	return m.needsContent
+}
+
+// Match returns the rules matching doc, in the order of the rule set.
+func (m *Matcher) Match(doc *Document) []RuleMatch {
+	doc.index = make(map[documentKey]*fieldIndex, len(m.groups)*len(scopeFields[ScopeAll]))
+	candidates := make([]bool, len(m.rules))
+	for _, i := range m.always {
+		candidates[i] = true
+	}
+	for fold, g := range m.groups {
+		for _, f := range scopeFields[ScopeAll] {
+			if f == ScopeContent && !m.needsContent {
+				continue
+			}
+			found := make([]bool, len(g.ids))
+			g.ac.scan(doc.field(f, fold, false), found)
+			doc.index[documentKey{scope: f, fold: fold}] = &fieldIndex{ids: g.ids, found: found}
+			for id, ok := range found {
+				if ok {
+					for _, i := range g.anchored[id] {
+						candidates[i] = true
+					}
+				}
+			}
+		}
+	}
+	var matches []RuleMatch
+	for i, cr := range m.rules {
+		if !candidates[i] {
+			continue
+		}
+		if ok, scopes := cr.MatchDocument(doc); ok {
+			matches = append(matches, RuleMatch{Rule: cr, Scopes: scopes})
+		}
+	}
+	return matches
+}
+
+// walkTerms calls fn for every term of n.
+func walkTerms(n node, fn func(t *termNode)) {
+	switch v := n.(type) {
+	case *termNode:
+		fn(v)
+	case *notNode:
+		walkTerms(v.child, fn)
+	case *andNode:
+		for _, c := range v.children {
+			walkTerms(c, fn)
+		}
+	case *orNode:
+		for _, c := range v.children {
+			walkTerms(c, fn)
+		}
+	}
+}
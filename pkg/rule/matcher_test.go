@@ -0,0 +1,173 @@
+package rule
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+func TestAhoCorasick(t *testing.T) {
+	patterns := []string{"he", "she", "his", "hers", "服务器", "务器", "交换机"}
+	ac := newAhoCorasick(patterns)
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"ushers", []string{"he", "she", "hers"}},
+		{"this", []string{"his"}},
+		{"某单位服务器采购", []string{"服务器", "务器"}},
+		{"服务交换机", []string{"交换机"}},
+		{"nothing", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		found := make([]bool, len(patterns))
+		ac.scan(tt.text, found)
+		var got []string
+		for id, ok := range found {
+			if ok {
+				got = append(got, patterns[id])
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("scan(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMatcher_Match(t *testing.T) {
+	keywords := []string{
+		"服务器", "服务器 -维保", "存储 | 交换机", "(服务器 | 存储) 采购", "-采购",
+		"content:GPU", "采购 -content:维保", "ABC-001", `re:/DL\d+/`, "服务器*公告",
+		"伺服器 opt:trad", "fwq opt:pinyin", "交换器 opt:fuzzy", "NOT (服务器 | 存储)",
+	}
+	var rules []*ComplexRule
+	for _, kw := range keywords {
+		rules = append(rules, NewComplexRule(&model.Keyword{Keyword: kw}))
+	}
+	m := NewMatcher(rules)
+	if !m.NeedsContent() {
+		t.Error("expected the content rules to need the content")
+	}
+	docs := [][3]string{
+		{"某单位服务器采购公告", "2024-ABC-001", "GPU 服务器，含三年维保"},
+		{"核心交换机采购", "", "交换机 2 台"},
+		{"DL380 伺服器採購", "", ""},
+		{"存储扩容", "XYZ-9", "GPU"},
+		{"", "", ""},
+	}
+	for _, d := range docs {
+		var want []RuleMatch
+		for _, cr := range rules {
+			if ok, scopes := cr.MatchDocument(NewDocument(d[0], d[1], d[2])); ok {
+				want = append(want, RuleMatch{Rule: cr, Scopes: scopes})
+			}
+		}
+		got := m.Match(NewDocument(d[0], d[1], d[2]))
+		if !slices.EqualFunc(got, want, func(a, b RuleMatch) bool {
+			return a.Rule == b.Rule && slices.Equal(a.Scopes, b.Scopes)
+		}) {
+			t.Errorf("Match(%q) = %v, want %v", d, labels(got), labels(want))
+		}
+	}
+}
+
+func TestMatcher_SkipsContent(t *testing.T) {
+	m := NewMatcher([]*ComplexRule{
+		NewComplexRule(&model.Keyword{Keyword: "title:服务器"}),
+		NewComplexRule(&model.Keyword{Keyword: "code:ABC"}),
+	})
+	if m.NeedsContent() {
+		t.Fatal("expected scoped rules not to need the content")
+	}
+	doc := NewDocument("服务器采购", "ABC-001", "服务器 ABC")
+	if got := m.Match(doc); len(got) != 2 {
+		t.Errorf("Match() = %v", labels(got))
+	}
+	for key := range doc.index {
+		if key.scope == ScopeContent {
+			t.Errorf("expected the content not to be scanned, got %+v", key)
+		}
+	}
+}
+
+func labels(matches []RuleMatch) []string {
+	var s []string
+	for _, m := range matches {
+		s = append(s, fmt.Sprintf("%s%v", m.Rule.ToString(), m.Scopes))
+	}
+	return s
+}
+
+var benchWords = []string{
+	"服务器", "存储", "交换机", "路由器", "防火墙", "数据库", "中间件", "操作系统", "虚拟化", "云平台",
+	"运维", "维保", "监控", "安全", "审计", "备份", "容灾", "机房", "空调", "UPS",
+	"显示器", "打印机", "笔记本", "台式机", "投影仪", "摄像头", "门禁", "网络", "光纤", "布线",
+	"软件", "硬件", "系统", "平台", "采购", "项目", "服务", "升级", "改造", "建设",
+}
+
+// benchmarkRules builds n rules of one to three terms, a third of them with
+// an exclude term.
+func benchmarkRules(n int) []*ComplexRule {
+	r := rand.New(rand.NewSource(1))
+	rules := make([]*ComplexRule, 0, n)
+	for i := 0; i < n; i++ {
+		kw := benchWords[r.Intn(len(benchWords))] + fmt.Sprintf("%d", i%50)
+		for j := r.Intn(3); j > 0; j-- {
+			kw += " " + benchWords[r.Intn(len(benchWords))]
+		}
+		if i%3 == 0 {
+			kw += " -" + benchWords[r.Intn(len(benchWords))]
+		}
+		rules = append(rules, NewComplexRule(&model.Keyword{Keyword: kw}))
+	}
+	return rules
+}
+
+func benchmarkTitles(n int) [][2]string {
+	r := rand.New(rand.NewSource(2))
+	titles := make([][2]string, 0, n)
+	for i := 0; i < n; i++ {
+		title := "某单位"
+		for j := 0; j < 6; j++ {
+			title += benchWords[r.Intn(len(benchWords))] + fmt.Sprintf("%d", r.Intn(60))
+		}
+		titles = append(titles, [2]string{title + "公告", fmt.Sprintf("ZB-2024-%04d", i)})
+	}
+	return titles
+}
+
+// BenchmarkRuleLoop is the loop Projects.Filter used before the Matcher,
+// every rule evaluated on its own.
+func BenchmarkRuleLoop(b *testing.B) {
+	for _, n := range []int{50, 500} {
+		rules, titles := benchmarkRules(n), benchmarkTitles(1000)
+		b.Run(fmt.Sprintf("rules=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, title := range titles {
+					doc := NewDocument(title[0], title[1], "")
+					for _, cr := range rules {
+						cr.MatchDocument(doc)
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMatcher(b *testing.B) {
+	for _, n := range []int{50, 500} {
+		rules, titles := benchmarkRules(n), benchmarkTitles(1000)
+		b.Run(fmt.Sprintf("rules=%d", n), func(b *testing.B) {
+			m := NewMatcher(rules)
+			for i := 0; i < b.N; i++ {
+				for _, title := range titles {
+					m.Match(NewDocument(title[0], title[1], ""))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package rule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/width"
+)
+
+// Rule options, written as "opt:trad" terms and applying to the whole rule.
+const (
+	// OptionTrad folds traditional Chinese characters and common Taiwan and
+	// Hong Kong IT terms to simplified Chinese, so 服务器 matches 伺服器.
+	OptionTrad = "trad"
+	// OptionWidth folds full-width letters, digits and punctuation to their
+	// ASCII form, so DL380 matches ＤＬ３８０.
+	OptionWidth = "width"
+	// OptionPinyin lets an all-letter term such as "fwq" match the pinyin
+	// initials of the text, 服务器 here.
+	OptionPinyin = "pinyin"
+	// OptionFuzzy tolerates up to 1 (opt:fuzzy) or 2 (opt:fuzzy=2) typos,
+	// i.e. inserted, deleted or replaced characters, per plain term.
+	OptionFuzzy = "fuzzy"
+)
+
+const (
+	optionPrefix = "opt:"
+	maxFuzzy     = 2
+)
+
+// Options are the normalization options of a rule. The zero value keeps the
+// default matching.
+type Options struct {
+	Trad   bool
+	Width  bool
+	Pinyin bool
+	// Fuzzy is the edit distance tolerated per plain term, see fuzzyDistance.
+	Fuzzy int
+}
+
+// parseOption parses an "opt:name" term into o, ok is false when term is not
+// an option at all.
+func (o *Options) parseOption(term string) (ok bool, err error) {
+	name, found := strings.CutPrefix(strings.ToLower(term), optionPrefix)
+	if !found {
+		return false, nil
+	}
+	name, value, hasValue := strings.Cut(name, "=")
+	switch {
+	case name == OptionTrad && !hasValue:
+		o.Trad = true
+	case name == OptionWidth && !hasValue:
+		o.Width = true
+	case name == OptionPinyin && !hasValue:
+		o.Pinyin = true
+	case name == OptionFuzzy:
+		o.Fuzzy = 1
+		if hasValue {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > maxFuzzy {
+				return true, fmt.Errorf("invalid option %q, the distance must be between 1 and %d", term, maxFuzzy)
+			}
+			o.Fuzzy = n
+		}
+	default:
+		return true, fmt.Errorf("unknown option %q, expected one of %s", term,
+			strings.Join([]string{OptionTrad, OptionWidth, OptionPinyin, OptionFuzzy}, ", "))
+	}
+	return true, nil
+}
+
+// terms renders the options as they are written in a keyword.
+func (o Options) terms() []string {
+	var terms []string
+	if o.Trad {
+		terms = append(terms, optionPrefix+OptionTrad)
+	}
+	if o.Width {
+		terms = append(terms, optionPrefix+OptionWidth)
+	}
+	if o.Pinyin {
+		terms = append(terms, optionPrefix+OptionPinyin)
+	}
+	switch o.Fuzzy {
+	case 0:
+	case 1:
+		terms = append(terms, optionPrefix+OptionFuzzy)
+	default:
+		terms = append(terms, fmt.Sprintf("%s%s=%d", optionPrefix, OptionFuzzy, o.Fuzzy))
+	}
+	return terms
+}
+
+// folding selects the folds applied to a normalized text, it keys the
+// folded fields cached by a Document.
+type folding struct {
+	trad, width bool
+}
+
+func (o Options) folding() folding {
+	return folding{trad: o.Trad, width: o.Width}
+}
+
+// fold applies f to a normalized text.
+func (f folding) fold(s string) string {
+	if f.width {
+		s = width.Fold.String(s)
+	}
+	if f.trad {
+		s = foldTraditional(s)
+	}
+	return s
+}
+
+var tradToSimp = func() map[rune]rune {
+	m := make(map[rune]rune, len(tradPairs))
+	for _, pair := range strings.Fields(tradPairs) {
+		t, size := utf8.DecodeRuneInString(pair)
+		s, _ := utf8.DecodeRuneInString(pair[size:])
+		m[t] = s
+	}
+	return m
+}()
+
+// tradTerms maps Taiwan and Hong Kong terms, after folding them to
+// simplified characters, to the terms used on the mainland.
+var tradTerms = strings.NewReplacer(
+	"伺服器", "服务器",
+	"软体", "软件",
+	"硬体", "硬件",
+	"韧体", "固件",
+	"网路", "网络",
+	"资料库", "数据库",
+	"记忆体", "内存",
+	"印表机", "打印机",
+	"萤幕", "显示器",
+	"笔电", "笔记本电脑",
+)
+
+// foldTraditional converts traditional characters and terms to simplified
+// Chinese.
+func foldTraditional(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if v, ok := tradToSimp[r]; ok {
+			return v
+		}
+		return r
+	}, s)
+	return tradTerms.Replace(s)
+}
+
+// pinyinBounds holds the first GB2312 code of every pinyin initial. The
+// level one characters of GB2312, the 3755 most common ones, are ordered by
+// their pinyin.
+var pinyinBounds = []struct {
+	code    uint16
+	initial byte
+}{
+	{0xB0A1, 'a'}, {0xB0C5, 'b'}, {0xB2C1, 'c'}, {0xB4EE, 'd'}, {0xB6EA, 'e'},
+	{0xB7A2, 'f'}, {0xB8C1, 'g'}, {0xB9FE, 'h'}, {0xBBF7, 'j'}, {0xBFA6, 'k'},
+	{0xC0AC, 'l'}, {0xC2E8, 'm'}, {0xC4C3, 'n'}, {0xC5B6, 'o'}, {0xC5BE, 'p'},
+	{0xC6DA, 'q'}, {0xC8BB, 'r'}, {0xC8F6, 's'}, {0xCBFA, 't'}, {0xCDDA, 'w'},
+	{0xCEF4, 'x'}, {0xD1B9, 'y'}, {0xD4D1, 'z'},
+}
+
+const pinyinEnd = 0xD7F9
+
+// pinyinInitials replaces every common Chinese character of s with the
+// initial of its pinyin and lower cases ASCII letters. Other characters are
+// kept, so the result still separates unrelated words.
+func pinyinInitials(s string) string {
+	encoder := simplifiedchinese.GBK.NewEncoder()
+	var sb strings.Builder
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			sb.WriteByte(byte(toLowerASCII(r)))
+			continue
+		}
+		if b, err := encoder.Bytes([]byte(string(r))); err == nil && len(b) == 2 {
+			code := uint16(b[0])<<8 | uint16(b[1])
+			if code >= pinyinBounds[0].code && code <= pinyinEnd {
+				initial := pinyinBounds[0].initial
+				for _, bound := range pinyinBounds {
+					if code < bound.code {
+						break
+					}
+					initial = bound.initial
+				}
+				sb.WriteByte(initial)
+				continue
+			}
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// isPinyinTerm reports whether term can be matched as pinyin initials.
+func isPinyinTerm(term string) bool {
+	if len(term) < 2 {
+		return false
+	}
+	for _, r := range term {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func toLowerASCII(r rune) rune {
+	if r >= 'A' && r <= 'Z' {
+		return r + 'a' - 'A'
+	}
+	return r
+}
+
+// fuzzyDistance caps the tolerated edit distance by the length of the term,
+// a term needs more than twice as many characters as typos tolerated, else
+// nearly anything would match it.
+func fuzzyDistance(term string, limit int) int {
+	return min(limit, (utf8.RuneCountInString(term)-1)/2)
+}
+
+// fuzzyContains reports whether s contains a substring within edit distance
+// k of term, using Sellers' variant of the Levenshtein distance.
+func fuzzyContains(s, term string, k int) bool {
+	if k <= 0 {
+		return strings.Contains(s, term)
+	}
+	p := []rune(term)
+	// col[i] is the distance between p[:i] and the best substring of s
+	// ending at the current position.
+	col := make([]int, len(p)+1)
+	for i := range col {
+		col[i] = i
+	}
+	if col[len(p)] <= k {
+		return true
+	}
+	for _, r := range s {
+		diag := col[0] // a match may start anywhere, col[0] stays 0
+		for i := 1; i <= len(p); i++ {
+			cost := 1
+			if p[i-1] == r {
+				cost = 0
+			}
+			next := min(col[i]+1, col[i-1]+1, diag+cost)
+			diag, col[i] = col[i], next
+		}
+		if col[len(p)] <= k {
+			return true
+		}
+	}
+	return false
+}
+
+// tradPairs lists traditional characters each followed by its simplified
+// form, limited to the characters common in procurement notices.
+const tradPairs = `
+萬万 與与 專专 業业 東东 絲丝 兩两 嚴严 個个 豐丰 臨临 為为 麗丽 舉举 義义
+樂乐 習习 鄉乡 書书 買买 亂乱 爭争 於于 虧亏 雲云 亞亚 產产 親亲 億亿 僅仅
+從从 倉仓 儀仪 們们 價价 眾众 優优 會会 偉伟 傳传 傷伤 倫伦 偽伪 體体 餘余
+偵侦 側侧 係系 債债 傾倾 備备 傑杰 儲储 兒儿 黨党 蘭兰 關关 興兴 養养 內内
+冊册 寫写 軍军 農农 衝冲 決决 況况 凍冻 淨净 減减 幾几 鳳凤 憑凭 凱凯 擊击
+劃划 劉刘 則则 剛刚 創创 刪删 別别 劑剂 劍剑 劇剧 勸劝 辦办 務务 動动 勵励
+勞劳 勢势 勝胜 區区 醫医 華华 協协 單单 賣卖 衛卫 卻却 廠厂 廳厅 歷历 曆历
+厲厉 壓压 廁厕 廈厦 縣县 參参 雙双 發发 髮发 變变 敘叙 疊叠 號号 嘆叹 嗎吗
+啟启 員员 聽听 鳴鸣 響响 問问 圖图 國国 圍围 園园 圓圆 聖圣 場场 壞坏 塊块
+堅坚 壇坛 壩坝 墳坟 執执 報报 牆墙 壯壮 聲声 殼壳 處处 復复 複复 夠够 頭头
+誇夸 奪夺 奮奋 獎奖 婦妇 媽妈 孫孙 學学 寧宁 寶宝 實实 審审 憲宪 寬宽 賓宾
+對对 尋寻 導导 壽寿 將将 爾尔 塵尘 層层 屬属 歲岁 嶺岭 島岛 崗岗 幣币 師师
+帳帐 幫帮 帶带 廣广 莊庄 慶庆 庫库 應应 廟庙 廢废 開开 異异 棄弃 張张 彎弯
+彈弹 強强 歸归 當当 錄录 徹彻 徑径 後后 憶忆 懷怀 態态 總总 戀恋 懇恳 惡恶
+惱恼 悅悦 懸悬 驚惊 慣惯 願愿 戲戏 戰战 戶户 撲扑 擴扩 掃扫 揚扬 擾扰 撫抚
+搶抢 護护 擔担 擬拟 揀拣 擁拥 攔拦 撥拨 擇择 掛挂 損损 換换 據据 擺摆 攜携
+攝摄 搖摇 數数 斷断 無无 舊旧 時时 畫画 暢畅 暫暂 機机 殺杀 雜杂 權权 條条
+來来 楊杨 極极 構构 樹树 標标 欄栏 樣样 檢检 樓楼 櫃柜 檔档 橋桥 樁桩 夢梦
+歡欢 歐欧 殘残 毀毁 氣气 漢汉 湯汤 溝沟 沒没 滬沪 潔洁 灑洒 濁浊 測测 濟济
+濃浓 漲涨 潤润 漸渐 溫温 遊游 灣湾 濕湿 滿满 濾滤 潛潜 災灾 燈灯 靈灵 爐炉
+點点 煉炼 爛烂 熱热 煙烟 營营 燒烧 愛爱 牽牵 狀状 獨独 獲获 環环 現现 電电
+療疗 盤盘 監监 礦矿 碼码 磚砖 確确 礎础 禮礼 離离 種种 稱称 積积 穩稳 窮穷
+競竞 筆笔 築筑 簡简 籃篮 類类 糧粮 緊紧 紀纪 級级 約约 紅红 紡纺 紙纸 紋纹
+納纳 純纯 線线 練练 組组 細细 終终 經经 結结 給给 絡络 統统 絕绝 維维 綜综
+綠绿 網网 緣缘 編编 緯纬 縮缩 績绩 續续 繩绳 繪绘 繼继 纜缆 罰罚 聯联 職职
+聰聪 腦脑 膠胶 臟脏 臉脸 艦舰 艙舱 藝艺 節节 範范 薦荐 藥药 蓋盖 蘇苏 蟲虫
+補补 裝装 見见 規规 視视 覽览 覺觉 計计 訂订 認认 討讨 讓让 訓训 議议 記记
+講讲 許许 論论 設设 訪访 證证 評评 識识 詞词 試试 話话 該该 詳详 誤误 說说
+請请 讀读 課课 調调 談谈 謝谢 譜谱 貝贝 負负 財财 責责 販贩 貨货 質质 購购
+貿贸 費费 資资 賬账 賽赛 贈赠 趕赶 躍跃 軌轨 車车 輕轻 載载 輔辅 輛辆 輸输
+轉转 輪轮 軟软 邊边 達达 遷迁 過过 運运 還还 這这 進进 遠远 違违 連连 選选
+遺遗 鄰邻 釋释 裡里 針针 鋼钢 鐵铁 鉛铅 銀银 銷销 鋪铺 鏈链 鍵键 鎖锁 鏡镜
+錢钱 錯错 鑰钥 鍋锅 長长 門门 閃闪 閉闭 閒闲 間间 閱阅 闊阔 隊队 陽阳 陰阴
+陣阵 階阶 際际 陸陆 隨随 險险 隱隐 雞鸡 難难 霧雾 靜静 順顺 頁页 項项 須须
+預预 領领 頻频 題题 額额 顏颜 顯显 風风 飛飞 飯饭 飲饮 館馆 馬马 駕驾 驗验
+騎骑 驅驱 鬥斗 魚鱼 鳥鸟 鹽盐 麥麦 黃黄 齊齐 齒齿 龍龙 龜龟 滾滚 臺台 檯台
+週周 隻只 採采 製制 韌韧 體体 訊讯 腳脚 螢萤 頻频 憶忆 筆笔 戶户 纖纤
+`
@@ -0,0 +1,93 @@
+package rule
+
+import (
+	"testing"
+
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+func TestComplexRule_Options(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		title string
+		want  bool
+	}{
+		{"default keeps traditional apart", "服务器", "伺服器採購案", false},
+		{"trad term", "服务器 opt:trad", "伺服器採購案", true},
+		{"trad characters", "网络设备 opt:trad", "網絡設備採購", true},
+		{"trad folds the rule too", "網絡設備 opt:trad", "网络设备采购", true},
+		{"trad exclude", "采购 -维保 opt:trad", "伺服器採購及維保", false},
+		{"default keeps width apart", "DL380", "ＤＬ３８０服务器", false},
+		{"width", "DL380 opt:width", "ＤＬ３８０服务器", true},
+		{"width and trad", "DL380 服务器 opt:width opt:trad", "ＤＬ３８０伺服器", true},
+		{"pinyin", "fwq opt:pinyin", "某单位服务器采购", true},
+		{"pinyin upper case", "FWQ opt:pinyin", "某单位服务器采购", true},
+		{"pinyin miss", "fwq opt:pinyin", "某单位交换机采购", false},
+		{"pinyin off", "fwq", "某单位服务器采购", false},
+		{"fuzzy replace", "交换机 opt:fuzzy", "核心交换器采购", true},
+		{"fuzzy insert", "交换机 opt:fuzzy", "核心交换主机采购", true},
+		{"fuzzy too far", "交换机 opt:fuzzy", "核心路由器采购", false},
+		{"fuzzy short terms exact", "网卡 opt:fuzzy", "网线采购", false},
+		{"fuzzy 2", "核心交换机 opt:fuzzy=2", "核心交互设机采购", true},
+		{"fuzzy leaves regex exact", `re:/交换机/ opt:fuzzy`, "核心交换器采购", false},
+		{"glob folded", "伺服器*采购 opt:trad", "服务器设备采购", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr, err := ParseComplexRule(&model.Keyword{Keyword: tt.rule})
+			if err != nil {
+				t.Fatalf("ParseComplexRule() error = %v", err)
+			}
+			if got := cr.IsMatch(tt.title); got != tt.want {
+				t.Errorf("IsMatch(%q) = %v, want %v", tt.title, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComplexRule_OptionsString(t *testing.T) {
+	cr, err := ParseComplexRule(&model.Keyword{Keyword: "opt:fuzzy=2 伺服器 OPT:Pinyin opt:trad budget>1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "+服务器 budget>1 opt:trad opt:pinyin opt:fuzzy=2"
+	if got := cr.ToString(); got != want {
+		t.Errorf("ToString() = %q, want %q", got, want)
+	}
+	if again := NewComplexRule(&model.Keyword{Keyword: want}); again == nil || again.ToString() != want {
+		t.Errorf("canonical form %q does not round trip", want)
+	}
+	for _, rule := range []string{"a opt:bold", "a opt:fuzzy=3", "a | opt:trad", "content:(a opt:trad)"} {
+		if _, err := ParseComplexRule(&model.Keyword{Keyword: rule}); err == nil {
+			t.Errorf("ParseComplexRule(%q) should fail", rule)
+		}
+	}
+}
+
+func TestPinyinInitials(t *testing.T) {
+	if got, want := pinyinInitials("服务器DL380采购（二）"), "fwqdl380cg（e）"; got != want {
+		t.Errorf("pinyinInitials() = %q, want %q", got, want)
+	}
+}
+
+func TestFuzzyContains(t *testing.T) {
+	tests := []struct {
+		s, term string
+		k       int
+		want    bool
+	}{
+		{"abcdef", "cde", 0, true},
+		{"abcdef", "cxe", 0, false},
+		{"abcdef", "cxe", 1, true},
+		{"abcdef", "cxxe", 1, false},
+		{"abcdef", "cxxe", 2, true},
+		{"", "ab", 1, false},
+		{"", "ab", 2, true},
+	}
+	for _, tt := range tests {
+		if got := fuzzyContains(tt.s, tt.term, tt.k); got != tt.want {
+			t.Errorf("fuzzyContains(%q, %q, %d) = %v, want %v", tt.s, tt.term, tt.k, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,615 @@
+package rule
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// The keyword grammar, terms next to each other are ANDed:
+//
+//	expr    = and { ("OR" | "|") and }
+//	and     = unary { ["AND"] unary }
+//	unary   = ("NOT" | "-") unary | "+" unary | primary
+//	primary = "(" expr ")" | scope ":(" expr ")" | term
+//	term    = [scope ":"] (word | "re:/" regexp "/") | condition
+//
+// A word may contain quoted parts, which keep spaces and operators literal,
+// an unquoted "*" in a word matches any run of characters.
+// Parentheses inside a word such as 系统(二次) are literal as well, only a
+// parenthesis starting a token opens a group.
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokOr
+	tokAnd
+	tokNot
+	tokPlus
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // 1-based rune column, for error messages
+}
+
+// ParseError describes malformed keyword input.
+type ParseError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	if e.Pos <= 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("%s at column %d", e.Msg, e.Pos)
+}
+
+func tokenize(s string) ([]token, error) {
+	runes := []rune(s)
+	var tokens []token
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i + 1})
+			i++
+		case r == '|':
+			tokens = append(tokens, token{kind: tokOr, text: "|", pos: i + 1})
+			i++
+		case r == '-':
+			tokens = append(tokens, token{kind: tokNot, text: "-", pos: i + 1})
+			i++
+		case r == '+':
+			tokens = append(tokens, token{kind: tokPlus, text: "+", pos: i + 1})
+			i++
+		default:
+			end, err := scanWord(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			text := string(runes[i:end])
+			kind := tokWord
+			switch text {
+			case "OR":
+				kind = tokOr
+			case "AND":
+				kind = tokAnd
+			case "NOT":
+				kind = tokNot
+			}
+			tokens = append(tokens, token{kind: kind, text: text, pos: i + 1})
+			i = end
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(runes) + 1}), nil
+}
+
+// scanWord returns the end of the word starting at runes[start].
+func scanWord(runes []rune, start int) (int, error) {
+	depth := 0
+	i := start
+	for i < len(runes) {
+		if hasRegexAt(runes, start, i) {
+			end := regexEnd(runes, i)
+			if end < 0 {
+				return 0, &ParseError{Pos: i + 1, Msg: "unterminated regular expression"}
+			}
+			i = end
+			continue
+		}
+		r := runes[i]
+		switch {
+		case r == '"':
+			closing := slices.Index(runes[i+1:], '"')
+			if closing < 0 {
+				return 0, &ParseError{Pos: i + 1, Msg: "unterminated quote"}
+			}
+			i += closing + 2
+			continue
+		case unicode.IsSpace(r), r == '|':
+			return i, nil
+		case r == '(':
+			// "content:(" scopes the group that follows.
+			if _, ok := scopeFields[Scope(strings.ToLower(strings.TrimSuffix(string(runes[start:i]), ":")))]; ok &&
+				i > start && runes[i-1] == ':' {
+				return i, nil
+			}
+			depth++
+		case r == ')':
+			if depth == 0 {
+				return i, nil
+			}
+			depth--
+		}
+		i++
+	}
+	return i, nil
+}
+
+type node interface{}
+
+type termNode struct {
+	scope Scope // empty for the default fields
+	kind  termKind
+	// text is the normalized term, or the pattern of a glob or regex term.
+	text string
+	re   *regexp.Regexp // compiled pattern of glob and regex terms
+	// fold, fuzzy and pinyin are set from the options of the rule, see
+	// applyOptions.
+	fold   folding
+	fuzzy  int
+	pinyin bool
+}
+
+type notNode struct{ child node }
+
+type andNode struct{ children []node }
+
+type orNode struct{ children []node }
+
+// condNode is a budget or deadline condition, or a rule option when option
+// is set. Both apply to the notice as a whole.
+type condNode struct {
+	cond   Condition
+	option string
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// parse parses a whole keyword, an empty keyword yields a nil node.
+func parse(s string) (node, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, nil
+	}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q", t.text)}
+	}
+	return n, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []node{first}
+	for p.peek().kind == tokOr {
+		p.next()
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, n)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return flatten(&orNode{children: children}), nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	var children []node
+	for {
+		t := p.peek()
+		switch t.kind {
+		case tokAnd:
+			if len(children) == 0 || p.tokens[p.pos-1].kind == tokAnd {
+				return nil, &ParseError{Pos: t.pos, Msg: "expected a term before AND"}
+			}
+			p.next()
+			continue
+		case tokWord, tokNot, tokPlus, tokLParen:
+			n, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, n)
+			continue
+		}
+		if len(children) == 0 {
+			return nil, p.expected(t)
+		}
+		if prev := p.tokens[p.pos-1]; prev.kind == tokAnd {
+			return nil, p.expected(t)
+		}
+		break
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return flatten(&andNode{children: children}), nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	switch t := p.peek(); t.kind {
+	case tokNot:
+		p.next()
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if n, ok := child.(*notNode); ok {
+			return n.child, nil
+		}
+		return &notNode{child: child}, nil
+	case tokPlus:
+		p.next()
+		return p.parseUnary()
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		return p.parseGroup(t)
+	case tokWord:
+		if scope, ok := groupScope(t, p.peek()); ok {
+			group, err := p.parseGroup(p.next())
+			if err != nil {
+				return nil, err
+			}
+			if err := applyScope(group, scope); err != nil {
+				return nil, &ParseError{Pos: t.pos, Msg: err.Error()}
+			}
+			return group, nil
+		}
+		return parseTerm(t)
+	}
+	return nil, p.expected(t)
+}
+
+// groupScope reports whether t is a scope directly followed by a group, as in
+// "content:(GPU | 服务器)".
+func groupScope(t, next token) (Scope, bool) {
+	name, ok := strings.CutSuffix(t.text, ":")
+	if !ok || next.kind != tokLParen || next.pos != t.pos+len([]rune(t.text)) {
+		return "", false
+	}
+	scope := Scope(strings.ToLower(name))
+	_, ok = scopeFields[scope]
+	return scope, ok
+}
+
+func (p *parser) parseGroup(open token) (node, error) {
+	if p.peek().kind == tokRParen {
+		return nil, &ParseError{Pos: open.pos, Msg: "empty group"}
+	}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokRParen {
+		return nil, &ParseError{Pos: open.pos, Msg: "missing closing parenthesis"}
+	}
+	p.next()
+	return n, nil
+}
+
+func (p *parser) expected(t token) error {
+	if t.kind == tokEOF {
+		return &ParseError{Pos: t.pos, Msg: "unexpected end of keyword, expected a term"}
+	}
+	return &ParseError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %q, expected a term", t.text)}
+}
+
+// parseTerm turns a word into a term or a condition. A scope prefix and a
+// condition are only recognised outside quotes, so `"budget>1"` stays a
+// literal term.
+func parseTerm(t token) (node, error) {
+	raw := t.text
+	quote := strings.IndexByte(raw, '"')
+	if quote < 0 {
+		quote = len(raw)
+		if c, ok, err := parseCondition(normalizeString(raw)); ok {
+			if err != nil {
+				return nil, &ParseError{Pos: t.pos, Msg: err.Error()}
+			}
+			return &condNode{cond: c}, nil
+		}
+		var opts Options
+		if ok, err := opts.parseOption(normalizeString(raw)); ok {
+			if err != nil {
+				return nil, &ParseError{Pos: t.pos, Msg: err.Error()}
+			}
+			return &condNode{option: normalizeString(raw)}, nil
+		}
+	}
+	var scope Scope
+	if i := strings.IndexAny(raw, ":："); i >= 0 && i < quote {
+		if _, ok := scopeFields[Scope(strings.ToLower(raw[:i]))]; ok {
+			scope = Scope(strings.ToLower(raw[:i]))
+			_, raw, _ = strings.Cut(strings.Replace(raw, "：", ":", 1), ":")
+		}
+	}
+	if strings.HasPrefix(raw, regexPrefix) {
+		runes := []rune(raw)
+		if end := regexEnd(runes, 0); end != len(runes) {
+			return nil, &ParseError{Pos: t.pos, Msg: "unexpected text after regular expression"}
+		}
+		src := string(runes[len(regexPrefix) : len(runes)-1])
+		re, err := compileRegex(src)
+		if err != nil {
+			return nil, &ParseError{Pos: t.pos, Msg: err.Error()}
+		}
+		return &termNode{scope: scope, kind: termRegex, text: src, re: re}, nil
+	}
+	text := normalizeString(strings.ReplaceAll(raw, `"`, ""))
+	if text == "" {
+		return nil, &ParseError{Pos: t.pos, Msg: "empty term"}
+	}
+	if !strings.Contains(raw, `"`) && strings.Contains(text, "*") {
+		re, err := compileGlob(text)
+		if err != nil {
+			return nil, &ParseError{Pos: t.pos, Msg: err.Error()}
+		}
+		return &termNode{scope: scope, kind: termGlob, text: text, re: re}, nil
+	}
+	return &termNode{scope: scope, text: text}, nil
+}
+
+// applyOptions folds the terms of n and sets up their matching as selected
+// by opts.
+func applyOptions(n node, opts Options) error {
+	switch v := n.(type) {
+	case *termNode:
+		v.fold = opts.folding()
+		switch v.kind {
+		case termText:
+			v.text = v.fold.fold(v.text)
+			v.fuzzy = fuzzyDistance(v.text, opts.Fuzzy)
+			v.pinyin = opts.Pinyin && isPinyinTerm(v.text)
+		case termGlob:
+			v.text = v.fold.fold(v.text)
+			re, err := compileGlob(v.text)
+			if err != nil {
+				return err
+			}
+			v.re = re
+		}
+	case *notNode:
+		return applyOptions(v.child, opts)
+	case *andNode:
+		for _, c := range v.children {
+			if err := applyOptions(c, opts); err != nil {
+				return err
+			}
+		}
+	case *orNode:
+		for _, c := range v.children {
+			if err := applyOptions(c, opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyScope applies scope to every unscoped term of a "scope:(...)" group.
+func applyScope(n node, scope Scope) error {
+	switch v := n.(type) {
+	case *termNode:
+		if v.scope == "" {
+			v.scope = scope
+		}
+	case *notNode:
+		return applyScope(v.child, scope)
+	case *andNode:
+		for _, c := range v.children {
+			if err := applyScope(c, scope); err != nil {
+				return err
+			}
+		}
+	case *orNode:
+		for _, c := range v.children {
+			if err := applyScope(c, scope); err != nil {
+				return err
+			}
+		}
+	case *condNode:
+		if v.option != "" {
+			return fmt.Errorf("option %s cannot be scoped", v.option)
+		}
+		return fmt.Errorf("condition %s cannot be scoped", v.cond)
+	}
+	return nil
+}
+
+// flatten merges directly nested ANDs into their AND parent and nested ORs
+// into their OR parent.
+func flatten(n node) node {
+	switch v := n.(type) {
+	case *andNode:
+		var children []node
+		for _, c := range v.children {
+			if a, ok := c.(*andNode); ok {
+				children = append(children, a.children...)
+			} else {
+				children = append(children, c)
+			}
+		}
+		v.children = children
+	case *orNode:
+		var children []node
+		for _, c := range v.children {
+			if o, ok := c.(*orNode); ok {
+				children = append(children, o.children...)
+			} else {
+				children = append(children, c)
+			}
+		}
+		v.children = children
+	}
+	return n
+}
+
+// extractConditions removes the top level conditions and options from n.
+// Conditions anywhere else are rejected, they constrain the notice as a
+// whole and cannot be ORed or negated.
+func extractConditions(n node) (node, []*condNode, error) {
+	var conditions []*condNode
+	switch v := n.(type) {
+	case *condNode:
+		return nil, []*condNode{v}, nil
+	case *andNode:
+		var rest []node
+		for _, c := range v.children {
+			if cond, ok := c.(*condNode); ok {
+				conditions = append(conditions, cond)
+			} else {
+				rest = append(rest, c)
+			}
+		}
+		switch len(rest) {
+		case 0:
+			n = nil
+		case 1:
+			n = rest[0]
+		default:
+			v.children = rest
+		}
+	}
+	if containsCondition(n) {
+		return nil, nil, &ParseError{Msg: "budget and deadline conditions and options can only be combined with AND at the top level"}
+	}
+	return n, conditions, nil
+}
+
+func containsCondition(n node) bool {
+	switch v := n.(type) {
+	case *condNode:
+		return true
+	case *notNode:
+		return containsCondition(v.child)
+	case *andNode:
+		return slices.ContainsFunc(v.children, containsCondition)
+	case *orNode:
+		return slices.ContainsFunc(v.children, containsCondition)
+	}
+	return false
+}
+
+// quoteTerm quotes text when it would not read back as the same single term.
+func quoteTerm(text string) string {
+	runes := []rune(text)
+	end, err := scanWord(runes, 0)
+	special := strings.ContainsAny(text[:1], "(+-") || text == "OR" || text == "AND" || text == "NOT"
+	if err == nil && end == len(runes) && !special {
+		if n, err := parseTerm(token{kind: tokWord, text: text}); err == nil {
+			if t, ok := n.(*termNode); ok && t.scope == "" && t.kind == termText && t.text == text {
+				return text
+			}
+		}
+	}
+	return `"` + text + `"`
+}
+
+func (t *termNode) String() string {
+	if t.scope == "" {
+		return t.term()
+	}
+	return string(t.scope) + ":" + t.term()
+}
+
+// term renders the term without its scope.
+func (t *termNode) term() string {
+	switch t.kind {
+	case termRegex:
+		return regexPrefix + t.text + "/"
+	case termGlob:
+		return t.text
+	}
+	return quoteTerm(t.text)
+}
+
+// format renders n in the canonical form. The top level AND keeps the
+// original "+include -exclude" notation, unscoped includes and excludes each
+// sorted, followed by scoped terms and groups in input order.
+func format(n node, top bool) string {
+	switch v := n.(type) {
+	case *termNode:
+		if top {
+			return "+" + v.String()
+		}
+		return v.String()
+	case *notNode:
+		return "-" + formatOperand(v.child)
+	case *orNode:
+		parts := make([]string, 0, len(v.children))
+		for _, c := range v.children {
+			parts = append(parts, formatOperand(c))
+		}
+		if top {
+			return "+(" + strings.Join(parts, " | ") + ")"
+		}
+		return strings.Join(parts, " | ")
+	case *andNode:
+		var includes, excludes, rest []string
+		for _, c := range v.children {
+			s := format(c, top)
+			switch t := c.(type) {
+			case *termNode:
+				if t.scope == "" {
+					includes = append(includes, s)
+					continue
+				}
+			case *notNode:
+				if t, ok := t.child.(*termNode); ok && t.scope == "" {
+					excludes = append(excludes, s)
+					continue
+				}
+			}
+			rest = append(rest, s)
+		}
+		slices.Sort(includes)
+		slices.Sort(excludes)
+		return strings.Join(slices.Concat(includes, excludes, rest), " ")
+	}
+	return ""
+}
+
+// formatOperand renders an operand of NOT or OR, grouping compound nodes.
+func formatOperand(n node) string {
+	switch n.(type) {
+	case *andNode, *orNode:
+		return "(" + format(n, false) + ")"
+	}
+	return format(n, false)
+}
@@ -0,0 +1,112 @@
+package rule
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+func TestComplexRule_BooleanMatch(t *testing.T) {
+	doc := NewDocument("某单位服务器采购公告", "2024-ABC-001", "采购内容：GPU 显卡 2 块，含三年维保")
+	tests := []struct {
+		name       string
+		rule       string
+		want       bool
+		wantScopes []Scope
+	}{
+		{"or first", "服务器 | 存储", true, []Scope{ScopeTitle}},
+		{"or second", "存储 OR 服务器", true, []Scope{ScopeTitle}},
+		{"or miss", "存储 | 交换机", false, nil},
+		{"and keyword", "服务器 AND 采购", true, []Scope{ScopeTitle}},
+		{"not keyword", "服务器 NOT 公告", false, nil},
+		{"and binds tighter", "存储 采购 | 服务器", true, []Scope{ScopeTitle}},
+		{"group", "(存储 | 服务器) 采购", true, []Scope{ScopeTitle}},
+		{"negated group", "采购 -(存储 | 服务器)", false, nil},
+		{"nested group", "((存储 | 交换机) | (服务器 -维修))", true, []Scope{ScopeTitle}},
+		{"scoped group", "采购 content:(交换机 | GPU)", true, []Scope{ScopeTitle, ScopeContent}},
+		{"failed branch not reported", "content:交换机 | 服务器", true, []Scope{ScopeTitle}},
+		{"quoted operator", `"服务器|采购"`, false, nil},
+		{"code or", "交换机 | ABC", true, []Scope{ScopeCode}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr, err := ParseComplexRule(&model.Keyword{Keyword: tt.rule})
+			if err != nil {
+				t.Fatalf("ParseComplexRule() error = %v", err)
+			}
+			got, scopes := cr.MatchDocument(doc)
+			if got != tt.want || !slices.Equal(scopes, tt.wantScopes) {
+				t.Errorf("MatchDocument() = %v, %v, want %v, %v", got, scopes, tt.want, tt.wantScopes)
+			}
+		})
+	}
+}
+
+func TestComplexRule_CanonicalString(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"服务器 -维保 存储", "+存储 +服务器 -维保"},
+		{"(服务器 OR 存储) AND NOT 维保", "-维保 +(服务器 | 存储)"},
+		{"服务器 | 存储 -维保", "+(服务器 | (存储 -维保))"},
+		{"-(a | b)", "-(a | b)"},
+		{"content:(GPU | 显卡) 采购", "+采购 +(content:GPU | content:显卡)"},
+		{`"a|b" 系统(二次)`, `+"a|b" +系统(二次)`},
+		{`"budget>1"`, `+"budget>1"`},
+		{"a (b (c | d))", "+a +b +(c | d)"},
+		{"Budget>=1万 a", "+a budget>=1万"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			cr, err := ParseComplexRule(&model.Keyword{Keyword: tt.rule})
+			if err != nil {
+				t.Fatalf("ParseComplexRule() error = %v", err)
+			}
+			got := cr.ToString()
+			if got != tt.want {
+				t.Errorf("ToString() = %q, want %q", got, tt.want)
+			}
+			again, err := ParseComplexRule(&model.Keyword{Keyword: got})
+			if err != nil || again.ToString() != got {
+				t.Errorf("canonical form %q does not round trip: %v", got, err)
+			}
+		})
+	}
+}
+
+func TestParseComplexRule_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		pos  int
+	}{
+		{"unbalanced open", "(a | b", 1},
+		{"unbalanced close", "a)", 2},
+		{"unterminated quote", `a "b`, 3},
+		{"dangling or", "a |", 4},
+		{"leading and", "AND a", 1},
+		{"empty group", "a ()", 3},
+		{"dangling not", "a NOT", 6},
+		{"condition in or", "a | budget>1", 0},
+		{"negated condition", "a -deadline<7d", 0},
+		{"malformed condition", "a budget>abc", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseComplexRule(&model.Keyword{Keyword: tt.rule})
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("ParseComplexRule(%q) error = %v, want a *ParseError", tt.rule, err)
+			}
+			if pe.Pos != tt.pos {
+				t.Errorf("ParseComplexRule(%q) error at %d, want %d: %v", tt.rule, pe.Pos, tt.pos, err)
+			}
+			if NewComplexRule(&model.Keyword{Keyword: tt.rule}) != nil {
+				t.Errorf("NewComplexRule(%q) should return nil", tt.rule)
+			}
+		})
+	}
+}
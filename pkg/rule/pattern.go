@@ -0,0 +1,105 @@
+package rule
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// Limits of user supplied patterns. Go regular expressions run in linear
+// time, the limits keep a single rule from compiling into a huge program that
+// is then run against every notice.
+const (
+	maxPatternLen  = 256
+	maxPatternInst = 2000
+)
+
+const regexPrefix = "re:/"
+
+type termKind int
+
+const (
+	termText termKind = iota
+	termGlob
+	termRegex
+)
+
+// regexEnd returns the index after the closing slash of the regular
+// expression starting at runes[i], which must start with regexPrefix, or -1
+// when it is not terminated. A slash is escaped as `\/`.
+func regexEnd(runes []rune, i int) int {
+	for j := i + len(regexPrefix); j < len(runes); j++ {
+		switch runes[j] {
+		case '\\':
+			j++
+		case '/':
+			return j + 1
+		}
+	}
+	return -1
+}
+
+// hasRegexAt reports whether a regular expression starts at runes[i] of the
+// word starting at runes[start], either right at its start or after a scope
+// prefix as in "content:re:/.../".
+func hasRegexAt(runes []rune, start, i int) bool {
+	if !strings.HasPrefix(string(runes[i:min(i+len(regexPrefix), len(runes))]), regexPrefix) {
+		return false
+	}
+	if i == start {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(string(runes[start:i]), ":")
+	if !ok {
+		return false
+	}
+	_, ok = scopeFields[Scope(strings.ToLower(prefix))]
+	return ok
+}
+
+// compileRegex compiles the source of a "re:/.../" term.
+func compileRegex(src string) (*regexp.Regexp, error) {
+	if src == "" {
+		return nil, fmt.Errorf("empty regular expression")
+	}
+	return compilePattern(src)
+}
+
+// compileGlob compiles a term with "*" wildcards, each standing for any run
+// of characters.
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	parts := strings.Split(glob, "*")
+	for i, p := range parts {
+		parts[i] = regexp.QuoteMeta(p)
+	}
+	return compilePattern(strings.Join(parts, ".*"))
+}
+
+// compilePattern compiles src after checking it against the complexity
+// limits. A pattern matching the empty string matches every notice and is
+// rejected as well.
+func compilePattern(src string) (*regexp.Regexp, error) {
+	if len([]rune(src)) > maxPatternLen {
+		return nil, fmt.Errorf("pattern longer than %d characters", maxPatternLen)
+	}
+	parsed, err := syntax.Parse(src, syntax.Perl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	if len(prog.Inst) > maxPatternInst {
+		return nil, fmt.Errorf("pattern too complex")
+	}
+	re, err := regexp.Compile(src)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	if re.MatchString("") {
+		return nil, fmt.Errorf("pattern matches every notice")
+	}
+	return re, nil
+}
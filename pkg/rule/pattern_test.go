@@ -0,0 +1,96 @@
+package rule
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+func TestComplexRule_PatternMatch(t *testing.T) {
+	doc := NewDocument("某单位 DL380 Gen10 服务器采购公告", "ZB-2024-0815", "采购内容：R740xd 服务器 2 台")
+	tests := []struct {
+		name string
+		rule string
+		want bool
+	}{
+		{"regex title", `re:/DL\d+/`, true},
+		{"regex code", `re:/^ZB-\d{4}-\d+$/`, true},
+		{"regex miss", `re:/^HT-/`, false},
+		{"regex with space and bar", `re:/Gen(9|10) ?服务器/`, true},
+		{"escaped slash", `re:/a\/b/ | 服务器`, true},
+		{"scoped regex", `content:re:/R7\d0/`, true},
+		{"scoped regex misses title", `title:re:/R7\d0/`, false},
+		{"excluded regex", `服务器 -re:/Gen\d+/`, false},
+		{"glob", "服务器*公告", true},
+		{"glob order", "公告*服务器", false},
+		{"glob code", "ZB-*-0815", true},
+		{"quoted star is literal", `"服务器*公告"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr, err := ParseComplexRule(&model.Keyword{Keyword: tt.rule})
+			if err != nil {
+				t.Fatalf("ParseComplexRule() error = %v", err)
+			}
+			if got, _ := cr.MatchDocument(doc); got != tt.want {
+				t.Errorf("MatchDocument() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComplexRule_PatternString(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{`re:/DL\d+/ 服务器`, `+re:/DL\d+/ +服务器`},
+		{`content:re:/a b/`, `+content:re:/a b/`},
+		{"服务器*公告 -维保", "+服务器*公告 -维保"},
+		{`"a*b"`, `+"a*b"`},
+		{`"re:/a/"`, `+"re:/a/"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			cr, err := ParseComplexRule(&model.Keyword{Keyword: tt.rule})
+			if err != nil {
+				t.Fatalf("ParseComplexRule() error = %v", err)
+			}
+			got := cr.ToString()
+			if got != tt.want {
+				t.Errorf("ToString() = %q, want %q", got, tt.want)
+			}
+			again, err := ParseComplexRule(&model.Keyword{Keyword: got})
+			if err != nil || again.ToString() != got {
+				t.Errorf("canonical form %q does not round trip: %v", got, err)
+			}
+		})
+	}
+}
+
+func TestParseComplexRule_PatternErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+	}{
+		{"unterminated", `re:/abc`},
+		{"invalid", `re:/a(b/`},
+		{"empty", `re://`},
+		{"trailing text", `re:/a/b`},
+		{"matches everything", `re:/.*/`},
+		{"bare star", "*"},
+		{"too long", "re:/" + strings.Repeat("a", maxPatternLen+1) + "/"},
+		{"too complex", `re:/(a{1,100}){1,100}/`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseComplexRule(&model.Keyword{Keyword: tt.rule})
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Errorf("ParseComplexRule(%q) error = %v, want a *ParseError", tt.rule, err)
+			}
+		})
+	}
+}
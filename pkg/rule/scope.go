@@ -0,0 +1,203 @@
+package rule
+
+import "strings"
+
+// Scope selects the notice field a term is matched against.
+type Scope string
+
+const (
+	ScopeTitle   Scope = "title"
+	ScopeCode    Scope = "code"
+	ScopeContent Scope = "content"
+	// ScopeAll matches a term against the title, the tender code and the
+	// content.
+	ScopeAll Scope = "all"
+)
+
+var scopeFields = map[Scope][]Scope{
+	ScopeTitle:   {ScopeTitle},
+	ScopeCode:    {ScopeCode},
+	ScopeContent: {ScopeContent},
+	ScopeAll:     {ScopeTitle, ScopeCode, ScopeContent},
+}
+
+// ScopedTerm is a term restricted to a scope, written "content:服务器" or
+// "-title:维保".
+type ScopedTerm struct {
+	Scope   Scope
+	Term    string
+	Exclude bool
+}
+
+func (t ScopedTerm) String() string {
+	sign := "+"
+	if t.Exclude {
+		sign = "-"
+	}
+	return sign + string(t.Scope) + ":" + t.Term
+}
+
+// Document is a notice prepared for matching, every field normalized once
+// instead of once per rule. The folded forms rule options ask for are cached
+// as well, a Document is not safe for concurrent use.
+type Document struct {
+	fields map[Scope]string
+	cache  map[documentKey]string
+	// index holds the plain terms a Matcher found in each field.
+	index map[documentKey]*fieldIndex
+}
+
+type documentKey struct {
+	scope    Scope
+	fold     folding
+	initials bool
+}
+
+// NewDocument normalizes the fields of a notice. content is plain text, it
+// is only read by content and all scoped terms.
+func NewDocument(title, code, content string) *Document {
+	return &Document{fields: map[Scope]string{
+		ScopeTitle:   normalizeString(title),
+		ScopeCode:    normalizeString(code),
+		ScopeContent: normalizeString(content),
+	}}
+}
+
+// field returns the field of the given scope folded by fold, converted to
+// pinyin initials when initials is set.
+func (d *Document) field(scope Scope, fold folding, initials bool) string {
+	if fold == (folding{}) && !initials {
+		return d.fields[scope]
+	}
+	key := documentKey{scope: scope, fold: fold, initials: initials}
+	if s, ok := d.cache[key]; ok {
+		return s
+	}
+	s := fold.fold(d.fields[scope])
+	if initials {
+		s = pinyinInitials(s)
+	}
+	if d.cache == nil {
+		d.cache = make(map[documentKey]string)
+	}
+	d.cache[key] = s
+	return s
+}
+
+// lookup reports whether the plain term text was found in the field, indexed
+// is false when no Matcher indexed the term.
+func (d *Document) lookup(scope Scope, fold folding, text string) (found, indexed bool) {
+	idx, ok := d.index[documentKey{scope: scope, fold: fold}]
+	if !ok {
+		return false, false
+	}
+	id, ok := idx.ids[text]
+	if !ok {
+		return false, false
+	}
+	return idx.found[id], true
+}
+
+// matchField reports whether t is found in the field f of doc.
+func (t *termNode) matchField(doc *Document, f Scope) bool {
+	if t.re != nil {
+		return t.re.MatchString(doc.field(f, t.fold, false))
+	}
+	found, indexed := false, false
+	if t.fuzzy == 0 {
+		found, indexed = doc.lookup(f, t.fold, t.text)
+	}
+	if !indexed {
+		found = fuzzyContains(doc.field(f, t.fold, false), t.text, t.fuzzy)
+	}
+	return found || t.pinyin && strings.Contains(doc.field(f, t.fold, true), strings.ToLower(t.text))
+}
+
+// NeedsContent reports whether any term of the rule reads the notice content,
+// so callers can skip extracting it otherwise.
+func (cr *ComplexRule) NeedsContent() bool {
+	for _, t := range cr.ScopedTerms {
+		if t.Scope == ScopeContent || t.Scope == ScopeAll {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchDocument evaluates the rule against doc and returns the fields the
+// terms were found in, in title, code, content order.
+//
+// Unscoped terms keep their original meaning: the expression is evaluated
+// with them reading the title, and if that fails once more with them reading
+// the tender code, unless the notice has none. Scoped terms always read the
+// fields of their scope.
+func (cr *ComplexRule) MatchDocument(doc *Document) (bool, []Scope) {
+	if cr.expr == nil {
+		return true, nil
+	}
+	for _, field := range []Scope{ScopeTitle, ScopeCode} {
+		if field == ScopeCode && doc.fields[ScopeCode] == "" {
+			break
+		}
+		matched := make(map[Scope]struct{})
+		if eval(cr.expr, doc, field, matched) {
+			var scopes []Scope
+			for _, s := range scopeFields[ScopeAll] {
+				if _, ok := matched[s]; ok {
+					scopes = append(scopes, s)
+				}
+			}
+			return true, scopes
+		}
+		if len(cr.IncludeTerms)+len(cr.ExcludeTerms) == 0 {
+			break // only scoped terms, the second pass would not differ
+		}
+	}
+	return false, nil
+}
+
+// eval evaluates n with unscoped terms reading field and records in matched
+// the fields a satisfied, non negated term was found in. A nil matched
+// records nothing.
+func eval(n node, doc *Document, field Scope, matched map[Scope]struct{}) bool {
+	switch v := n.(type) {
+	case *termNode:
+		fields := []Scope{field}
+		if v.scope != "" {
+			fields = scopeFields[v.scope]
+		}
+		for _, f := range fields {
+			if v.matchField(doc, f) {
+				if matched != nil {
+					matched[f] = struct{}{}
+				}
+				return true
+			}
+		}
+		return false
+	case *notNode:
+		return !eval(v.child, doc, field, nil)
+	case *andNode:
+		for _, c := range v.children {
+			if !eval(c, doc, field, matched) {
+				return false
+			}
+		}
+		return true
+	case *orNode:
+		for _, c := range v.children {
+			// Keep the fields of a failed alternative out of the result.
+			branch := make(map[Scope]struct{})
+			if eval(c, doc, field, branch) {
+				if matched != nil {
+					for f := range branch {
+						matched[f] = struct{}{}
+					}
+				}
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
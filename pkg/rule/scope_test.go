@@ -0,0 +1,55 @@
+package rule
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gythialy/magnet/pkg/model"
+)
+
+func TestComplexRule_MatchDocument(t *testing.T) {
+	doc := NewDocument("某单位服务器采购公告", "2024-ABC-001", "采购内容：GPU 服务器 2 台，含三年维保")
+	tests := []struct {
+		name       string
+		rule       string
+		want       bool
+		wantScopes []Scope
+	}{
+		{"unscoped title", "服务器", true, []Scope{ScopeTitle}},
+		{"unscoped code", "2024-ABC", true, []Scope{ScopeCode}},
+		{"unscoped miss", "GPU", false, nil},
+		{"content", `content:"GPU 服务器"`, true, []Scope{ScopeContent}},
+		{"content miss", "content:交换机", false, nil},
+		{"title scope misses content", "title:GPU", false, nil},
+		{"code scope", "code:ABC", true, []Scope{ScopeCode}},
+		{"all scope", "all:GPU", true, []Scope{ScopeContent}},
+		{"all scope prefers title", "all:服务器", true, []Scope{ScopeTitle}},
+		{"mixed", "采购 +content:GPU", true, []Scope{ScopeTitle, ScopeContent}},
+		{"scoped exclude", "服务器 -content:维保", false, nil},
+		{"scoped exclude elsewhere", "服务器 -title:维保", true, []Scope{ScopeTitle}},
+		{"chinese colon", "content：GPU", true, []Scope{ScopeContent}},
+		{"unknown scope is a plain term", "body:GPU", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := NewComplexRule(&model.Keyword{Keyword: tt.rule})
+			got, scopes := cr.MatchDocument(doc)
+			if got != tt.want || !slices.Equal(scopes, tt.wantScopes) {
+				t.Errorf("MatchDocument() = %v, %v, want %v, %v", got, scopes, tt.want, tt.wantScopes)
+			}
+		})
+	}
+}
+
+func TestComplexRule_ScopedToString(t *testing.T) {
+	cr := NewComplexRule(&model.Keyword{Keyword: `服务器 -维保 content:"GPU" -title:二次 budget>=1e6`})
+	if got, want := cr.ToString(), "+服务器 -维保 +content:GPU -title:二次 budget>=1e6"; got != want {
+		t.Errorf("ToString() = %q, want %q", got, want)
+	}
+	if !cr.NeedsContent() {
+		t.Error("expected the rule to need the content")
+	}
+	if NewComplexRule(&model.Keyword{Keyword: "服务器 title:GPU"}).NeedsContent() {
+		t.Error("expected the rule not to need the content")
+	}
+}